@@ -16,6 +16,7 @@ import (
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 	"github.com/quenyu/deadlock-stats/internal/clients/deadlockapi"
+	"github.com/quenyu/deadlock-stats/internal/clients/steam"
 	"github.com/quenyu/deadlock-stats/internal/config"
 	"github.com/quenyu/deadlock-stats/internal/database/pool"
 	"github.com/quenyu/deadlock-stats/internal/handlers"
@@ -97,14 +98,6 @@ func main() {
 
 	staticDataService := services.NewStaticDataService(logger)
 
-	go func() {
-		if err := staticDataService.LoadStaticData(); err != nil {
-			logger.Error("failed to load static data", zap.Error(err))
-		} else {
-			logger.Info("static data loaded successfully")
-		}
-	}()
-
 	userRepository := repositories.NewUserRepository(db)
 	playerProfileRepository := repositories.NewPlayerProfilePostgresRepository(db)
 
@@ -115,7 +108,13 @@ func main() {
 		deadlockAPIClient = deadlockapi.NewClient()
 	}
 
-	authService := services.NewAuthService(userRepository, cfg, logger)
+	steamClient := steam.NewClientWithBaseURL(cfg.Steam.APIKey, cfg.Steam.APIBaseURL, cfg.API.Timeout)
+
+	authService := services.NewAuthService(userRepository, steamClient, cfg, logger)
+	steamIDResolver := services.NewSteamIDResolver(steamClient, rdb, logger)
+
+	searchCache := services.NewSearchCache(rdb, cfg.SearchCache.TTL, cfg.SearchCache.LocalTTL, cfg.SearchCache.MaxEntries, logger)
+	userRepository.OnChange(searchCache.OnUserChange)
 
 	playerSearchService := services.NewPlayerSearchService(
 		playerProfileRepository,
@@ -123,21 +122,62 @@ func main() {
 		authService,
 		deadlockAPIClient,
 		rdb,
-		cfg.Steam.APIKey,
+		steamClient,
+		steamIDResolver,
+		searchCache,
 		logger,
 	)
 
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+
+	go staticDataService.Start(jobsCtx)
+	go searchCache.Start(jobsCtx)
+
+	searchAnalytics := services.NewSearchAnalytics(rdb, userRepository, logger)
+	go searchAnalytics.Start(jobsCtx)
+
+	if cfg.UserRefresh.Enabled {
+		userRefreshService := services.NewUserRefreshService(
+			userRepository,
+			steamClient,
+			cfg.UserRefresh.Interval,
+			cfg.UserRefresh.StaleAfter,
+			cfg.UserRefresh.MaxUsersPerRun,
+			logger,
+		)
+		go userRefreshService.Start(jobsCtx)
+	}
+
 	playerProfileService := services.NewPlayerProfileService(playerProfileRepository, userRepository, authService, deadlockAPIClient, staticDataService, rdb, logger)
 
 	crosshairRepository := repositories.NewCrosshairRepository(db)
 	crosshairService := services.NewCrosshairService(crosshairRepository)
+	crosshairCollectionRepository := repositories.NewCrosshairCollectionRepository(db)
+	crosshairCollectionService := services.NewCrosshairCollectionService(crosshairCollectionRepository, crosshairService)
+	crosshairViews := services.NewCrosshairViews(rdb, crosshairRepository, cfg.JWT.Secret, logger)
+	go crosshairViews.Start(jobsCtx)
+
+	buildRepository := repositories.NewBuildRepository(db)
+	buildService := services.NewBuildService(buildRepository, staticDataService)
+	matchDetailsRepository := repositories.NewMatchDetailsRepository(db)
+	buildStatsJob := services.NewBuildStatsJob(buildRepository, matchDetailsRepository, deadlockAPIClient, logger)
+	go buildStatsJob.Start(jobsCtx)
+
+	commentRepository := repositories.NewCommentRepository(db)
+	commentService := services.NewCommentService(commentRepository, crosshairService, buildService, rdb, logger)
 
 	authHandler := handlers.NewAuthHandler(authService, cfg)
-	playerSearchHandler := handlers.NewPlayerSearchHandler(playerSearchService, logger)
-	playerProfileHandler := handlers.NewPlayerProfileHandler(playerProfileService)
-	crosshairHandler := handlers.NewCrosshairHandler(crosshairService)
+	playerSearchHandler := handlers.NewPlayerSearchHandler(playerSearchService, steamIDResolver, searchAnalytics, logger)
+	playerProfileHandler := handlers.NewPlayerProfileHandler(playerProfileService, steamIDResolver, searchAnalytics)
+	searchAnalyticsHandler := handlers.NewSearchAnalyticsHandler(searchAnalytics)
+	crosshairHandler := handlers.NewCrosshairHandler(crosshairService, crosshairViews, cfg)
+	crosshairCollectionHandler := handlers.NewCrosshairCollectionHandler(crosshairCollectionService)
+	buildHandler := handlers.NewBuildHandler(buildService)
+	commentHandler := handlers.NewCommentHandler(commentService)
 	healthHandler := handlers.NewHealthHandler(poolManager, logger)
 	jwtMiddleware := customMiddleware.NewJWTMiddleware(cfg)
+	adminMiddleware := customMiddleware.NewAdminMiddleware(cfg, userRepository)
 
 	e := echo.New()
 
@@ -206,16 +246,36 @@ func main() {
 	v1Group.GET("/players/search/filters", playerSearchHandler.SearchPlayersWithFilters)
 	v1Group.GET("/players/popular", playerSearchHandler.GetPopularPlayers)
 	v1Group.GET("/players/recently-active", playerSearchHandler.GetRecentlyActivePlayers)
+	v1Group.GET("/players/trending", searchAnalyticsHandler.GetTrendingPlayers)
 
 	v1Group.GET("/players/:steamId", playerProfileHandler.GetPlayerProfileV2)
 	v1Group.GET("/players/:steamId/metrics", playerProfileHandler.GetPlayerProfileWithMetrics)
 	v1Group.GET("/players/:steamId/matches", playerProfileHandler.GetRecentMatches)
+	v1Group.GET("/players/:steamId/friends", playerSearchHandler.GetPlayerFriends)
 	v1Group.GET("/ranks", staticDataService.GetRanksHandler)
 
 	// Crosshair routes (public)
-	v1Group.GET("/crosshairs", crosshairHandler.GetAll)
-	v1Group.GET("/crosshairs/:id", crosshairHandler.GetByID)
-	v1Group.GET("/authors/:author_id/crosshairs", crosshairHandler.GetByAuthorID)
+	v1Group.GET("/crosshairs", crosshairHandler.GetAll, jwtMiddleware.OptionalAuthorization)
+	v1Group.GET("/crosshairs/:id", crosshairHandler.GetByID, jwtMiddleware.OptionalAuthorization)
+	v1Group.GET("/crosshairs/:id/export", crosshairHandler.Export, jwtMiddleware.OptionalAuthorization)
+	v1Group.GET("/crosshairs/:id/preview.svg", crosshairHandler.PreviewSVG, jwtMiddleware.OptionalAuthorization)
+	v1Group.GET("/crosshairs/:id/preview.png", crosshairHandler.PreviewPNG, jwtMiddleware.OptionalAuthorization)
+	v1Group.GET("/crosshairs/:id/share", crosshairHandler.Share, jwtMiddleware.OptionalAuthorization)
+	v1Group.POST("/crosshairs/import", crosshairHandler.Import)
+	v1Group.GET("/crosshairs/:id/lineage", crosshairHandler.GetLineage, jwtMiddleware.OptionalAuthorization)
+	v1Group.GET("/crosshairs/:id/comments", commentHandler.GetCrosshairComments, jwtMiddleware.OptionalAuthorization)
+	v1Group.GET("/authors/:author_id/crosshairs", crosshairHandler.GetByAuthorID, jwtMiddleware.OptionalAuthorization)
+	v1Group.GET("/authors/:author_id/crosshair-collections", crosshairCollectionHandler.GetByAuthorID, jwtMiddleware.OptionalAuthorization)
+	v1Group.GET("/crosshair-collections/:id", crosshairCollectionHandler.GetByID, jwtMiddleware.OptionalAuthorization)
+
+	// Build routes (public)
+	v1Group.GET("/builds", buildHandler.GetAll, jwtMiddleware.OptionalAuthorization)
+	v1Group.GET("/builds/:id", buildHandler.GetByID, jwtMiddleware.OptionalAuthorization)
+	v1Group.GET("/builds/:id/export", buildHandler.Export, jwtMiddleware.OptionalAuthorization)
+	v1Group.GET("/builds/:id/stats", buildHandler.GetStats, jwtMiddleware.OptionalAuthorization)
+	v1Group.GET("/builds/:id/comments", commentHandler.GetBuildComments, jwtMiddleware.OptionalAuthorization)
+	v1Group.POST("/builds/import", buildHandler.Import)
+	v1Group.GET("/authors/:author_id/builds", buildHandler.GetByAuthorID, jwtMiddleware.OptionalAuthorization)
 
 	// Logout route
 	v1Group.POST("/auth/logout", authHandler.LogoutHandler)
@@ -224,12 +284,43 @@ func main() {
 	protectedGroup := v1Group.Group("")
 	protectedGroup.Use(jwtMiddleware.Authorization)
 	protectedGroup.GET("/users/me", authHandler.GetUserMe)
+	protectedGroup.GET("/users/me/liked-crosshairs", crosshairHandler.GetLiked)
+	protectedGroup.GET("/users/me/crosshair-stats", crosshairHandler.GetStats)
 
 	// Protected crosshair routes
 	protectedGroup.POST("/crosshairs", crosshairHandler.Create)
+	protectedGroup.PUT("/crosshairs/:id", crosshairHandler.Update)
+	protectedGroup.GET("/crosshairs/:id/revisions", crosshairHandler.GetRevisions)
+	protectedGroup.POST("/crosshairs/:id/revisions/:revision_id/restore", crosshairHandler.RestoreRevision)
 	protectedGroup.POST("/crosshairs/:id/like", crosshairHandler.Like)
 	protectedGroup.DELETE("/crosshairs/:id/like", crosshairHandler.Unlike)
 	protectedGroup.DELETE("/crosshairs/:id", crosshairHandler.Delete)
+	protectedGroup.POST("/crosshairs/:id/fork", crosshairHandler.Fork)
+	protectedGroup.POST("/crosshairs/:id/comments", commentHandler.CreateCrosshairComment)
+
+	// Protected crosshair collection routes
+	protectedGroup.GET("/users/me/crosshair-collections", crosshairCollectionHandler.GetMine)
+	protectedGroup.POST("/crosshair-collections", crosshairCollectionHandler.Create)
+	protectedGroup.PUT("/crosshair-collections/:id", crosshairCollectionHandler.Update)
+	protectedGroup.DELETE("/crosshair-collections/:id", crosshairCollectionHandler.Delete)
+	protectedGroup.POST("/crosshair-collections/:id/crosshairs", crosshairCollectionHandler.AddCrosshair)
+	protectedGroup.PUT("/crosshair-collections/:id/crosshairs", crosshairCollectionHandler.SetCrosshairs)
+	protectedGroup.DELETE("/crosshair-collections/:id/crosshairs/:crosshair_id", crosshairCollectionHandler.RemoveCrosshair)
+
+	// Protected build routes
+	protectedGroup.POST("/builds", buildHandler.Create)
+	protectedGroup.PUT("/builds/:id", buildHandler.Update)
+	protectedGroup.DELETE("/builds/:id", buildHandler.Delete)
+	protectedGroup.POST("/builds/:id/comments", commentHandler.CreateBuildComment)
+
+	// Protected comment routes
+	protectedGroup.PUT("/comments/:id", commentHandler.Update)
+	protectedGroup.DELETE("/comments/:id", commentHandler.Delete)
+
+	// Admin routes
+	adminGroup := protectedGroup.Group("/admin")
+	adminGroup.Use(adminMiddleware.RequireAdmin)
+	adminGroup.GET("/search-stats", searchAnalyticsHandler.GetSearchStats)
 
 	e.GET("/health", healthHandler.HealthCheck)
 	e.GET("/health/detailed", healthHandler.HealthCheckDetailed)
@@ -351,7 +442,7 @@ func buildSecurityConfig(cfg *config.Config, logger *zap.Logger) *security.Manag
 			AllowOrigins:     []string{cfg.App.ClientURL},
 			AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
 			AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", "X-CSRF-Token", "X-Request-ID"},
-			ExposeHeaders:    []string{"X-RateLimit-Limit", "X-RateLimit-Remaining", "X-RateLimit-Reset"},
+			ExposeHeaders:    []string{"X-RateLimit-Limit", "X-RateLimit-Remaining", "X-RateLimit-Reset", "X-Canonical-Steam-ID"},
 			AllowCredentials: true,
 			MaxAge:           86400,
 			Logger:           logger,
@@ -2,6 +2,7 @@ package deadlockapi
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
@@ -12,6 +13,17 @@ import (
 
 const baseURL = "https://api.deadlock-api.com/v1"
 
+var (
+	// ErrNotFound is returned when the Deadlock API answers 404. Such
+	// requests are not retried.
+	ErrNotFound = errors.New("deadlock API resource not found")
+	// ErrRateLimited is returned when the Deadlock API answers 429. Such
+	// requests are not retried.
+	ErrRateLimited = errors.New("deadlock API rate limit exceeded")
+	// ErrUnavailable is returned when the Deadlock API cannot be reached.
+	ErrUnavailable = errors.New("deadlock API unavailable")
+)
+
 type Client struct {
 	httpClient *http.Client
 }
@@ -158,6 +170,24 @@ func (c *Client) FetchSteamProfileSearch(query string) ([]domain.SteamProfileSea
 	return profileSearch, err
 }
 
+// FetchMatchMetadata returns the match and its participants. It wraps
+// ErrNotFound for matches the API does not know about.
+func (c *Client) FetchMatchMetadata(matchID int64) (*MatchInfo, error) {
+	url := matchMetadataURL(matchID)
+
+	var metadata MatchMetadata
+	if err := c.doRequestWithRetry(url, &metadata, 2); err != nil {
+		return nil, err
+	}
+
+	return &metadata.MatchInfo, nil
+}
+
+// matchMetadataURL is the public metadata endpoint of a match.
+func matchMetadataURL(matchID int64) string {
+	return fmt.Sprintf("%s/matches/%d/metadata", baseURL, matchID)
+}
+
 func (c *Client) doRequest(url string, target interface{}) error {
 	req, err := c.createRequest(url)
 	if err != nil {
@@ -166,7 +196,7 @@ func (c *Client) doRequest(url string, target interface{}) error {
 
 	resp, err := c.executeRequest(req)
 	if err != nil {
-		return fmt.Errorf("failed to execute request: %w", err)
+		return fmt.Errorf("%w: %v", ErrUnavailable, err)
 	}
 	defer resp.Body.Close()
 
@@ -188,7 +218,7 @@ func (c *Client) doRequestWithRetry(url string, target interface{}, maxRetries i
 
 		lastErr = err
 
-		if attempt == maxRetries {
+		if errors.Is(err, ErrNotFound) || errors.Is(err, ErrRateLimited) || attempt == maxRetries {
 			break
 		}
 
@@ -208,6 +238,12 @@ func (c *Client) executeRequest(req *http.Request) (*http.Response, error) {
 }
 
 func (c *Client) validateResponse(resp *http.Response, url string) error {
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("%w: %s", ErrNotFound, url)
+	}
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return fmt.Errorf("%w: %s", ErrRateLimited, url)
+	}
 	if resp.StatusCode != http.StatusOK {
 		return fmt.Errorf("deadlock API returned non-200 status: %d for URL %s", resp.StatusCode, url)
 	}
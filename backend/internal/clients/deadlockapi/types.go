@@ -26,6 +26,24 @@ type HeroV2 struct {
 	ClassName string     `json:"class_name"`
 	Name      string     `json:"name"`
 	Images    HeroImages `json:"images"`
+
+	// Items maps the hero's slots (signature1 to signature4 for abilities)
+	// to item class names.
+	Items map[string]string `json:"items"`
+}
+
+// ItemV2 is an item of the assets API: a shop upgrade, an ability or a
+// weapon, told apart by Type.
+type ItemV2 struct {
+	ID           int    `json:"id"`
+	ClassName    string `json:"class_name"`
+	Name         string `json:"name"`
+	Type         string `json:"type"`
+	Cost         int    `json:"cost"`
+	ItemTier     int    `json:"item_tier"`
+	ItemSlotType string `json:"item_slot_type"`
+	Shopable     bool   `json:"shopable"`
+	Disabled     bool   `json:"disabled"`
 }
 
 type DeadlockMatch struct {
@@ -51,3 +69,35 @@ type HeroStatAPI struct {
 	Deaths        int     `json:"deaths"`
 	Assists       int     `json:"assists"`
 }
+
+type MatchMetadata struct {
+	MatchInfo MatchInfo `json:"match_info"`
+}
+
+type MatchInfo struct {
+	MatchID     int64         `json:"match_id"`
+	StartTime   int64         `json:"start_time"`
+	DurationS   int           `json:"duration_s"`
+	WinningTeam int           `json:"winning_team"`
+	Players     []MatchPlayer `json:"players"`
+}
+
+type MatchPlayer struct {
+	AccountID  int `json:"account_id"`
+	PlayerSlot int `json:"player_slot"`
+	Team       int `json:"team"`
+	HeroID     int `json:"hero_id"`
+	Kills      int `json:"kills"`
+	Deaths     int `json:"deaths"`
+	Assists    int `json:"assists"`
+	NetWorth   int `json:"net_worth"`
+
+	Items []MatchPlayerItem `json:"items"`
+}
+
+// MatchPlayerItem is an item a player bought, or an ability they upgraded.
+type MatchPlayerItem struct {
+	ItemID    int64 `json:"item_id"`
+	GameTimeS int   `json:"game_time_s"`
+	SoldTimeS int   `json:"sold_time_s"`
+}
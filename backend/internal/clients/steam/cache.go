@@ -0,0 +1,63 @@
+package steam
+
+import (
+	"sync"
+	"time"
+)
+
+const maxCacheEntries = 10000
+
+type cacheEntry[T any] struct {
+	value     T
+	expiresAt time.Time
+}
+
+// ttlCache is a small in-process cache used to avoid hitting the Steam Web
+// API rate limit for data that rarely changes within minutes.
+type ttlCache[T any] struct {
+	ttl     time.Duration
+	entries map[string]cacheEntry[T]
+	mu      sync.Mutex
+}
+
+func newTTLCache[T any](ttl time.Duration) *ttlCache[T] {
+	return &ttlCache[T]{
+		ttl:     ttl,
+		entries: make(map[string]cacheEntry[T]),
+	}
+}
+
+func (c *ttlCache[T]) get(key string) (T, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok || time.Now().After(entry.expiresAt) {
+		var zero T
+		return zero, false
+	}
+	return entry.value, true
+}
+
+func (c *ttlCache[T]) set(key string, value T) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.entries) >= maxCacheEntries {
+		c.evictExpired()
+	}
+	if len(c.entries) >= maxCacheEntries {
+		c.entries = make(map[string]cacheEntry[T])
+	}
+
+	c.entries[key] = cacheEntry[T]{value: value, expiresAt: time.Now().Add(c.ttl)}
+}
+
+func (c *ttlCache[T]) evictExpired() {
+	now := time.Now()
+	for key, entry := range c.entries {
+		if now.After(entry.expiresAt) {
+			delete(c.entries, key)
+		}
+	}
+}
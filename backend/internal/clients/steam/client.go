@@ -0,0 +1,254 @@
+package steam
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultBaseURL = "https://api.steampowered.com"
+
+	// MaxSteamIDsPerRequest is the GetPlayerSummaries limit imposed by Steam.
+	MaxSteamIDsPerRequest = 100
+
+	defaultMaxRetries   = 2
+	defaultRetryBackoff = 200 * time.Millisecond
+	summaryCacheTTL     = 10 * time.Minute
+	vanityCacheTTL      = time.Hour
+	friendsCacheTTL     = 5 * time.Minute
+)
+
+var (
+	ErrMissingAPIKey   = errors.New("steam API key is empty")
+	ErrVanityNotFound  = errors.New("vanity URL could not be resolved")
+	ErrPrivateProfile  = errors.New("steam profile is private")
+	ErrPlayerNotFound  = errors.New("no player data found for steam id")
+	errRetryableStatus = errors.New("steam API returned retryable status")
+)
+
+type Client struct {
+	httpClient   *http.Client
+	baseURL      string
+	apiKey       string
+	maxRetries   int
+	retryBackoff time.Duration
+
+	summaries *ttlCache[PlayerSummary]
+	vanities  *ttlCache[string]
+	friends   *ttlCache[[]Friend]
+}
+
+func NewClient(apiKey string) *Client {
+	return NewClientWithBaseURL(apiKey, DefaultBaseURL, 10*time.Second)
+}
+
+// NewClientWithBaseURL creates a client against an arbitrary Web API host,
+// which allows running against a local fake of api.steampowered.com.
+func NewClientWithBaseURL(apiKey, baseURL string, timeout time.Duration) *Client {
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+
+	transport := &http.Transport{
+		MaxIdleConns:        100,
+		MaxIdleConnsPerHost: 10,
+		IdleConnTimeout:     90 * time.Second,
+	}
+
+	return &Client{
+		httpClient: &http.Client{
+			Timeout:   timeout,
+			Transport: transport,
+		},
+		baseURL:      strings.TrimSuffix(baseURL, "/"),
+		apiKey:       apiKey,
+		maxRetries:   defaultMaxRetries,
+		retryBackoff: defaultRetryBackoff,
+		summaries:    newTTLCache[PlayerSummary](summaryCacheTTL),
+		vanities:     newTTLCache[string](vanityCacheTTL),
+		friends:      newTTLCache[[]Friend](friendsCacheTTL),
+	}
+}
+
+// GetPlayerSummaries fetches summaries for any number of steam IDs, splitting
+// them into batches of MaxSteamIDsPerRequest. Profiles Steam does not know
+// about are simply absent from the result.
+func (c *Client) GetPlayerSummaries(ctx context.Context, steamIDs []string) ([]PlayerSummary, error) {
+	summaries := make([]PlayerSummary, 0, len(steamIDs))
+	missing := make([]string, 0, len(steamIDs))
+	seen := make(map[string]bool, len(steamIDs))
+
+	for _, steamID := range steamIDs {
+		if steamID == "" || seen[steamID] {
+			continue
+		}
+		seen[steamID] = true
+
+		if summary, ok := c.summaries.get(steamID); ok {
+			summaries = append(summaries, summary)
+			continue
+		}
+		missing = append(missing, steamID)
+	}
+
+	for start := 0; start < len(missing); start += MaxSteamIDsPerRequest {
+		end := start + MaxSteamIDsPerRequest
+		if end > len(missing) {
+			end = len(missing)
+		}
+
+		var resp playerSummariesResponse
+		params := url.Values{"steamids": {strings.Join(missing[start:end], ",")}}
+		if err := c.doRequestWithRetry(ctx, "ISteamUser/GetPlayerSummaries/v0002", params, &resp); err != nil {
+			return nil, err
+		}
+
+		for _, summary := range resp.Response.Players {
+			c.summaries.set(summary.SteamID, summary)
+			summaries = append(summaries, summary)
+		}
+	}
+
+	return summaries, nil
+}
+
+func (c *Client) GetPlayerSummary(ctx context.Context, steamID string) (*PlayerSummary, error) {
+	summaries, err := c.GetPlayerSummaries(ctx, []string{steamID})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, summary := range summaries {
+		if summary.SteamID == steamID {
+			return &summary, nil
+		}
+	}
+
+	return nil, ErrPlayerNotFound
+}
+
+func (c *Client) ResolveVanityURL(ctx context.Context, vanity string) (string, error) {
+	key := strings.ToLower(vanity)
+	if steamID, ok := c.vanities.get(key); ok {
+		return steamID, nil
+	}
+
+	var resp resolveVanityURLResponse
+	params := url.Values{"vanityurl": {vanity}}
+	if err := c.doRequestWithRetry(ctx, "ISteamUser/ResolveVanityURL/v1", params, &resp); err != nil {
+		return "", err
+	}
+
+	if resp.Response.Success != 1 || resp.Response.SteamID == "" {
+		return "", ErrVanityNotFound
+	}
+
+	c.vanities.set(key, resp.Response.SteamID)
+	return resp.Response.SteamID, nil
+}
+
+// GetFriendList returns the public friend list of a player. Steam answers
+// with 401 for private friend lists, which is reported as ErrPrivateProfile.
+func (c *Client) GetFriendList(ctx context.Context, steamID string) ([]Friend, error) {
+	if friends, ok := c.friends.get(steamID); ok {
+		return friends, nil
+	}
+
+	var resp friendListResponse
+	params := url.Values{"steamid": {steamID}, "relationship": {"friend"}}
+	if err := c.doRequestWithRetry(ctx, "ISteamUser/GetFriendList/v0001", params, &resp); err != nil {
+		return nil, err
+	}
+
+	friends := resp.FriendsList.Friends
+	if friends == nil {
+		friends = []Friend{}
+	}
+
+	c.friends.set(steamID, friends)
+	return friends, nil
+}
+
+func (c *Client) buildURL(method string, params url.Values) string {
+	query := url.Values{}
+	for key, values := range params {
+		query[key] = values
+	}
+	query.Set("key", c.apiKey)
+
+	return fmt.Sprintf("%s/%s/?%s", c.baseURL, method, query.Encode())
+}
+
+func (c *Client) doRequest(ctx context.Context, method string, params url.Values, target interface{}) error {
+	if c.apiKey == "" {
+		return ErrMissingAPIKey
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.buildURL(method, params), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		// The URL carries the API key, so only the cause is kept.
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		if ctx.Err() != nil {
+			return err
+		}
+		return fmt.Errorf("%w: %v", errRetryableStatus, err)
+	}
+	defer resp.Body.Close()
+
+	if err := c.validateResponse(resp, method); err != nil {
+		return err
+	}
+
+	return json.NewDecoder(resp.Body).Decode(target)
+}
+
+func (c *Client) doRequestWithRetry(ctx context.Context, method string, params url.Values, target interface{}) error {
+	var lastErr error
+
+	for attempt := 0; attempt <= c.maxRetries; attempt++ {
+		err := c.doRequest(ctx, method, params, target)
+		if err == nil {
+			return nil
+		}
+
+		lastErr = err
+		if !errors.Is(err, errRetryableStatus) || attempt == c.maxRetries {
+			break
+		}
+
+		backoff := time.Duration(c.retryBackoff.Nanoseconds() * (1 << attempt))
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("steam API %s failed: %w", method, ctx.Err())
+		case <-time.After(backoff):
+		}
+	}
+
+	return fmt.Errorf("steam API %s failed: %w", method, lastErr)
+}
+
+func (c *Client) validateResponse(resp *http.Response, method string) error {
+	switch {
+	case resp.StatusCode == http.StatusOK:
+		return nil
+	case resp.StatusCode == http.StatusUnauthorized && strings.Contains(method, "GetFriendList"):
+		return ErrPrivateProfile
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError:
+		return fmt.Errorf("%w: %d", errRetryableStatus, resp.StatusCode)
+	default:
+		return fmt.Errorf("steam API returned non-200 status: %d", resp.StatusCode)
+	}
+}
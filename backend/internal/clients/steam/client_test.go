@@ -0,0 +1,108 @@
+package steam
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const testAPIKey = "secret-test-key"
+
+func newTestClient(t *testing.T, handler http.HandlerFunc) (*Client, *httptest.Server) {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	client := NewClientWithBaseURL(testAPIKey, server.URL, time.Second)
+	client.retryBackoff = time.Millisecond
+	return client, server
+}
+
+func TestGetPlayerSummaryRetriesServerErrors(t *testing.T) {
+	var calls atomic.Int32
+	client, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("key") != testAPIKey {
+			t.Errorf("key = %q, want %q", r.URL.Query().Get("key"), testAPIKey)
+		}
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte(`{"response":{"players":[{"steamid":"76561197960287930","personaname":"Rabscuttle"}]}}`))
+	})
+
+	summary, err := client.GetPlayerSummary(context.Background(), "76561197960287930")
+	if err != nil {
+		t.Fatalf("GetPlayerSummary() error = %v", err)
+	}
+	if summary.PersonaName != "Rabscuttle" {
+		t.Errorf("PersonaName = %q, want %q", summary.PersonaName, "Rabscuttle")
+	}
+	if got := calls.Load(); got != 2 {
+		t.Errorf("calls = %d, want 2", got)
+	}
+}
+
+func TestGetPlayerSummaryGivesUpAfterMaxRetries(t *testing.T) {
+	var calls atomic.Int32
+	client, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+
+	_, err := client.GetPlayerSummary(context.Background(), "76561197960287930")
+	if !errors.Is(err, errRetryableStatus) {
+		t.Fatalf("error = %v, want errRetryableStatus", err)
+	}
+	if got := calls.Load(); got != defaultMaxRetries+1 {
+		t.Errorf("calls = %d, want %d", got, defaultMaxRetries+1)
+	}
+}
+
+func TestGetFriendListPrivateProfile(t *testing.T) {
+	var calls atomic.Int32
+	client, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusUnauthorized)
+	})
+
+	_, err := client.GetFriendList(context.Background(), "76561197960287930")
+	if !errors.Is(err, ErrPrivateProfile) {
+		t.Fatalf("error = %v, want ErrPrivateProfile", err)
+	}
+	if got := calls.Load(); got != 1 {
+		t.Errorf("calls = %d, want 1", got)
+	}
+}
+
+func TestTransportErrorHidesAPIKey(t *testing.T) {
+	client, server := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {})
+	server.Close()
+
+	_, err := client.ResolveVanityURL(context.Background(), "gabelogannewell")
+	if err == nil {
+		t.Fatal("expected an error from a closed server")
+	}
+	if strings.Contains(err.Error(), testAPIKey) {
+		t.Errorf("error leaks the API key: %v", err)
+	}
+}
+
+func TestCanceledContextStopsRetries(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	client, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		cancel()
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	client.retryBackoff = time.Minute
+
+	_, err := client.GetFriendList(ctx, "76561197960287930")
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("error = %v, want context.Canceled", err)
+	}
+}
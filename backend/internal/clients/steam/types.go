@@ -0,0 +1,43 @@
+package steam
+
+type PlayerSummary struct {
+	SteamID                  string `json:"steamid"`
+	PersonaName              string `json:"personaname"`
+	ProfileURL               string `json:"profileurl"`
+	Avatar                   string `json:"avatar"`
+	AvatarMedium             string `json:"avatarmedium"`
+	AvatarFull               string `json:"avatarfull"`
+	PersonaState             int    `json:"personastate"`
+	CommunityVisibilityState int    `json:"communityvisibilitystate"`
+	ProfileState             int    `json:"profilestate"`
+	LastLogoff               int64  `json:"lastlogoff"`
+	RealName                 string `json:"realname"`
+	LocCountryCode           string `json:"loccountrycode"`
+	TimeCreated              int64  `json:"timecreated"`
+}
+
+type Friend struct {
+	SteamID      string `json:"steamid"`
+	Relationship string `json:"relationship"`
+	FriendSince  int64  `json:"friend_since"`
+}
+
+type playerSummariesResponse struct {
+	Response struct {
+		Players []PlayerSummary `json:"players"`
+	} `json:"response"`
+}
+
+type resolveVanityURLResponse struct {
+	Response struct {
+		SteamID string `json:"steamid"`
+		Success int    `json:"success"`
+		Message string `json:"message"`
+	} `json:"response"`
+}
+
+type friendListResponse struct {
+	FriendsList struct {
+		Friends []Friend `json:"friends"`
+	} `json:"friendslist"`
+}
@@ -16,6 +16,10 @@ type Config struct {
 	API       APIConfig       `mapstructure:"api"`
 	RateLimit RateLimitConfig `mapstructure:"rate_limit"`
 	Security  SecurityConfig  `mapstructure:"security"`
+
+	UserRefresh UserRefreshConfig `mapstructure:"user_refresh"`
+	SearchCache SearchCacheConfig `mapstructure:"search_cache"`
+	Admin       AdminConfig       `mapstructure:"admin"`
 }
 
 type APIConfig struct {
@@ -75,6 +79,31 @@ type JWTConfig struct {
 type SteamConfig struct {
 	RedirectURL string `mapstructure:"domain"`
 	APIKey      string `mapstructure:"steam_api_key"`
+
+	// APIBaseURL overrides https://api.steampowered.com, e.g. for a local fake
+	APIBaseURL string `mapstructure:"api_base_url"`
+}
+
+// UserRefreshConfig controls the background job that re-fetches Steam
+// nicknames and avatars for users that have not been updated recently
+type UserRefreshConfig struct {
+	Enabled        bool          `mapstructure:"enabled"`
+	Interval       time.Duration `mapstructure:"interval"`
+	StaleAfter     time.Duration `mapstructure:"stale_after"`
+	MaxUsersPerRun int           `mapstructure:"max_users_per_run"`
+}
+
+// SearchCacheConfig sizes the player search cache. LocalTTL applies to the
+// in-process tier and bounds how long other replicas may serve stale results
+type SearchCacheConfig struct {
+	TTL        time.Duration `mapstructure:"ttl"`
+	LocalTTL   time.Duration `mapstructure:"local_ttl"`
+	MaxEntries int           `mapstructure:"max_entries"`
+}
+
+// AdminConfig lists the Steam accounts allowed to use the /admin endpoints
+type AdminConfig struct {
+	SteamIDs []string `mapstructure:"steam_ids"`
 }
 
 type RateLimitConfig struct {
@@ -7,13 +7,68 @@ import (
 )
 
 type Build struct {
-	ID          uuid.UUID `json:"id"`
-	AuthorID    uuid.UUID `json:"author_id"`
-	Title       string    `json:"title"`
-	Description string    `json:"description"`
-	GameVersion string    `json:"game_version"`
-	IsPublic    bool      `json:"is_public"`
-	ViewCount   int       `json:"view_count"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID            uuid.UUID `json:"id"`
+	AuthorID      uuid.UUID `json:"author_id"`
+	Author        *User     `json:"author,omitempty" gorm:"foreignKey:AuthorID"`
+	HeroID        int       `json:"hero_id"`
+	Title         string    `json:"title"`
+	Description   string    `json:"description"`
+	GameVersion   string    `json:"game_version"`
+	IsPublic      bool      `json:"is_public"`
+	ViewCount     int       `json:"view_count"`
+	CommentsCount int       `json:"comments_count" gorm:"default:0"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+
+	Items           []BuildItem           `json:"items,omitempty" gorm:"foreignKey:BuildID"`
+	AbilityUpgrades []BuildAbilityUpgrade `json:"ability_upgrades,omitempty" gorm:"foreignKey:BuildID"`
+	Stats           *BuildStats           `json:"stats,omitempty" gorm:"foreignKey:BuildID"`
+}
+
+// BuildItem is one item of a build. Items are grouped into named phases
+// (early, mid, late...) ordered by PhasePosition, and ordered by Position
+// within their phase.
+type BuildItem struct {
+	BuildID       uuid.UUID `json:"build_id" gorm:"primaryKey"`
+	PhasePosition int       `json:"phase_position" gorm:"primaryKey"`
+	Phase         string    `json:"phase"`
+	Position      int       `json:"position" gorm:"primaryKey"`
+	ItemID        int       `json:"item_id"`
+}
+
+// BuildAbilityUpgrade is one step of a build's ability upgrade order.
+type BuildAbilityUpgrade struct {
+	BuildID     uuid.UUID `json:"build_id" gorm:"primaryKey"`
+	Position    int       `json:"position" gorm:"primaryKey"`
+	AbilitySlot int       `json:"ability_slot"`
+}
+
+// BuildStats is how the recent matches that followed a public build went.
+// A match follows a build when the player, on the build's hero, bought
+// enough of the build's items; AvgAdherence is the average share bought.
+// WinRate is a percentage.
+type BuildStats struct {
+	BuildID      uuid.UUID `json:"build_id" gorm:"primaryKey"`
+	Matches      int       `json:"matches"`
+	Wins         int       `json:"wins"`
+	WinRate      float64   `json:"win_rate"`
+	AvgKDA       float64   `json:"avg_kda" gorm:"column:avg_kda"`
+	AvgAdherence float64   `json:"avg_adherence"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// BuildFilter selects one page of builds. Zero AuthorID and HeroID match
+// every author and hero; private builds are only listed with
+// IncludePrivate.
+type BuildFilter struct {
+	AuthorID       uuid.UUID
+	HeroID         int
+	IncludePrivate bool
+	Sort           string // "new", "win_rate", "matches" or "kda"
+	Page           int
+	Limit          int
+
+	// MinSample is how many matches the win_rate and kda sorts require
+	// before a build ranks above builds without stats.
+	MinSample int
 }
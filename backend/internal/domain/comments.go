@@ -6,12 +6,38 @@ import (
 	"github.com/google/uuid"
 )
 
+// Content types that can be commented on.
+const (
+	CommentContentCrosshair = "crosshair"
+	CommentContentBuild     = "build"
+)
+
+// Comment is a comment on a crosshair or build. Replies point to their
+// parent comment; Depth is 0 for top-level comments. Deleted comments keep
+// their place in the thread without their body.
 type Comment struct {
-	ID          uuid.UUID `json:"id"`
-	AuthorID    uuid.UUID `json:"author_id"`
-	ParentID    uuid.UUID `json:"parent_id"`
-	ContentType string    `json:"content_type"`
-	ContentID   uuid.UUID `json:"content_id"`
-	Body        string    `json:"body"`
-	CreatedAt   time.Time `json:"created_at"`
+	ID          uuid.UUID  `json:"id"`
+	AuthorID    uuid.UUID  `json:"author_id"`
+	Author      *User      `json:"author,omitempty" gorm:"foreignKey:AuthorID"`
+	ParentID    *uuid.UUID `json:"parent_id,omitempty"`
+	ContentType string     `json:"content_type"`
+	ContentID   uuid.UUID  `json:"content_id"`
+	Depth       int        `json:"depth"`
+	Body        string     `json:"body"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   *time.Time `json:"updated_at,omitempty"`
+	DeletedAt   *time.Time `json:"deleted_at,omitempty"`
+
+	// RepliesCount is only loaded when listing comments.
+	RepliesCount int `json:"-" gorm:"->;column:replies_count"`
+}
+
+// CommentFilter selects one page of a thread level: the top-level comments
+// of a content when ParentID is nil, or the replies to ParentID.
+type CommentFilter struct {
+	ContentType string
+	ContentID   uuid.UUID
+	ParentID    *uuid.UUID
+	Page        int
+	Limit       int
 }
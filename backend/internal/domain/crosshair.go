@@ -22,22 +22,113 @@ type CrosshairSettings struct {
 }
 
 type Crosshair struct {
+	ID            uuid.UUID       `json:"id" db:"id"`
+	AuthorID      uuid.UUID       `json:"author_id" db:"author_id"`
+	Author        *User           `json:"author,omitempty" gorm:"foreignKey:AuthorID"`
+	Title         string          `json:"title" db:"title"`
+	Description   string          `json:"description" db:"description"`
+	Settings      json.RawMessage `json:"settings" db:"settings" gorm:"type:jsonb"`
+	LikesCount    int             `json:"likes_count" db:"likes_count"`
+	IsPublic      bool            `json:"is_public" db:"is_public" gorm:"default:false"`
+	ViewCount     int             `json:"view_count" db:"view_count" gorm:"default:0"`
+	ForkedFrom    *uuid.UUID      `json:"forked_from,omitempty" db:"forked_from"`
+	ForksCount    int             `json:"forks_count" db:"forks_count" gorm:"default:0"`
+	CommentsCount int             `json:"comments_count" db:"comments_count" gorm:"default:0"`
+	CreatedAt     time.Time       `json:"created_at" db:"created_at"`
+	UpdatedAt     time.Time       `json:"updated_at" db:"updated_at"`
+
+	// TrendingScore is only loaded by the trending gallery sort.
+	TrendingScore float64 `json:"-" gorm:"->;column:trending_score"`
+}
+
+// CrosshairFilter selects one page of the crosshair gallery. Public
+// crosshairs are always listed; private ones only to their author.
+type CrosshairFilter struct {
+	ViewerID uuid.UUID
+	Sort     string // "new", "top" or "trending"
+	After    *CrosshairKeyset
+	Limit    int
+
+	// Trending scores every like and, weighted by TrendingViewWeight, every
+	// view since TrendingAt-TrendingWindow, halving their weight every
+	// TrendingHalfLife.
+	TrendingAt         time.Time
+	TrendingWindow     time.Duration
+	TrendingHalfLife   time.Duration
+	TrendingViewWeight float64
+
+	Dot          *bool
+	ColorFamily  string
+	MinThickness *int
+	MaxThickness *int
+	Tag          string
+}
+
+// CrosshairKeyset marks the last crosshair of a gallery page: the value of
+// the sort column and the ID that breaks ties.
+type CrosshairKeyset struct {
+	Value string
+	ID    uuid.UUID
+}
+
+type CrosshairLike struct {
+	ID          uuid.UUID `json:"id" db:"id"`
+	UserID      uuid.UUID `json:"user_id" db:"user_id"`
+	CrosshairID uuid.UUID `json:"crosshair_id" db:"crosshair_id"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+}
+
+// CrosshairRevision is a crosshair as it was before one of its edits.
+type CrosshairRevision struct {
 	ID          uuid.UUID       `json:"id" db:"id"`
-	AuthorID    uuid.UUID       `json:"author_id" db:"author_id"`
-	Author      *User           `json:"author,omitempty" gorm:"foreignKey:AuthorID"`
+	CrosshairID uuid.UUID       `json:"crosshair_id" db:"crosshair_id"`
 	Title       string          `json:"title" db:"title"`
 	Description string          `json:"description" db:"description"`
 	Settings    json.RawMessage `json:"settings" db:"settings" gorm:"type:jsonb"`
-	LikesCount  int             `json:"likes_count" db:"likes_count"`
-	IsPublic    bool            `json:"is_public" db:"is_public" gorm:"default:false"`
-	ViewCount   int             `json:"view_count" db:"view_count" gorm:"default:0"`
+	IsPublic    bool            `json:"is_public" db:"is_public"`
 	CreatedAt   time.Time       `json:"created_at" db:"created_at"`
-	UpdatedAt   time.Time       `json:"updated_at" db:"updated_at"`
 }
 
-type CrosshairLike struct {
-	ID          uuid.UUID `json:"id" db:"id"`
-	UserID      uuid.UUID `json:"user_id" db:"user_id"`
+// CrosshairDailyViews counts the deduplicated views of a crosshair on one
+// UTC day.
+type CrosshairDailyViews struct {
 	CrosshairID uuid.UUID `json:"crosshair_id" db:"crosshair_id"`
+	Day         time.Time `json:"day" db:"day"`
+	Views       int       `json:"views" db:"views"`
+}
+
+// CrosshairViewStats is one of an author's crosshairs with its views in the
+// requested window.
+type CrosshairViewStats struct {
+	ID          uuid.UUID `json:"id"`
+	Title       string    `json:"title"`
+	IsPublic    bool      `json:"is_public"`
+	ViewCount   int       `json:"view_count"`
+	LikesCount  int       `json:"likes_count"`
+	RecentViews int       `json:"recent_views"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// CrosshairCollection is a named, ordered list of crosshairs curated by a
+// user, e.g. one crosshair per hero.
+type CrosshairCollection struct {
+	ID          uuid.UUID `json:"id" db:"id"`
+	OwnerID     uuid.UUID `json:"owner_id" db:"owner_id"`
+	Owner       *User     `json:"owner,omitempty" gorm:"foreignKey:OwnerID"`
+	Name        string    `json:"name" db:"name"`
+	Description string    `json:"description" db:"description"`
+	IsPublic    bool      `json:"is_public" db:"is_public"`
 	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+
+	// ItemsCount is only loaded when listing collections.
+	ItemsCount int `json:"-" gorm:"->;column:items_count"`
+}
+
+type CrosshairCollectionItem struct {
+	CollectionID uuid.UUID  `json:"collection_id" db:"collection_id"`
+	CrosshairID  uuid.UUID  `json:"crosshair_id" db:"crosshair_id"`
+	Crosshair    *Crosshair `json:"crosshair,omitempty" gorm:"foreignKey:CrosshairID"`
+	Position     int        `json:"position" db:"position"`
+	AddedAt      time.Time  `json:"added_at" db:"added_at"`
 }
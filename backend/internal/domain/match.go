@@ -27,3 +27,35 @@ type Match struct {
 	MatchTime            time.Time `json:"match_time,omitempty"`
 	Result               string    `json:"result"`
 }
+
+// MatchParticipant is a tracked user that played in a locally stored match.
+type MatchParticipant struct {
+	User      `gorm:"embedded"`
+	HeroName  string    `json:"hero_name"`
+	MatchTime time.Time `json:"match_time"`
+}
+
+// MatchDetails is a match as ingested from the Deadlock API, with every
+// player and the items they bought.
+type MatchDetails struct {
+	MatchID   int64
+	StartTime time.Time
+	DurationS int
+	Players   []MatchDetailsPlayer
+}
+
+type MatchDetailsPlayer struct {
+	AccountID int64
+	HeroID    int
+	Team      int
+	Won       bool
+	Kills     int
+	Deaths    int
+	Assists   int
+	Items     []MatchItemPurchase
+}
+
+type MatchItemPurchase struct {
+	ItemID    int64
+	GameTimeS int
+}
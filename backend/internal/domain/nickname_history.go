@@ -0,0 +1,27 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type NicknameHistory struct {
+	ID          uuid.UUID `json:"id"`
+	UserID      uuid.UUID `json:"user_id"`
+	Nickname    string    `json:"nickname"`
+	FirstSeenAt time.Time `json:"first_seen_at"`
+	LastSeenAt  time.Time `json:"last_seen_at"`
+}
+
+func (NicknameHistory) TableName() string {
+	return "user_nickname_history"
+}
+
+// NicknameAliasMatch is a user found through one of their former nicknames.
+type NicknameAliasMatch struct {
+	User            `gorm:"embedded"`
+	MatchedAlias    string    `json:"matched_alias"`
+	AliasLastSeenAt time.Time `json:"alias_last_seen_at"`
+	Relevance       float64   `json:"relevance"`
+}
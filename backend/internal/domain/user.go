@@ -1,17 +1,105 @@
 package domain
 
 import (
+	"strconv"
 	"time"
 
 	"github.com/google/uuid"
 )
 
 type User struct {
-	ID         uuid.UUID `json:"id"`
-	SteamID    string    `json:"steam_id"`
-	Nickname   string    `json:"nickname"`
-	AvatarURL  string    `json:"avatar_url"`
-	ProfileURL string    `json:"profile_url"`
-	CreatedAt  time.Time `json:"created_at"`
-	UpdatedAt  time.Time `json:"updated_at"`
+	ID          uuid.UUID `json:"id"`
+	SteamID     string    `json:"steam_id"`
+	Nickname    string    `json:"nickname"`
+	AvatarURL   string    `json:"avatar_url"`
+	ProfileURL  string    `json:"profile_url"`
+	CountryCode string    `json:"country_code,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// RankedUser is a user returned by a nickname search together with its
+// relevance score (0..1, higher is better).
+type RankedUser struct {
+	User      `gorm:"embedded"`
+	Relevance float64 `json:"relevance"`
+}
+
+// UserWithStats is a user joined with the headline numbers from player_stats.
+type UserWithStats struct {
+	User         `gorm:"embedded"`
+	PlayerRank   int     `json:"player_rank"`
+	TotalMatches int     `json:"total_matches"`
+	WinRate      float64 `json:"win_rate"`
+	KDRatio      float64 `json:"kd_ratio"`
+	Relevance    float64 `json:"relevance"`
+}
+
+// Ranked players have a player_rank of tier*10+sub-tier in this range.
+// player_stats rows of players that never synced keep the column default,
+// which lies outside it.
+const (
+	MinPlayerRank = 11
+	MaxPlayerRank = 116
+)
+
+// PlayerStatsFilter narrows a player search by player_stats columns. Zero
+// values mean "no bound".
+type PlayerStatsFilter struct {
+	MinRank    int
+	MaxRank    int
+	MinMatches int
+	MaxMatches int
+	MinWinRate float64
+	MaxWinRate float64
+	MinKDRatio float64
+	MaxKDRatio float64
+	Hero       string
+	Country    string
+	SortBy     string
+	SortOrder  string
+}
+
+// SearchKeyset marks the last row of a search page: the value of the sort
+// column rendered as text and the user ID that breaks ties.
+type SearchKeyset struct {
+	Value string
+	ID    uuid.UUID
+}
+
+func (u User) sortValue(sortBy string) string {
+	switch sortBy {
+	case "created_at":
+		return u.CreatedAt.Format(time.RFC3339Nano)
+	case "updated_at":
+		return u.UpdatedAt.Format(time.RFC3339Nano)
+	default:
+		return u.Nickname
+	}
+}
+
+// Keyset returns the position right after this user when sorting by sortBy.
+func (u RankedUser) Keyset(sortBy string) SearchKeyset {
+	if sortBy == "relevance" {
+		return SearchKeyset{Value: strconv.FormatFloat(u.Relevance, 'g', -1, 64), ID: u.ID}
+	}
+	return SearchKeyset{Value: u.sortValue(sortBy), ID: u.ID}
+}
+
+// Keyset returns the position right after this user when sorting by sortBy.
+func (u UserWithStats) Keyset(sortBy string) SearchKeyset {
+	switch sortBy {
+	case "rank":
+		return SearchKeyset{Value: strconv.Itoa(u.PlayerRank), ID: u.ID}
+	case "matches":
+		return SearchKeyset{Value: strconv.Itoa(u.TotalMatches), ID: u.ID}
+	case "win_rate":
+		return SearchKeyset{Value: strconv.FormatFloat(u.WinRate, 'g', -1, 64), ID: u.ID}
+	case "kd_ratio":
+		return SearchKeyset{Value: strconv.FormatFloat(u.KDRatio, 'g', -1, 64), ID: u.ID}
+	case "relevance":
+		return SearchKeyset{Value: strconv.FormatFloat(u.Relevance, 'g', -1, 64), ID: u.ID}
+	default:
+		return SearchKeyset{Value: u.sortValue(sortBy), ID: u.ID}
+	}
 }
@@ -35,4 +35,6 @@ type ExtendedPlayerProfile struct {
 	MateStats          []domain.MateStat       `json:"mate_stats"`
 	HeroMMRHistory     []domain.HeroMMRHistory `json:"hero_mmr_history"`
 	LastUpdatedAt      time.Time               `json:"last_updated_at"`
+
+	PreviousNicknames []domain.NicknameHistory `json:"previous_nicknames"`
 }
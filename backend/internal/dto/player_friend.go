@@ -0,0 +1,20 @@
+package dto
+
+import "time"
+
+type PlayerFriend struct {
+	UserSearchResult
+	FriendSince *time.Time `json:"friend_since,omitempty"`
+}
+
+// PlayerFriends lists the friends known to play Deadlock. Friends whose
+// status could not be checked yet are only counted in UncheckedCount; they
+// are probed in the background, so asking again later may list more.
+type PlayerFriends struct {
+	SteamID        string         `json:"steam_id"`
+	Results        []PlayerFriend `json:"results"`
+	TotalCount     int            `json:"total_count"`
+	FriendCount    int            `json:"friend_count"`
+	UncheckedCount int            `json:"unchecked_count"`
+	Partial        bool           `json:"partial"`
+}
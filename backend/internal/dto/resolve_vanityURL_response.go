@@ -1,8 +0,0 @@
-package dto
-
-type ResolveVanityURLResponse struct {
-	Response struct {
-		SteamID string `json:"steamid"`
-		Success int    `json:"success"`
-	} `json:"response"`
-}
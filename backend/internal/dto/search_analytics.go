@@ -0,0 +1,27 @@
+package dto
+
+type TrendingPlayer struct {
+	UserSearchResult
+	Views int64 `json:"views"`
+}
+
+type SearchQueryCount struct {
+	Query string `json:"query"`
+	Count int64  `json:"count"`
+}
+
+type SearchLatencyStats struct {
+	Samples int     `json:"samples"`
+	P50Ms   float64 `json:"p50_ms"`
+	P90Ms   float64 `json:"p90_ms"`
+	P99Ms   float64 `json:"p99_ms"`
+}
+
+type SearchStats struct {
+	Days               int                `json:"days"`
+	TotalSearches      int64              `json:"total_searches"`
+	ZeroResultSearches int64              `json:"zero_result_searches"`
+	TopQueries         []SearchQueryCount `json:"top_queries"`
+	ZeroResultQueries  []SearchQueryCount `json:"zero_result_queries"`
+	Latency            SearchLatencyStats `json:"latency"`
+}
@@ -1,9 +1,13 @@
 package dto
 
-import "fmt"
+import (
+	"fmt"
+
+	"github.com/quenyu/deadlock-stats/internal/domain"
+)
 
 type SearchFilters struct {
-	SearchType string  `json:"search_type"` // "all", "steamid", "nickname"
+	SearchType string  `json:"search_type"` // "all", "steamid", "nickname", "match"
 	MinRank    int     `json:"min_rank"`
 	MaxRank    int     `json:"max_rank"`
 	MinMatches int     `json:"min_matches"`
@@ -12,11 +16,28 @@ type SearchFilters struct {
 	MaxWinRate float64 `json:"max_win_rate"`
 	MinKDRatio float64 `json:"min_kd_ratio"`
 	MaxKDRatio float64 `json:"max_kd_ratio"`
-	SortBy     string  `json:"sort_by"`    // "rank", "matches", "win_rate", "kd_ratio", "nickname", "created_at", "updated_at"
-	SortOrder  string  `json:"sort_order"` // "asc", "desc"
+	Hero       string  `json:"hero,omitempty"`
+	Country    string  `json:"country,omitempty"` // ISO 3166-1 alpha-2
+	SortBy     string  `json:"sort_by"`           // "relevance", "rank", "matches", "win_rate", "kd_ratio", "nickname", "created_at", "updated_at"
+	SortOrder  string  `json:"sort_order"`        // "asc", "desc"
 }
 
 func (f *SearchFilters) Validate() error {
+	if f.MinRank < 0 || f.MaxRank < 0 || f.MinMatches < 0 || f.MaxMatches < 0 ||
+		f.MinWinRate < 0 || f.MaxWinRate < 0 || f.MinKDRatio < 0 || f.MaxKDRatio < 0 {
+		return fmt.Errorf("stat filters cannot be negative")
+	}
+	for _, rank := range []int{f.MinRank, f.MaxRank} {
+		if rank != 0 && (rank < domain.MinPlayerRank || rank > domain.MaxPlayerRank) {
+			return fmt.Errorf("rank filters must be between %d and %d", domain.MinPlayerRank, domain.MaxPlayerRank)
+		}
+	}
+	if f.MinWinRate > 100 || f.MaxWinRate > 100 {
+		return fmt.Errorf("win_rate filters must be between 0 and 100")
+	}
+	if f.Country != "" && len(f.Country) != 2 {
+		return fmt.Errorf("country must be a two-letter country code")
+	}
 	if f.MinRank > f.MaxRank && f.MaxRank != 0 {
 		return fmt.Errorf("min_rank cannot be greater than max_rank")
 	}
@@ -31,6 +52,7 @@ func (f *SearchFilters) Validate() error {
 	}
 
 	validSortBy := map[string]bool{
+		"relevance":  true,
 		"rank":       true,
 		"matches":    true,
 		"win_rate":   true,
@@ -74,3 +96,81 @@ func (f *SearchFilters) GetSearchType() string {
 	}
 	return f.SearchType
 }
+
+// HasStatFilters reports whether any filter can only be applied in SQL
+// against player_stats and the other player tables.
+func (f *SearchFilters) HasStatFilters() bool {
+	return f.MinRank != 0 || f.MaxRank != 0 ||
+		f.MinMatches != 0 || f.MaxMatches != 0 ||
+		f.MinWinRate != 0 || f.MaxWinRate != 0 ||
+		f.MinKDRatio != 0 || f.MaxKDRatio != 0 ||
+		f.Hero != "" || f.Country != ""
+}
+
+// IsStatSort reports whether results are sorted by a player_stats column.
+func (f *SearchFilters) IsStatSort() bool {
+	switch f.SortBy {
+	case "rank", "matches", "win_rate", "kd_ratio":
+		return true
+	default:
+		return false
+	}
+}
+
+func (f *SearchFilters) StatsFilter() domain.PlayerStatsFilter {
+	return domain.PlayerStatsFilter{
+		MinRank:    f.MinRank,
+		MaxRank:    f.MaxRank,
+		MinMatches: f.MinMatches,
+		MaxMatches: f.MaxMatches,
+		MinWinRate: f.MinWinRate,
+		MaxWinRate: f.MaxWinRate,
+		MinKDRatio: f.MinKDRatio,
+		MaxKDRatio: f.MaxKDRatio,
+		Hero:       f.Hero,
+		Country:    f.Country,
+		SortBy:     f.GetDefaultSortBy(),
+		SortOrder:  f.GetDefaultSortOrder(),
+	}
+}
+
+// Override copies every filter that is set in other onto f.
+func (f *SearchFilters) Override(other SearchFilters) {
+	if other.SearchType != "" {
+		f.SearchType = other.SearchType
+	}
+	if other.MinRank != 0 {
+		f.MinRank = other.MinRank
+	}
+	if other.MaxRank != 0 {
+		f.MaxRank = other.MaxRank
+	}
+	if other.MinMatches != 0 {
+		f.MinMatches = other.MinMatches
+	}
+	if other.MaxMatches != 0 {
+		f.MaxMatches = other.MaxMatches
+	}
+	if other.MinWinRate != 0 {
+		f.MinWinRate = other.MinWinRate
+	}
+	if other.MaxWinRate != 0 {
+		f.MaxWinRate = other.MaxWinRate
+	}
+	if other.MinKDRatio != 0 {
+		f.MinKDRatio = other.MinKDRatio
+	}
+	if other.MaxKDRatio != 0 {
+		f.MaxKDRatio = other.MaxKDRatio
+	}
+	if other.Hero != "" {
+		f.Hero = other.Hero
+	}
+	if other.Country != "" {
+		f.Country = other.Country
+	}
+	if other.SortBy != "" {
+		f.SortBy = other.SortBy
+		f.SortOrder = other.SortOrder
+	}
+}
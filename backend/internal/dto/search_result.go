@@ -6,4 +6,5 @@ type SearchResult struct {
 	Page       int                `json:"page"`
 	PageSize   int                `json:"page_size"`
 	TotalPages int                `json:"total_pages"`
+	NextCursor string             `json:"next_cursor,omitempty"`
 }
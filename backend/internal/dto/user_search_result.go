@@ -16,6 +16,26 @@ type UserSearchResult struct {
 	LastUpdated int64  `json:"last_updated,omitempty"`
 	Realname    string `json:"realname,omitempty"`
 
+	// Stats are only filled by searches that join player_stats
+	PlayerRank   int     `json:"player_rank,omitempty"`
+	TotalMatches int     `json:"total_matches,omitempty"`
+	WinRate      float64 `json:"win_rate,omitempty"`
+	KDRatio      float64 `json:"kd_ratio,omitempty"`
+
+	// Relevance is the search score in the 0..1 range, higher is better
+	Relevance float64 `json:"relevance"`
+
 	IsDeadlockPlayer    bool `json:"is_deadlock_player"`
 	DeadlockStatusKnown bool `json:"deadlock_status_known"`
+
+	// Set when the query was a match ID and the user played in that match
+	MatchID       string `json:"match_id,omitempty"`
+	MatchURL      string `json:"match_url,omitempty"`
+	MatchHeroID   int    `json:"match_hero_id,omitempty"`
+	MatchHeroName string `json:"match_hero_name,omitempty"`
+	MatchTeam     *int   `json:"match_team,omitempty"`
+
+	// Set when the user was found through a former nickname
+	MatchedAlias    string     `json:"matched_alias,omitempty"`
+	AliasLastSeenAt *time.Time `json:"alias_last_seen_at,omitempty"`
 }
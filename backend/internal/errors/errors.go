@@ -10,6 +10,7 @@ var (
 	ErrRateLimited       = errors.New("rate limited")
 	ErrAPIUnavailable    = errors.New("external api unavailable")
 	ErrPlayerDataMissing = errors.New("player data missing")
+	ErrPrivateProfile    = errors.New("steam profile is private")
 
 	// --- Auth-related errors ---
 	ErrUserNotFound        = errors.New("user not found")
@@ -26,8 +27,16 @@ var (
 	ErrInvalidCrosshairID = errors.New("invalid crosshair ID")
 	ErrInvalidUserID      = errors.New("invalid user ID")
 	ErrCrosshairForbidden = errors.New("not allowed to modify this crosshair")
+	ErrRevisionNotFound   = errors.New("crosshair revision not found")
 	ErrInvalidRequestBody = errors.New("invalid request body")
 
+	// --- Collection-related errors ---
+	ErrCollectionNotFound  = errors.New("collection not found")
+	ErrInvalidCollectionID = errors.New("invalid collection ID")
+	ErrCollectionForbidden = errors.New("not allowed to modify this collection")
+	ErrCollectionNameTaken = errors.New("collection name already in use")
+	ErrCollectionLimit     = errors.New("collection limit reached")
+
 	// --- Match / Search-related errors ---
 	ErrMatchNotFound   = errors.New("match not found")
 	ErrInvalidSearch   = errors.New("invalid search type")
@@ -47,10 +56,19 @@ var (
 
 	// --- Build-related errors ---
 	ErrBuildNotFound  = errors.New("build not found")
+	ErrInvalidBuild   = errors.New("invalid build")
 	ErrInvalidBuildID = errors.New("invalid build ID")
 	ErrBuildForbidden = errors.New("not allowed to modify this build")
 	ErrInvalidItemID  = errors.New("invalid item ID")
 	ErrInvalidAbility = errors.New("invalid ability")
+	ErrInvalidHeroID  = errors.New("invalid hero ID")
+	ErrInvalidPhase   = errors.New("invalid build phase")
+
+	// --- Comment-related errors ---
+	ErrCommentNotFound  = errors.New("comment not found")
+	ErrInvalidCommentID = errors.New("invalid comment ID")
+	ErrCommentForbidden = errors.New("not allowed to modify this comment")
+	ErrInvalidComment   = errors.New("invalid comment")
 
 	// --- System / Internal errors ---
 	ErrDatabaseError   = errors.New("database operation failed")
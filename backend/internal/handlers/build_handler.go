@@ -0,0 +1,266 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	cErrors "github.com/quenyu/deadlock-stats/internal/errors"
+	"github.com/quenyu/deadlock-stats/internal/services"
+	"github.com/quenyu/deadlock-stats/internal/validators"
+)
+
+// maxBuildImportBody is read from an import request; larger inputs are
+// rejected by the parser.
+const maxBuildImportBody = 512 << 10
+
+type BuildHandler struct {
+	service *services.BuildService
+}
+
+func NewBuildHandler(service *services.BuildService) *BuildHandler {
+	return &BuildHandler{service: service}
+}
+
+func (h *BuildHandler) Create(c echo.Context) error {
+	req, err := bindBuildRequest(c)
+	if err != nil {
+		return ErrorHandler(err, c)
+	}
+	authorID, err := uuid.Parse(c.Get("userID").(string))
+	if err != nil {
+		return ErrorHandler(cErrors.ErrInvalidUserID, c)
+	}
+	build, err := h.service.Create(authorID, req)
+	if err != nil {
+		return ErrorHandler(err, c)
+	}
+	return c.JSON(http.StatusCreated, build)
+}
+
+func (h *BuildHandler) Update(c echo.Context) error {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return ErrorHandler(cErrors.ErrInvalidBuildID, c)
+	}
+	req, err := bindBuildRequest(c)
+	if err != nil {
+		return ErrorHandler(err, c)
+	}
+	authorID, err := uuid.Parse(c.Get("userID").(string))
+	if err != nil {
+		return ErrorHandler(cErrors.ErrInvalidUserID, c)
+	}
+	build, err := h.service.Update(id, authorID, req)
+	if err != nil {
+		return ErrorHandler(err, c)
+	}
+	return c.JSON(http.StatusOK, build)
+}
+
+func (h *BuildHandler) GetByID(c echo.Context) error {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return ErrorHandler(cErrors.ErrInvalidBuildID, c)
+	}
+	build, err := h.service.GetByID(id, viewerID(c))
+	if err != nil {
+		return ErrorHandler(err, c)
+	}
+	return c.JSON(http.StatusOK, build)
+}
+
+// GetStats returns the win rate and KDA of the recent matches that followed
+// a build.
+func (h *BuildHandler) GetStats(c echo.Context) error {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return ErrorHandler(cErrors.ErrInvalidBuildID, c)
+	}
+	stats, err := h.service.GetStats(id, viewerID(c))
+	if err != nil {
+		return ErrorHandler(err, c)
+	}
+	return c.JSON(http.StatusOK, stats)
+}
+
+// GetAll lists public builds filtered by hero_id, in sort order: new
+// (default), win_rate, matches or kda.
+func (h *BuildHandler) GetAll(c echo.Context) error {
+	query, err := buildListQuery(c)
+	if err != nil {
+		return ErrorHandler(err, c)
+	}
+	builds, err := h.service.List(query, viewerID(c))
+	if err != nil {
+		return ErrorHandler(err, c)
+	}
+	return c.JSON(http.StatusOK, builds)
+}
+
+// GetByAuthorID lists an author's builds, private ones only for the author.
+func (h *BuildHandler) GetByAuthorID(c echo.Context) error {
+	authorID, err := uuid.Parse(c.Param("author_id"))
+	if err != nil {
+		return ErrorHandler(cErrors.ErrInvalidUserID, c)
+	}
+	query, err := buildListQuery(c)
+	if err != nil {
+		return ErrorHandler(err, c)
+	}
+	query.AuthorID = authorID
+	builds, err := h.service.List(query, viewerID(c))
+	if err != nil {
+		return ErrorHandler(err, c)
+	}
+	return c.JSON(http.StatusOK, builds)
+}
+
+func (h *BuildHandler) Delete(c echo.Context) error {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return ErrorHandler(cErrors.ErrInvalidBuildID, c)
+	}
+	authorID, err := uuid.Parse(c.Get("userID").(string))
+	if err != nil {
+		return ErrorHandler(cErrors.ErrInvalidUserID, c)
+	}
+	if err := h.service.Delete(id, authorID); err != nil {
+		return ErrorHandler(err, c)
+	}
+	return c.JSON(http.StatusOK, map[string]string{"message": "Deleted successfully"})
+}
+
+func (h *BuildHandler) Export(c echo.Context) error {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return ErrorHandler(cErrors.ErrInvalidBuildID, c)
+	}
+	export, err := h.service.ExportGameBuild(id, viewerID(c))
+	if err != nil {
+		return ErrorHandler(err, c)
+	}
+	c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", export.Filename))
+	return c.Blob(http.StatusOK, export.ContentType, export.Content)
+}
+
+// Import reads a hero build file exported by the game, JSON or KeyValues,
+// from the request body and returns it as a build request without saving
+// it.
+func (h *BuildHandler) Import(c echo.Context) error {
+	data, err := io.ReadAll(io.LimitReader(c.Request().Body, maxBuildImportBody))
+	if err != nil {
+		return ErrorHandler(cErrors.ErrInvalidRequestBody, c)
+	}
+	req, err := h.service.ImportGameBuild(data)
+	if err != nil {
+		return ErrorHandler(err, c)
+	}
+	if err := validateBuildInput(req); err != nil {
+		return ErrorHandler(err, c)
+	}
+	if err := h.service.ValidateGameData(req); err != nil {
+		return ErrorHandler(err, c)
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{"build": req})
+}
+
+func buildListQuery(c echo.Context) (services.BuildListQuery, error) {
+	query := services.BuildListQuery{Sort: "new", Page: 1, Limit: 20}
+	if s := c.QueryParam("sort"); s != "" {
+		if !slices.Contains(services.BuildSorts, s) {
+			return query, cErrors.ErrInvalidQuery
+		}
+		query.Sort = s
+	}
+	if p := c.QueryParam("page"); p != "" {
+		if _, err := fmt.Sscanf(p, "%d", &query.Page); err != nil {
+			return query, cErrors.ErrInvalidQuery
+		}
+	}
+	if l := c.QueryParam("limit"); l != "" {
+		if _, err := fmt.Sscanf(l, "%d", &query.Limit); err != nil {
+			return query, cErrors.ErrInvalidQuery
+		}
+	}
+	if h := c.QueryParam("hero_id"); h != "" {
+		if _, err := fmt.Sscanf(h, "%d", &query.HeroID); err != nil {
+			return query, cErrors.ErrInvalidQuery
+		}
+		if err := validators.ValidateHeroID(query.HeroID); err != nil {
+			return query, err
+		}
+	}
+	if err := validators.ValidateIntRange(query.Page, 1, 1000); err != nil {
+		return query, err
+	}
+	if err := validators.ValidateIntRange(query.Limit, 1, 100); err != nil {
+		return query, err
+	}
+	return query, nil
+}
+
+func bindBuildRequest(c echo.Context) (*services.BuildRequest, error) {
+	var req services.BuildRequest
+	if err := c.Bind(&req); err != nil {
+		return nil, cErrors.ErrInvalidRequestBody
+	}
+	req.Title = strings.TrimSpace(req.Title)
+	req.Description = strings.TrimSpace(req.Description)
+	req.GameVersion = strings.TrimSpace(req.GameVersion)
+	for i := range req.Phases {
+		req.Phases[i].Name = strings.TrimSpace(req.Phases[i].Name)
+	}
+
+	if err := validateBuildInput(&req); err != nil {
+		return nil, err
+	}
+	return &req, nil
+}
+
+// validateBuildInput checks the shape of a build request and reports every
+// failing field. The build service checks it against the game data.
+func validateBuildInput(req *services.BuildRequest) error {
+	errs := validators.NewFieldErrors(cErrors.ErrInvalidBuild)
+	errs.Check("title", validators.ValidateBuildTitle(req.Title))
+	errs.Check("description", validators.ValidateBuildDescription(req.Description))
+	errs.Check("hero_id", validators.ValidateHeroID(req.HeroID))
+	if req.GameVersion != "" {
+		errs.Check("game_version", validators.ValidatePatchVersion(req.GameVersion))
+	}
+
+	if len(req.Phases) > services.MaxBuildPhases {
+		errs.Add("phases", "max", strconv.Itoa(len(req.Phases)), fmt.Sprintf("at most %d phases", services.MaxBuildPhases))
+	}
+	phaseNames := make(map[string]bool, len(req.Phases))
+	for i, phase := range req.Phases {
+		field := fmt.Sprintf("phases[%d]", i)
+		if err := validators.ValidateBuildPhaseName(phase.Name); err != nil {
+			errs.Check(field+".name", err)
+		} else if name := strings.ToLower(phase.Name); phaseNames[name] {
+			errs.Add(field+".name", "unique", phase.Name, "phase is listed twice")
+		} else {
+			phaseNames[name] = true
+		}
+
+		if len(phase.Items) == 0 || len(phase.Items) > services.MaxBuildPhaseItems {
+			errs.Add(field+".items", "len", strconv.Itoa(len(phase.Items)), fmt.Sprintf("must have 1 to %d items", services.MaxBuildPhaseItems))
+		}
+		for j, itemID := range phase.Items {
+			errs.Check(fmt.Sprintf("%s.items[%d]", field, j), validators.ValidateItemID(itemID))
+		}
+	}
+
+	if len(req.AbilityOrder) > services.MaxBuildAbilityUpgrades {
+		errs.Add("ability_order", "max", strconv.Itoa(len(req.AbilityOrder)), fmt.Sprintf("at most %d ability upgrades", services.MaxBuildAbilityUpgrades))
+	}
+	for i, slot := range req.AbilityOrder {
+		errs.Check(fmt.Sprintf("ability_order[%d]", i), validators.ValidateAbilitySlot(slot))
+	}
+	return errs.Err()
+}
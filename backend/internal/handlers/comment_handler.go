@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/quenyu/deadlock-stats/internal/domain"
+	cErrors "github.com/quenyu/deadlock-stats/internal/errors"
+	"github.com/quenyu/deadlock-stats/internal/services"
+	"github.com/quenyu/deadlock-stats/internal/validators"
+)
+
+type CommentHandler struct {
+	service *services.CommentService
+}
+
+func NewCommentHandler(service *services.CommentService) *CommentHandler {
+	return &CommentHandler{service: service}
+}
+
+// GetCrosshairComments lists one thread level of a crosshair's comments:
+// top-level comments, or the replies to parent_id.
+func (h *CommentHandler) GetCrosshairComments(c echo.Context) error {
+	return h.list(c, domain.CommentContentCrosshair, cErrors.ErrInvalidCrosshairID)
+}
+
+func (h *CommentHandler) CreateCrosshairComment(c echo.Context) error {
+	return h.create(c, domain.CommentContentCrosshair, cErrors.ErrInvalidCrosshairID)
+}
+
+// GetBuildComments lists one thread level of a build's comments: top-level
+// comments, or the replies to parent_id.
+func (h *CommentHandler) GetBuildComments(c echo.Context) error {
+	return h.list(c, domain.CommentContentBuild, cErrors.ErrInvalidBuildID)
+}
+
+func (h *CommentHandler) CreateBuildComment(c echo.Context) error {
+	return h.create(c, domain.CommentContentBuild, cErrors.ErrInvalidBuildID)
+}
+
+func (h *CommentHandler) Update(c echo.Context) error {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return ErrorHandler(cErrors.ErrInvalidCommentID, c)
+	}
+	authorID, err := uuid.Parse(c.Get("userID").(string))
+	if err != nil {
+		return ErrorHandler(cErrors.ErrInvalidUserID, c)
+	}
+	var req services.UpdateCommentRequest
+	if err := c.Bind(&req); err != nil {
+		return ErrorHandler(cErrors.ErrInvalidRequestBody, c)
+	}
+	if req.Body, err = sanitizeCommentBody(req.Body); err != nil {
+		return ErrorHandler(err, c)
+	}
+	comment, err := h.service.Update(id, authorID, &req)
+	if err != nil {
+		return ErrorHandler(err, c)
+	}
+	return c.JSON(http.StatusOK, comment)
+}
+
+func (h *CommentHandler) Delete(c echo.Context) error {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return ErrorHandler(cErrors.ErrInvalidCommentID, c)
+	}
+	authorID, err := uuid.Parse(c.Get("userID").(string))
+	if err != nil {
+		return ErrorHandler(cErrors.ErrInvalidUserID, c)
+	}
+	if err := h.service.Delete(id, authorID); err != nil {
+		return ErrorHandler(err, c)
+	}
+	return c.JSON(http.StatusOK, map[string]string{"message": "Deleted successfully"})
+}
+
+func (h *CommentHandler) list(c echo.Context, contentType string, invalidID error) error {
+	contentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return ErrorHandler(invalidID, c)
+	}
+	query, err := commentListQuery(c)
+	if err != nil {
+		return ErrorHandler(err, c)
+	}
+	comments, err := h.service.List(contentType, contentID, query, viewerID(c))
+	if err != nil {
+		return ErrorHandler(err, c)
+	}
+	return c.JSON(http.StatusOK, comments)
+}
+
+func (h *CommentHandler) create(c echo.Context, contentType string, invalidID error) error {
+	contentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return ErrorHandler(invalidID, c)
+	}
+	authorID, err := uuid.Parse(c.Get("userID").(string))
+	if err != nil {
+		return ErrorHandler(cErrors.ErrInvalidUserID, c)
+	}
+	var req services.CommentRequest
+	if err := c.Bind(&req); err != nil {
+		return ErrorHandler(cErrors.ErrInvalidRequestBody, c)
+	}
+	if req.Body, err = sanitizeCommentBody(req.Body); err != nil {
+		return ErrorHandler(err, c)
+	}
+	comment, err := h.service.Create(contentType, contentID, authorID, &req)
+	if err != nil {
+		return ErrorHandler(err, c)
+	}
+	return c.JSON(http.StatusCreated, comment)
+}
+
+func commentListQuery(c echo.Context) (services.CommentListQuery, error) {
+	query := services.CommentListQuery{Page: 1, Limit: 20}
+	if p := c.QueryParam("parent_id"); p != "" {
+		parentID, err := uuid.Parse(p)
+		if err != nil {
+			return query, cErrors.ErrInvalidCommentID
+		}
+		query.ParentID = &parentID
+	}
+	var err error
+	if p := c.QueryParam("page"); p != "" {
+		if query.Page, err = strconv.Atoi(p); err != nil {
+			return query, cErrors.ErrInvalidQuery
+		}
+	}
+	if l := c.QueryParam("limit"); l != "" {
+		if query.Limit, err = strconv.Atoi(l); err != nil {
+			return query, cErrors.ErrInvalidQuery
+		}
+	}
+	if err := validators.ValidateIntRange(query.Page, 1, 1000); err != nil {
+		return query, err
+	}
+	if err := validators.ValidateIntRange(query.Limit, 1, 100); err != nil {
+		return query, err
+	}
+	return query, nil
+}
+
+func sanitizeCommentBody(body string) (string, error) {
+	body = validators.SanitizeCommentBody(body)
+	if err := validators.ValidateCommentBody(body); err != nil {
+		return "", err
+	}
+	return body, nil
+}
@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	cErrors "github.com/quenyu/deadlock-stats/internal/errors"
+	"github.com/quenyu/deadlock-stats/internal/services"
+	"github.com/quenyu/deadlock-stats/internal/validators"
+)
+
+type CrosshairCollectionHandler struct {
+	service *services.CrosshairCollectionService
+}
+
+func NewCrosshairCollectionHandler(service *services.CrosshairCollectionService) *CrosshairCollectionHandler {
+	return &CrosshairCollectionHandler{service: service}
+}
+
+type addCollectionCrosshairRequest struct {
+	CrosshairID string `json:"crosshair_id"`
+}
+
+type setCollectionCrosshairsRequest struct {
+	CrosshairIDs []string `json:"crosshair_ids"`
+}
+
+func (h *CrosshairCollectionHandler) Create(c echo.Context) error {
+	ownerID, err := uuid.Parse(c.Get("userID").(string))
+	if err != nil {
+		return ErrorHandler(cErrors.ErrInvalidUserID, c)
+	}
+	req, err := bindCollectionRequest(c)
+	if err != nil {
+		return ErrorHandler(err, c)
+	}
+	collection, err := h.service.Create(ownerID, req)
+	if err != nil {
+		return ErrorHandler(err, c)
+	}
+	return c.JSON(http.StatusCreated, collection)
+}
+
+func (h *CrosshairCollectionHandler) GetByID(c echo.Context) error {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return ErrorHandler(cErrors.ErrInvalidCollectionID, c)
+	}
+	collection, err := h.service.Get(id, viewerID(c))
+	if err != nil {
+		return ErrorHandler(err, c)
+	}
+	return c.JSON(http.StatusOK, collection)
+}
+
+func (h *CrosshairCollectionHandler) GetMine(c echo.Context) error {
+	ownerID, err := uuid.Parse(c.Get("userID").(string))
+	if err != nil {
+		return ErrorHandler(cErrors.ErrInvalidUserID, c)
+	}
+	collections, err := h.service.ListByOwner(ownerID, ownerID)
+	if err != nil {
+		return ErrorHandler(err, c)
+	}
+	return c.JSON(http.StatusOK, collections)
+}
+
+func (h *CrosshairCollectionHandler) GetByAuthorID(c echo.Context) error {
+	authorID, err := uuid.Parse(c.Param("author_id"))
+	if err != nil {
+		return ErrorHandler(cErrors.ErrInvalidUserID, c)
+	}
+	collections, err := h.service.ListByOwner(authorID, viewerID(c))
+	if err != nil {
+		return ErrorHandler(err, c)
+	}
+	return c.JSON(http.StatusOK, collections)
+}
+
+func (h *CrosshairCollectionHandler) Update(c echo.Context) error {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return ErrorHandler(cErrors.ErrInvalidCollectionID, c)
+	}
+	ownerID, err := uuid.Parse(c.Get("userID").(string))
+	if err != nil {
+		return ErrorHandler(cErrors.ErrInvalidUserID, c)
+	}
+	req, err := bindCollectionRequest(c)
+	if err != nil {
+		return ErrorHandler(err, c)
+	}
+	collection, err := h.service.Update(id, ownerID, req)
+	if err != nil {
+		return ErrorHandler(err, c)
+	}
+	return c.JSON(http.StatusOK, collection)
+}
+
+func (h *CrosshairCollectionHandler) Delete(c echo.Context) error {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return ErrorHandler(cErrors.ErrInvalidCollectionID, c)
+	}
+	ownerID, err := uuid.Parse(c.Get("userID").(string))
+	if err != nil {
+		return ErrorHandler(cErrors.ErrInvalidUserID, c)
+	}
+	if err := h.service.Delete(id, ownerID); err != nil {
+		return ErrorHandler(err, c)
+	}
+	return c.JSON(http.StatusOK, map[string]string{"message": "Deleted successfully"})
+}
+
+func (h *CrosshairCollectionHandler) AddCrosshair(c echo.Context) error {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return ErrorHandler(cErrors.ErrInvalidCollectionID, c)
+	}
+	ownerID, err := uuid.Parse(c.Get("userID").(string))
+	if err != nil {
+		return ErrorHandler(cErrors.ErrInvalidUserID, c)
+	}
+	var req addCollectionCrosshairRequest
+	if err := c.Bind(&req); err != nil {
+		return ErrorHandler(cErrors.ErrInvalidRequestBody, c)
+	}
+	crosshairID, err := uuid.Parse(req.CrosshairID)
+	if err != nil {
+		return ErrorHandler(cErrors.ErrInvalidCrosshairID, c)
+	}
+	collection, err := h.service.AddCrosshair(id, ownerID, crosshairID)
+	if err != nil {
+		return ErrorHandler(err, c)
+	}
+	return c.JSON(http.StatusOK, collection)
+}
+
+// SetCrosshairs replaces the crosshairs of a collection; send the current
+// IDs in a new order to reorder it.
+func (h *CrosshairCollectionHandler) SetCrosshairs(c echo.Context) error {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return ErrorHandler(cErrors.ErrInvalidCollectionID, c)
+	}
+	ownerID, err := uuid.Parse(c.Get("userID").(string))
+	if err != nil {
+		return ErrorHandler(cErrors.ErrInvalidUserID, c)
+	}
+	var req setCollectionCrosshairsRequest
+	if err := c.Bind(&req); err != nil {
+		return ErrorHandler(cErrors.ErrInvalidRequestBody, c)
+	}
+	crosshairIDs := make([]uuid.UUID, len(req.CrosshairIDs))
+	for i, rawID := range req.CrosshairIDs {
+		if crosshairIDs[i], err = uuid.Parse(rawID); err != nil {
+			return ErrorHandler(cErrors.ErrInvalidCrosshairID, c)
+		}
+	}
+	collection, err := h.service.SetCrosshairs(id, ownerID, crosshairIDs)
+	if err != nil {
+		return ErrorHandler(err, c)
+	}
+	return c.JSON(http.StatusOK, collection)
+}
+
+func (h *CrosshairCollectionHandler) RemoveCrosshair(c echo.Context) error {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return ErrorHandler(cErrors.ErrInvalidCollectionID, c)
+	}
+	crosshairID, err := uuid.Parse(c.Param("crosshair_id"))
+	if err != nil {
+		return ErrorHandler(cErrors.ErrInvalidCrosshairID, c)
+	}
+	ownerID, err := uuid.Parse(c.Get("userID").(string))
+	if err != nil {
+		return ErrorHandler(cErrors.ErrInvalidUserID, c)
+	}
+	collection, err := h.service.RemoveCrosshair(id, ownerID, crosshairID)
+	if err != nil {
+		return ErrorHandler(err, c)
+	}
+	return c.JSON(http.StatusOK, collection)
+}
+
+func bindCollectionRequest(c echo.Context) (*services.CrosshairCollectionRequest, error) {
+	var req services.CrosshairCollectionRequest
+	if err := c.Bind(&req); err != nil {
+		return nil, cErrors.ErrInvalidRequestBody
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	req.Description = strings.TrimSpace(req.Description)
+
+	if err := validators.ValidateCollectionName(req.Name); err != nil {
+		return nil, err
+	}
+	if err := validators.ValidateCrosshairDescription(req.Description); err != nil {
+		return nil, err
+	}
+	return &req, nil
+}
@@ -3,10 +3,13 @@ package handlers
 import (
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
+	"github.com/quenyu/deadlock-stats/internal/config"
+	"github.com/quenyu/deadlock-stats/internal/domain"
 	cErrors "github.com/quenyu/deadlock-stats/internal/errors"
 	"github.com/quenyu/deadlock-stats/internal/services"
 	"github.com/quenyu/deadlock-stats/internal/validators"
@@ -14,10 +17,12 @@ import (
 
 type CrosshairHandler struct {
 	service *services.CrosshairService
+	views   *services.CrosshairViews
+	config  *config.Config
 }
 
-func NewCrosshairHandler(service *services.CrosshairService) *CrosshairHandler {
-	return &CrosshairHandler{service: service}
+func NewCrosshairHandler(service *services.CrosshairService, views *services.CrosshairViews, config *config.Config) *CrosshairHandler {
+	return &CrosshairHandler{service: service, views: views, config: config}
 }
 
 func (h *CrosshairHandler) Create(c echo.Context) error {
@@ -29,78 +34,140 @@ func (h *CrosshairHandler) Create(c echo.Context) error {
 	req.Title = strings.TrimSpace(req.Title)
 	req.Description = strings.TrimSpace(req.Description)
 
-	if err := validators.ValidateCrosshairTitle(req.Title); err != nil {
+	if err := validateCrosshairInput(req.Title, req.Description, req.Settings); err != nil {
 		return ErrorHandler(err, c)
 	}
-	if err := validators.ValidateCrosshairDescription(req.Description); err != nil {
-		return ErrorHandler(err, c)
+	authorID, err := uuid.Parse(c.Get("userID").(string))
+	if err != nil {
+		return ErrorHandler(cErrors.ErrInvalidUserID, c)
 	}
-	if err := validators.ValidateIntRange(req.Settings.Thickness, 0, 20); err != nil { // example bounds
+	crosshair, err := h.service.Create(authorID, &req)
+	if err != nil {
 		return ErrorHandler(err, c)
 	}
-	if err := validators.ValidateIntRange(req.Settings.Length, 0, 100); err != nil {
-		return ErrorHandler(err, c)
+	return c.JSON(http.StatusCreated, crosshair)
+}
+
+func (h *CrosshairHandler) Update(c echo.Context) error {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return ErrorHandler(cErrors.ErrInvalidCrosshairID, c)
 	}
-	if err := validators.ValidateIntRange(req.Settings.Gap, -50, 100); err != nil {
-		return ErrorHandler(err, c)
+
+	var req services.UpdateCrosshairRequest
+	if err := c.Bind(&req); err != nil {
+		return ErrorHandler(cErrors.ErrInvalidRequestBody, c)
 	}
-	if err := validators.ValidateOpacity(req.Settings.Opacity); err != nil {
+
+	req.Title = strings.TrimSpace(req.Title)
+	req.Description = strings.TrimSpace(req.Description)
+
+	if err := validateCrosshairInput(req.Title, req.Description, req.Settings); err != nil {
 		return ErrorHandler(err, c)
 	}
-	if err := validators.ValidateOpacity(req.Settings.PipOpacity); err != nil {
+	authorID, err := uuid.Parse(c.Get("userID").(string))
+	if err != nil {
+		return ErrorHandler(cErrors.ErrInvalidUserID, c)
+	}
+	crosshair, err := h.service.Update(id, authorID, &req)
+	if err != nil {
 		return ErrorHandler(err, c)
 	}
-	if err := validators.ValidateOpacity(req.Settings.DotOutlineOpacity); err != nil {
+	return c.JSON(http.StatusOK, crosshair)
+}
+
+func (h *CrosshairHandler) GetRevisions(c echo.Context) error {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return ErrorHandler(cErrors.ErrInvalidCrosshairID, c)
+	}
+	authorID, err := uuid.Parse(c.Get("userID").(string))
+	if err != nil {
+		return ErrorHandler(cErrors.ErrInvalidUserID, c)
+	}
+	revisions, err := h.service.GetRevisions(id, authorID)
+	if err != nil {
 		return ErrorHandler(err, c)
 	}
+	return c.JSON(http.StatusOK, revisions)
+}
+
+func (h *CrosshairHandler) RestoreRevision(c echo.Context) error {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return ErrorHandler(cErrors.ErrInvalidCrosshairID, c)
+	}
+	revisionID, err := uuid.Parse(c.Param("revision_id"))
+	if err != nil {
+		return ErrorHandler(cErrors.ErrRevisionNotFound, c)
+	}
 	authorID, err := uuid.Parse(c.Get("userID").(string))
 	if err != nil {
 		return ErrorHandler(cErrors.ErrInvalidUserID, c)
 	}
-	crosshair, err := h.service.Create(authorID, &req)
+	crosshair, err := h.service.RestoreRevision(id, revisionID, authorID)
 	if err != nil {
 		return ErrorHandler(err, c)
 	}
-	return c.JSON(http.StatusCreated, crosshair)
+	return c.JSON(http.StatusOK, crosshair)
 }
 
+// GetAll serves the gallery: sort=new|top|trending, cursor, limit and the
+// dot, color, min_thickness, max_thickness and tag filters.
 func (h *CrosshairHandler) GetAll(c echo.Context) error {
-	page := 1
-	limit := 20
-	if p := c.QueryParam("page"); p != "" {
-		if _, err := fmt.Sscanf(p, "%d", &page); err != nil {
-			return ErrorHandler(cErrors.ErrInvalidRequestBody, c)
-		}
+	query := services.CrosshairGalleryQuery{
+		Sort:        c.QueryParam("sort"),
+		Cursor:      c.QueryParam("cursor"),
+		Limit:       20,
+		ColorFamily: strings.ToLower(c.QueryParam("color")),
+		Tag:         strings.TrimSpace(c.QueryParam("tag")),
 	}
 	if l := c.QueryParam("limit"); l != "" {
-		if _, err := fmt.Sscanf(l, "%d", &limit); err != nil {
-			return ErrorHandler(cErrors.ErrInvalidRequestBody, c)
+		if _, err := fmt.Sscanf(l, "%d", &query.Limit); err != nil {
+			return ErrorHandler(cErrors.ErrInvalidQuery, c)
 		}
 	}
-	if err := validators.ValidateIntRange(page, 1, 1000); err != nil {
+	if err := validators.ValidateIntRange(query.Limit, 1, 100); err != nil {
 		return ErrorHandler(err, c)
 	}
-	if err := validators.ValidateIntRange(limit, 1, 100); err != nil {
+	if d := c.QueryParam("dot"); d != "" {
+		dot, err := strconv.ParseBool(d)
+		if err != nil {
+			return ErrorHandler(cErrors.ErrInvalidQuery, c)
+		}
+		query.Dot = &dot
+	}
+	var err error
+	if query.MinThickness, err = thicknessParam(c, "min_thickness"); err != nil {
 		return ErrorHandler(err, c)
 	}
-	crosshairs, err := h.service.GetAll(page, limit)
-	if err != nil {
+	if query.MaxThickness, err = thicknessParam(c, "max_thickness"); err != nil {
 		return ErrorHandler(err, c)
 	}
+	if len(query.Tag) > 50 {
+		return ErrorHandler(cErrors.ErrInvalidQuery, c)
+	}
 
-	total, err := h.service.Count()
+	gallery, err := h.service.List(query, viewerID(c))
 	if err != nil {
 		return ErrorHandler(err, c)
 	}
+	return c.JSON(http.StatusOK, gallery)
+}
 
-	response := map[string]interface{}{
-		"crosshairs": crosshairs,
-		"total":      total,
-		"page":       page,
-		"limit":      limit,
+func thicknessParam(c echo.Context, name string) (*int, error) {
+	value := c.QueryParam(name)
+	if value == "" {
+		return nil, nil
 	}
-
-	return c.JSON(http.StatusOK, response)
+	thickness, err := strconv.Atoi(value)
+	if err != nil {
+		return nil, cErrors.ErrInvalidQuery
+	}
+	if err := validators.ValidateIntRange(thickness, 0, 20); err != nil {
+		return nil, err
+	}
+	return &thickness, nil
 }
 
 func (h *CrosshairHandler) GetByID(c echo.Context) error {
@@ -108,13 +175,52 @@ func (h *CrosshairHandler) GetByID(c echo.Context) error {
 	if err != nil {
 		return ErrorHandler(cErrors.ErrInvalidCrosshairID, c)
 	}
-	crosshair, err := h.service.GetByID(id)
+	viewer := viewerID(c)
+	crosshair, err := h.service.GetByID(id, viewer)
 	if err != nil {
 		return ErrorHandler(cErrors.ErrCrosshairNotFound, c)
 	}
+	if crosshair.AuthorID != viewer {
+		h.views.RecordView(id, viewer, c.RealIP(), c.Request().UserAgent())
+	}
 	return c.JSON(http.StatusOK, crosshair)
 }
 
+func (h *CrosshairHandler) Export(c echo.Context) error {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return ErrorHandler(cErrors.ErrInvalidCrosshairID, c)
+	}
+	export, err := h.service.Export(id, viewerID(c), c.QueryParam("format"))
+	if err != nil {
+		return ErrorHandler(err, c)
+	}
+	if export.Filename != "" {
+		c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", export.Filename))
+	}
+	return c.Blob(http.StatusOK, export.ContentType, export.Content)
+}
+
+type importCrosshairRequest struct {
+	Commands string `json:"commands"`
+}
+
+// Import parses pasted console commands into settings without saving them.
+func (h *CrosshairHandler) Import(c echo.Context) error {
+	var req importCrosshairRequest
+	if err := c.Bind(&req); err != nil {
+		return ErrorHandler(cErrors.ErrInvalidRequestBody, c)
+	}
+	settings, err := services.ParseCrosshairCommands(req.Commands)
+	if err != nil {
+		return ErrorHandler(err, c)
+	}
+	if err := validateCrosshairSettings(*settings); err != nil {
+		return ErrorHandler(err, c)
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{"settings": settings})
+}
+
 func (h *CrosshairHandler) Like(c echo.Context) error {
 	id, err := uuid.Parse(c.Param("id"))
 	if err != nil {
@@ -124,10 +230,11 @@ func (h *CrosshairHandler) Like(c echo.Context) error {
 	if err != nil {
 		return ErrorHandler(cErrors.ErrInvalidUserID, c)
 	}
-	if err := h.service.Like(id, userID); err != nil {
+	resp, err := h.service.Like(id, userID)
+	if err != nil {
 		return ErrorHandler(err, c)
 	}
-	return c.JSON(http.StatusOK, map[string]string{"message": "Liked successfully"})
+	return c.JSON(http.StatusOK, resp)
 }
 
 func (h *CrosshairHandler) Unlike(c echo.Context) error {
@@ -139,10 +246,11 @@ func (h *CrosshairHandler) Unlike(c echo.Context) error {
 	if err != nil {
 		return ErrorHandler(cErrors.ErrInvalidUserID, c)
 	}
-	if err := h.service.Unlike(id, userID); err != nil {
+	resp, err := h.service.Unlike(id, userID)
+	if err != nil {
 		return ErrorHandler(err, c)
 	}
-	return c.JSON(http.StatusOK, map[string]string{"message": "Unliked successfully"})
+	return c.JSON(http.StatusOK, resp)
 }
 
 func (h *CrosshairHandler) Delete(c echo.Context) error {
@@ -171,9 +279,150 @@ func (h *CrosshairHandler) GetByAuthorID(c echo.Context) error {
 			return ErrorHandler(cErrors.ErrInvalidQuery, c)
 		}
 	}
-	crosshairs, err := h.service.GetByAuthorID(authorID, limit)
+	crosshairs, err := h.service.GetByAuthorID(authorID, limit, viewerID(c))
 	if err != nil {
 		return ErrorHandler(err, c)
 	}
 	return c.JSON(http.StatusOK, crosshairs)
 }
+
+func (h *CrosshairHandler) GetLiked(c echo.Context) error {
+	userID, err := uuid.Parse(c.Get("userID").(string))
+	if err != nil {
+		return ErrorHandler(cErrors.ErrInvalidUserID, c)
+	}
+	page := 1
+	limit := 20
+	if p := c.QueryParam("page"); p != "" {
+		if _, err := fmt.Sscanf(p, "%d", &page); err != nil {
+			return ErrorHandler(cErrors.ErrInvalidRequestBody, c)
+		}
+	}
+	if l := c.QueryParam("limit"); l != "" {
+		if _, err := fmt.Sscanf(l, "%d", &limit); err != nil {
+			return ErrorHandler(cErrors.ErrInvalidRequestBody, c)
+		}
+	}
+	if err := validators.ValidateIntRange(page, 1, 1000); err != nil {
+		return ErrorHandler(err, c)
+	}
+	if err := validators.ValidateIntRange(limit, 1, 100); err != nil {
+		return ErrorHandler(err, c)
+	}
+	crosshairs, total, err := h.service.GetLiked(userID, page, limit)
+	if err != nil {
+		return ErrorHandler(err, c)
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"crosshairs": crosshairs,
+		"total":      total,
+		"page":       page,
+		"limit":      limit,
+	})
+}
+
+func (h *CrosshairHandler) Fork(c echo.Context) error {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return ErrorHandler(cErrors.ErrInvalidCrosshairID, c)
+	}
+	userID, err := uuid.Parse(c.Get("userID").(string))
+	if err != nil {
+		return ErrorHandler(cErrors.ErrInvalidUserID, c)
+	}
+
+	var req services.ForkCrosshairRequest
+	if c.Request().ContentLength != 0 {
+		if err := c.Bind(&req); err != nil {
+			return ErrorHandler(cErrors.ErrInvalidRequestBody, c)
+		}
+	}
+	req.Title = strings.TrimSpace(req.Title)
+	if req.Title != "" {
+		if err := validators.ValidateCrosshairTitle(req.Title); err != nil {
+			return ErrorHandler(err, c)
+		}
+	}
+
+	crosshair, err := h.service.Fork(id, userID, &req)
+	if err != nil {
+		return ErrorHandler(err, c)
+	}
+	return c.JSON(http.StatusCreated, crosshair)
+}
+
+func (h *CrosshairHandler) GetLineage(c echo.Context) error {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return ErrorHandler(cErrors.ErrInvalidCrosshairID, c)
+	}
+	lineage, err := h.service.GetLineage(id, viewerID(c))
+	if err != nil {
+		return ErrorHandler(err, c)
+	}
+	return c.JSON(http.StatusOK, lineage)
+}
+
+// GetStats serves the author dashboard for the last days (default 30).
+func (h *CrosshairHandler) GetStats(c echo.Context) error {
+	authorID, err := uuid.Parse(c.Get("userID").(string))
+	if err != nil {
+		return ErrorHandler(cErrors.ErrInvalidUserID, c)
+	}
+	days := 30
+	if d := c.QueryParam("days"); d != "" {
+		if days, err = strconv.Atoi(d); err != nil {
+			return ErrorHandler(cErrors.ErrInvalidQuery, c)
+		}
+	}
+	if err := validators.ValidateIntRange(days, 1, services.MaxCrosshairStatsDays); err != nil {
+		return ErrorHandler(err, c)
+	}
+	stats, err := h.service.AuthorStats(authorID, days)
+	if err != nil {
+		return ErrorHandler(err, c)
+	}
+	return c.JSON(http.StatusOK, stats)
+}
+
+// viewerID returns the authenticated user on public routes, or uuid.Nil.
+func viewerID(c echo.Context) uuid.UUID {
+	userID, ok := c.Get("userID").(string)
+	if !ok {
+		return uuid.Nil
+	}
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return uuid.Nil
+	}
+	return id
+}
+
+func validateCrosshairInput(title, description string, settings domain.CrosshairSettings) error {
+	if err := validators.ValidateCrosshairTitle(title); err != nil {
+		return err
+	}
+	if err := validators.ValidateCrosshairDescription(description); err != nil {
+		return err
+	}
+	return validateCrosshairSettings(settings)
+}
+
+func validateCrosshairSettings(settings domain.CrosshairSettings) error {
+	if err := validators.ValidateIntRange(settings.Thickness, 0, 20); err != nil { // example bounds
+		return err
+	}
+	if err := validators.ValidateIntRange(settings.Length, 0, 100); err != nil {
+		return err
+	}
+	if err := validators.ValidateIntRange(settings.Gap, -50, 100); err != nil {
+		return err
+	}
+	if err := validators.ValidateOpacity(settings.Opacity); err != nil {
+		return err
+	}
+	if err := validators.ValidateOpacity(settings.PipOpacity); err != nil {
+		return err
+	}
+	return validators.ValidateOpacity(settings.DotOutlineOpacity)
+}
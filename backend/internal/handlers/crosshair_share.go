@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	cErrors "github.com/quenyu/deadlock-stats/internal/errors"
+	"github.com/quenyu/deadlock-stats/internal/services"
+)
+
+// crosshairShareTemplate carries the OpenGraph tags link previews read and
+// sends browsers on to the crosshair page.
+var crosshairShareTemplate = template.Must(template.New("share").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<meta property="og:type" content="website">
+<meta property="og:site_name" content="Deadlock Stats">
+<meta property="og:title" content="{{.Title}}">
+<meta property="og:description" content="{{.Description}}">
+<meta property="og:url" content="{{.PageURL}}">
+<meta property="og:image" content="{{.ImageURL}}">
+<meta property="og:image:type" content="image/png">
+<meta property="og:image:width" content="{{.ImageSize}}">
+<meta property="og:image:height" content="{{.ImageSize}}">
+<meta name="twitter:card" content="summary">
+<meta name="twitter:title" content="{{.Title}}">
+<meta name="twitter:description" content="{{.Description}}">
+<meta name="twitter:image" content="{{.ImageURL}}">
+<meta http-equiv="refresh" content="0; url={{.PageURL}}">
+<link rel="canonical" href="{{.PageURL}}">
+</head>
+<body><a href="{{.PageURL}}">{{.Title}}</a></body>
+</html>
+`))
+
+func (h *CrosshairHandler) PreviewSVG(c echo.Context) error {
+	return h.preview(c, "svg")
+}
+
+func (h *CrosshairHandler) PreviewPNG(c echo.Context) error {
+	return h.preview(c, "png")
+}
+
+func (h *CrosshairHandler) preview(c echo.Context, format string) error {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return ErrorHandler(cErrors.ErrInvalidCrosshairID, c)
+	}
+	preview, err := h.service.Preview(id, viewerID(c), format, c.QueryParam("background"))
+	if err != nil {
+		return ErrorHandler(err, c)
+	}
+
+	header := c.Response().Header()
+	header.Set("ETag", preview.ETag)
+	header.Set("Cache-Control", crosshairCacheControl(preview.Public))
+	if c.Request().Header.Get("If-None-Match") == preview.ETag {
+		return c.NoContent(http.StatusNotModified)
+	}
+	return c.Blob(http.StatusOK, preview.ContentType, preview.Content)
+}
+
+// Share serves the link to paste into Discord and other apps that render
+// OpenGraph previews.
+func (h *CrosshairHandler) Share(c echo.Context) error {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return ErrorHandler(cErrors.ErrInvalidCrosshairID, c)
+	}
+	crosshair, err := h.service.GetByID(id, viewerID(c))
+	if err != nil {
+		return ErrorHandler(cErrors.ErrCrosshairNotFound, c)
+	}
+
+	description := strings.TrimSpace(crosshair.Description)
+	if description == "" {
+		description = "Deadlock crosshair"
+		if crosshair.AuthorName != "" {
+			description += " by " + crosshair.AuthorName
+		}
+	}
+
+	var page bytes.Buffer
+	err = crosshairShareTemplate.Execute(&page, map[string]interface{}{
+		"Title":       crosshair.Title,
+		"Description": description,
+		"PageURL":     fmt.Sprintf("%s/crosshairs/%s", strings.TrimRight(h.config.App.ClientURL, "/"), id),
+		"ImageURL":    fmt.Sprintf("%s/api/v1/crosshairs/%s/preview.png", strings.TrimRight(h.config.Steam.RedirectURL, "/"), id),
+		"ImageSize":   services.CrosshairPreviewSize,
+	})
+	if err != nil {
+		return ErrorHandler(err, c)
+	}
+	c.Response().Header().Set("Cache-Control", crosshairCacheControl(crosshair.IsPublic))
+	return c.HTMLBlob(http.StatusOK, page.Bytes())
+}
+
+// crosshairCacheControl keeps private crosshairs, which only their author
+// can load, out of shared caches.
+func crosshairCacheControl(public bool) string {
+	if public {
+		return "public, max-age=300"
+	}
+	return "private, max-age=300"
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/labstack/echo/v4"
 	cErrors "github.com/quenyu/deadlock-stats/internal/errors"
+	"github.com/quenyu/deadlock-stats/internal/validators"
 )
 
 type httpError struct {
@@ -21,6 +22,7 @@ var errorMap = map[error]httpError{
 	cErrors.ErrRateLimited:       {http.StatusTooManyRequests, "Rate limited"},
 	cErrors.ErrAPIUnavailable:    {http.StatusServiceUnavailable, "External API unavailable"},
 	cErrors.ErrPlayerDataMissing: {http.StatusNotFound, "Player data missing"},
+	cErrors.ErrPrivateProfile:    {http.StatusForbidden, "Steam profile is private"},
 
 	// Auth-related
 	cErrors.ErrUserNotFound:        {http.StatusNotFound, "User not found"},
@@ -37,8 +39,16 @@ var errorMap = map[error]httpError{
 	cErrors.ErrInvalidCrosshairID: {http.StatusBadRequest, "Invalid crosshair ID"},
 	cErrors.ErrInvalidUserID:      {http.StatusBadRequest, "Invalid user ID"},
 	cErrors.ErrCrosshairForbidden: {http.StatusForbidden, "Forbidden"},
+	cErrors.ErrRevisionNotFound:   {http.StatusNotFound, "Crosshair revision not found"},
 	cErrors.ErrInvalidRequestBody: {http.StatusBadRequest, "Invalid request body"},
 
+	// Collection-related
+	cErrors.ErrCollectionNotFound:  {http.StatusNotFound, "Collection not found"},
+	cErrors.ErrInvalidCollectionID: {http.StatusBadRequest, "Invalid collection ID"},
+	cErrors.ErrCollectionForbidden: {http.StatusForbidden, "Forbidden"},
+	cErrors.ErrCollectionNameTaken: {http.StatusConflict, "Collection name already in use"},
+	cErrors.ErrCollectionLimit:     {http.StatusBadRequest, "Collection limit reached"},
+
 	// Match-related
 	cErrors.ErrMatchNotFound:   {http.StatusNotFound, "Match not found"},
 	cErrors.ErrInvalidSearch:   {http.StatusBadRequest, "Invalid search type"},
@@ -58,10 +68,19 @@ var errorMap = map[error]httpError{
 
 	// Build-related
 	cErrors.ErrBuildNotFound:  {http.StatusNotFound, "Build not found"},
+	cErrors.ErrInvalidBuild:   {http.StatusBadRequest, "Invalid build"},
 	cErrors.ErrInvalidBuildID: {http.StatusBadRequest, "Invalid build ID"},
 	cErrors.ErrBuildForbidden: {http.StatusForbidden, "Not allowed to modify this build"},
 	cErrors.ErrInvalidItemID:  {http.StatusBadRequest, "Invalid item ID"},
 	cErrors.ErrInvalidAbility: {http.StatusBadRequest, "Invalid ability"},
+	cErrors.ErrInvalidHeroID:  {http.StatusBadRequest, "Invalid hero ID"},
+	cErrors.ErrInvalidPhase:   {http.StatusBadRequest, "Invalid build phase"},
+
+	// Comment-related
+	cErrors.ErrCommentNotFound:  {http.StatusNotFound, "Comment not found"},
+	cErrors.ErrInvalidCommentID: {http.StatusBadRequest, "Invalid comment ID"},
+	cErrors.ErrCommentForbidden: {http.StatusForbidden, "Not allowed to modify this comment"},
+	cErrors.ErrInvalidComment:   {http.StatusBadRequest, "Invalid comment"},
 
 	// System-related
 	cErrors.ErrDatabaseError:   {http.StatusInternalServerError, "Database operation failed"},
@@ -72,10 +91,18 @@ var errorMap = map[error]httpError{
 func ErrorHandler(err error, c echo.Context) error {
 	for targetErr, httpErr := range errorMap {
 		if errors.Is(err, targetErr) {
-			return c.JSON(httpErr.Code, echo.Map{
+			response := echo.Map{
 				"error": httpErr.Message,
 				"code":  httpErr.Code,
-			})
+			}
+			// Wrapped client errors carry a more specific explanation
+			var fieldErrs *validators.FieldErrors
+			if errors.As(err, &fieldErrs) {
+				response["fields"] = fieldErrs.Fields
+			} else if err != targetErr && httpErr.Code < http.StatusInternalServerError {
+				response["details"] = err.Error()
+			}
+			return c.JSON(httpErr.Code, response)
 		}
 	}
 
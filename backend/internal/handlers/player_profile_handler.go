@@ -2,7 +2,9 @@ package handlers
 
 import (
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/labstack/echo/v4"
@@ -13,19 +15,23 @@ import (
 )
 
 type PlayerProfileHandler struct {
-	service *services.PlayerProfileService
+	service         *services.PlayerProfileService
+	steamIDResolver *services.SteamIDResolver
+	analytics       *services.SearchAnalytics
 }
 
-func NewPlayerProfileHandler(service *services.PlayerProfileService) *PlayerProfileHandler {
+func NewPlayerProfileHandler(service *services.PlayerProfileService, steamIDResolver *services.SteamIDResolver, analytics *services.SearchAnalytics) *PlayerProfileHandler {
 	return &PlayerProfileHandler{
-		service: service,
+		service:         service,
+		steamIDResolver: steamIDResolver,
+		analytics:       analytics,
 	}
 }
 
 func (h *PlayerProfileHandler) GetPlayerProfileV2(c echo.Context) error {
-	steamID, err := h.validateSteamIDParam(c)
-	if err != nil {
-		return ErrorHandler(err, c)
+	steamID, handled, err := resolveSteamIDParam(c, h.steamIDResolver)
+	if handled {
+		return err
 	}
 
 	profile, err := h.service.GetExtendedPlayerProfile(c.Request().Context(), steamID)
@@ -37,13 +43,14 @@ func (h *PlayerProfileHandler) GetPlayerProfileV2(c echo.Context) error {
 		return ErrorHandler(cErrors.ErrPlayerNotFound, c)
 	}
 
+	h.analytics.RecordProfileView(steamID)
 	return c.JSON(http.StatusOK, profile)
 }
 
 func (h *PlayerProfileHandler) GetPlayerProfileWithMetrics(c echo.Context) error {
-	steamID, err := h.validateSteamIDParam(c)
-	if err != nil {
-		return ErrorHandler(err, c)
+	steamID, handled, err := resolveSteamIDParam(c, h.steamIDResolver)
+	if handled {
+		return err
 	}
 
 	start := time.Now()
@@ -73,9 +80,9 @@ func (h *PlayerProfileHandler) GetPlayerProfileWithMetrics(c echo.Context) error
 }
 
 func (h *PlayerProfileHandler) GetRecentMatches(c echo.Context) error {
-	steamID, err := h.validateSteamIDParam(c)
-	if err != nil {
-		return ErrorHandler(err, c)
+	steamID, handled, err := resolveSteamIDParam(c, h.steamIDResolver)
+	if handled {
+		return err
 	}
 
 	limit := 5
@@ -125,12 +132,34 @@ func (h *PlayerProfileHandler) SearchPlayers(c echo.Context) error {
 	return c.JSON(http.StatusOK, users)
 }
 
-func (h *PlayerProfileHandler) validateSteamIDParam(c echo.Context) (string, error) {
-	steamID := c.Param("steamId")
+// resolveSteamIDParam accepts any identifier supported by SteamIDResolver.
+// When the path does not already hold the canonical SteamID64 the client is
+// redirected to it. handled is true whenever a response (error or redirect)
+// has already been written.
+func resolveSteamIDParam(c echo.Context, resolver *services.SteamIDResolver) (string, bool, error) {
+	raw := c.Param("steamId")
+	if unescaped, err := url.PathUnescape(raw); err == nil {
+		raw = unescaped
+	}
+
+	steamID, err := resolver.Resolve(c.Request().Context(), raw)
+	if err != nil {
+		return "", true, ErrorHandler(err, c)
+	}
 	if err := validators.ValidateSteamID(steamID); err != nil {
-		return "", cErrors.ErrInvalidSteamID
+		return "", true, ErrorHandler(cErrors.ErrInvalidSteamID, c)
+	}
+
+	if raw == steamID {
+		return steamID, false, nil
+	}
+
+	location := strings.Replace(c.Path(), ":steamId", steamID, 1)
+	if query := c.Request().URL.RawQuery; query != "" {
+		location += "?" + query
 	}
-	return steamID, nil
+	c.Response().Header().Set("X-Canonical-Steam-ID", steamID)
+	return steamID, true, c.Redirect(http.StatusFound, location)
 }
 
 func (h *PlayerProfileHandler) validateSearchParams(c echo.Context) (string, string, error) {
@@ -1,26 +1,34 @@
 package handlers
 
 import (
+	"fmt"
+	"math"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/quenyu/deadlock-stats/internal/dto"
+	cErrors "github.com/quenyu/deadlock-stats/internal/errors"
 	"github.com/quenyu/deadlock-stats/internal/services"
 	"github.com/quenyu/deadlock-stats/internal/validators"
 	"go.uber.org/zap"
 )
 
 type PlayerSearchHandler struct {
-	searchService *services.PlayerSearchService
-	logger        *zap.Logger
+	searchService   *services.PlayerSearchService
+	steamIDResolver *services.SteamIDResolver
+	analytics       *services.SearchAnalytics
+	logger          *zap.Logger
 }
 
-func NewPlayerSearchHandler(searchService *services.PlayerSearchService, logger *zap.Logger) *PlayerSearchHandler {
+func NewPlayerSearchHandler(searchService *services.PlayerSearchService, steamIDResolver *services.SteamIDResolver, analytics *services.SearchAnalytics, logger *zap.Logger) *PlayerSearchHandler {
 	return &PlayerSearchHandler{
-		searchService: searchService,
-		logger:        logger,
+		searchService:   searchService,
+		steamIDResolver: steamIDResolver,
+		analytics:       analytics,
+		logger:          logger,
 	}
 }
 
@@ -36,13 +44,14 @@ func (h *PlayerSearchHandler) SearchPlayers(c echo.Context) error {
 
 	page, pageSize := parsePaginationParams(c, 1, 10)
 
-	result, err := h.searchService.SearchPlayers(c.Request().Context(), query, searchType, page, pageSize)
+	result, err := h.searchService.SearchPlayers(c.Request().Context(), query, searchType, c.QueryParam("cursor"), page, pageSize)
 	searchTime := time.Since(start)
 
 	if err != nil {
 		h.logger.Error("SearchPlayers error", zap.Error(err))
 		return ErrorHandler(err, c)
 	}
+	h.analytics.RecordSearch(query, result.TotalCount, searchTime)
 
 	response := echo.Map{
 		"results":     result.Results,
@@ -50,6 +59,7 @@ func (h *PlayerSearchHandler) SearchPlayers(c echo.Context) error {
 		"page":        result.Page,
 		"page_size":   result.PageSize,
 		"total_pages": result.TotalPages,
+		"next_cursor": result.NextCursor,
 		"searchType":  searchType,
 		"searchTime":  searchTime.Milliseconds(),
 		"query":       query,
@@ -99,15 +109,19 @@ func (h *PlayerSearchHandler) SearchPlayersWithFilters(c echo.Context) error {
 	}
 
 	page, pageSize := parsePaginationParams(c, 1, 20)
-	filters := parseSearchFilters(c)
+	filters, err := parseSearchFilters(c)
+	if err != nil {
+		return ErrorHandler(err, c)
+	}
 
 	start := time.Now()
-	result, err := h.searchService.SearchPlayersWithFilters(c.Request().Context(), query, filters, page, pageSize)
+	result, err := h.searchService.SearchPlayersWithFilters(c.Request().Context(), query, filters, c.QueryParam("cursor"), page, pageSize)
 	searchTime := time.Since(start)
 
 	if err != nil {
 		return ErrorHandler(err, c)
 	}
+	h.analytics.RecordSearch(query, result.TotalCount, searchTime)
 
 	response := echo.Map{
 		"query":       query,
@@ -117,6 +131,7 @@ func (h *PlayerSearchHandler) SearchPlayersWithFilters(c echo.Context) error {
 		"page":        result.Page,
 		"page_size":   result.PageSize,
 		"total_pages": result.TotalPages,
+		"next_cursor": result.NextCursor,
 		"searchTime":  searchTime.Milliseconds(),
 	}
 
@@ -169,6 +184,20 @@ func (h *PlayerSearchHandler) GetRecentlyActivePlayers(c echo.Context) error {
 	return c.JSON(http.StatusOK, response)
 }
 
+func (h *PlayerSearchHandler) GetPlayerFriends(c echo.Context) error {
+	steamID, handled, err := resolveSteamIDParam(c, h.steamIDResolver)
+	if handled {
+		return err
+	}
+
+	friends, err := h.searchService.GetPlayerFriends(c.Request().Context(), steamID)
+	if err != nil {
+		return ErrorHandler(err, c)
+	}
+
+	return c.JSON(http.StatusOK, friends)
+}
+
 func (h *PlayerSearchHandler) SearchPlayersDebug(c echo.Context) error {
 	query := c.QueryParam("query")
 	searchType := c.QueryParam("searchType")
@@ -180,7 +209,7 @@ func (h *PlayerSearchHandler) SearchPlayersDebug(c echo.Context) error {
 	page, pageSize := parsePaginationParams(c, 1, 10)
 
 	start := time.Now()
-	result, err := h.searchService.SearchPlayers(c.Request().Context(), query, searchType, page, pageSize)
+	result, err := h.searchService.SearchPlayers(c.Request().Context(), query, searchType, c.QueryParam("cursor"), page, pageSize)
 	searchTime := time.Since(start)
 
 	if err != nil {
@@ -208,6 +237,7 @@ func (h *PlayerSearchHandler) SearchPlayersDebug(c echo.Context) error {
 		"page":        result.Page,
 		"page_size":   result.PageSize,
 		"total_pages": result.TotalPages,
+		"next_cursor": result.NextCursor,
 		"results":     result.Results,
 		"debugInfo":   debugInfo,
 	}
@@ -239,17 +269,56 @@ func parseLimit(c echo.Context, defaultLimit, maxLimit int) int {
 	return defaultLimit
 }
 
-func parseSearchFilters(c echo.Context) dto.SearchFilters {
+func parseSearchFilters(c echo.Context) (dto.SearchFilters, error) {
 	filters := dto.SearchFilters{
 		SearchType: c.QueryParam("searchType"),
 		SortBy:     c.QueryParam("sort_by"),
 		SortOrder:  c.QueryParam("sort_order"),
+		Hero:       strings.TrimSpace(c.QueryParam("hero")),
+		Country:    strings.ToUpper(c.QueryParam("country")),
 	}
+
+	intParams := map[string]*int{
+		"min_rank":    &filters.MinRank,
+		"max_rank":    &filters.MaxRank,
+		"min_matches": &filters.MinMatches,
+		"max_matches": &filters.MaxMatches,
+	}
+	for name, target := range intParams {
+		if raw := c.QueryParam(name); raw != "" {
+			value, err := strconv.Atoi(raw)
+			if err != nil {
+				return filters, fmt.Errorf("%w: %s must be an integer", cErrors.ErrInvalidQuery, name)
+			}
+			*target = value
+		}
+	}
+
+	floatParams := map[string]*float64{
+		"min_win_rate": &filters.MinWinRate,
+		"max_win_rate": &filters.MaxWinRate,
+		"min_kd_ratio": &filters.MinKDRatio,
+		"max_kd_ratio": &filters.MaxKDRatio,
+	}
+	for name, target := range floatParams {
+		if raw := c.QueryParam(name); raw != "" {
+			value, err := strconv.ParseFloat(raw, 64)
+			if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+				return filters, fmt.Errorf("%w: %s must be a number", cErrors.ErrInvalidQuery, name)
+			}
+			*target = value
+		}
+	}
+
 	if filters.SortBy == "" {
 		filters.SortBy = "nickname"
 	}
-	if filters.SortOrder != "asc" && filters.SortOrder != "desc" {
+	if filters.SortOrder == "" {
 		filters.SortOrder = "asc"
 	}
-	return filters
+
+	if err := filters.Validate(); err != nil {
+		return filters, fmt.Errorf("%w: %v", cErrors.ErrInvalidQuery, err)
+	}
+	return filters, nil
 }
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	cErrors "github.com/quenyu/deadlock-stats/internal/errors"
+	"github.com/quenyu/deadlock-stats/internal/services"
+)
+
+var trendingPeriods = map[string]time.Duration{
+	"24h": 24 * time.Hour,
+	"7d":  7 * 24 * time.Hour,
+}
+
+type SearchAnalyticsHandler struct {
+	analytics *services.SearchAnalytics
+}
+
+func NewSearchAnalyticsHandler(analytics *services.SearchAnalytics) *SearchAnalyticsHandler {
+	return &SearchAnalyticsHandler{analytics: analytics}
+}
+
+func (h *SearchAnalyticsHandler) GetTrendingPlayers(c echo.Context) error {
+	period := c.QueryParam("period")
+	if period == "" {
+		period = "24h"
+	}
+
+	duration, ok := trendingPeriods[period]
+	if !ok {
+		return ErrorHandler(fmt.Errorf("%w: period must be 24h or 7d", cErrors.ErrInvalidQuery), c)
+	}
+
+	limit := parseLimit(c, 10, 50)
+	players, err := h.analytics.TrendingPlayers(c.Request().Context(), duration, limit)
+	if err != nil {
+		return ErrorHandler(err, c)
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{
+		"period":  period,
+		"results": players,
+	})
+}
+
+func (h *SearchAnalyticsHandler) GetSearchStats(c echo.Context) error {
+	days := 1
+	if value := c.QueryParam("days"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > services.MaxSearchStatsDays {
+			return ErrorHandler(fmt.Errorf("%w: days must be between 1 and %d", cErrors.ErrInvalidQuery, services.MaxSearchStatsDays), c)
+		}
+		days = parsed
+	}
+
+	limit := parseLimit(c, 20, 100)
+	stats, err := h.analytics.SearchStats(c.Request().Context(), days, limit)
+	if err != nil {
+		return ErrorHandler(err, c)
+	}
+
+	return c.JSON(http.StatusOK, stats)
+}
@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/quenyu/deadlock-stats/internal/config"
+	"github.com/quenyu/deadlock-stats/internal/repositories"
+)
+
+// AdminMiddleware only lets through users whose Steam ID is listed in
+// admin.steam_ids. It must run after JWTMiddleware.Authorization.
+type AdminMiddleware struct {
+	userRepository *repositories.UserRepository
+	steamIDs       map[string]bool
+}
+
+func NewAdminMiddleware(cfg *config.Config, userRepository *repositories.UserRepository) *AdminMiddleware {
+	steamIDs := make(map[string]bool, len(cfg.Admin.SteamIDs))
+	for _, steamID := range cfg.Admin.SteamIDs {
+		steamIDs[steamID] = true
+	}
+
+	return &AdminMiddleware{
+		userRepository: userRepository,
+		steamIDs:       steamIDs,
+	}
+}
+
+func (m *AdminMiddleware) RequireAdmin(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		userID, ok := c.Get("userID").(string)
+		if !ok || userID == "" {
+			return c.JSON(http.StatusUnauthorized, echo.Map{"error": "unauthorized"})
+		}
+
+		user, err := m.userRepository.FindByID(userID)
+		if err != nil || user == nil || !m.steamIDs[user.SteamID] {
+			return c.JSON(http.StatusForbidden, echo.Map{"error": "forbidden"})
+		}
+
+		return next(c)
+	}
+}
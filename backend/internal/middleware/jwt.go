@@ -34,6 +34,19 @@ func (m *JWTMiddleware) Authorization(next echo.HandlerFunc) echo.HandlerFunc {
 	}
 }
 
+// OptionalAuthorization sets userID when the request carries a valid token
+// and lets anonymous requests through unchanged.
+func (m *JWTMiddleware) OptionalAuthorization(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if tokenStr, err := m.extractToken(c); err == nil {
+			if userID, err := m.validateToken(tokenStr); err == nil {
+				c.Set("userID", userID)
+			}
+		}
+		return next(c)
+	}
+}
+
 func (m *JWTMiddleware) extractToken(c echo.Context) (string, error) {
 	tokenStr := c.Request().Header.Get("Authorization")
 	tokenStr = strings.TrimPrefix(tokenStr, "Bearer ")
@@ -49,7 +49,7 @@ var (
 			Name: "cache_hits_total",
 			Help: "Total number of cache hits",
 		},
-		[]string{"cache_type"},
+		[]string{"cache_type", "tier"},
 	)
 
 	cacheMisses = promauto.NewCounterVec(
@@ -122,9 +122,9 @@ func RecordCrosshairCreated() {
 	crosshairsCreated.Inc()
 }
 
-// RecordCacheHit записывает попадание в кэш
-func RecordCacheHit(cacheType string) {
-	cacheHits.WithLabelValues(cacheType).Inc()
+// RecordCacheHit записывает попадание в кэш; tier - уровень кэша, ответивший на запрос
+func RecordCacheHit(cacheType, tier string) {
+	cacheHits.WithLabelValues(cacheType, tier).Inc()
 }
 
 // RecordCacheMiss записывает промах кэша
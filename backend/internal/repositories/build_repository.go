@@ -0,0 +1,211 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/quenyu/deadlock-stats/internal/domain"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type BuildRepository struct {
+	db *gorm.DB
+}
+
+func NewBuildRepository(db *gorm.DB) *BuildRepository {
+	return &BuildRepository{db: db}
+}
+
+// Create stores a build with its items and ability upgrades.
+func (r *BuildRepository) Create(build *domain.Build) error {
+	build.ID = uuid.New()
+	build.CreatedAt = time.Now()
+	build.UpdatedAt = time.Now()
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Author", "Items", "AbilityUpgrades").Create(build).Error; err != nil {
+			return err
+		}
+		return saveBuildContents(tx, build)
+	})
+}
+
+func (r *BuildRepository) GetByID(id uuid.UUID) (*domain.Build, error) {
+	var build domain.Build
+	err := r.db.Preload("Author").Preload("Stats").
+		Preload("Items", func(db *gorm.DB) *gorm.DB {
+			return db.Order("phase_position, position")
+		}).
+		Preload("AbilityUpgrades", func(db *gorm.DB) *gorm.DB {
+			return db.Order("position")
+		}).
+		First(&build, "id = ?", id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("build not found")
+		}
+		return nil, err
+	}
+	return &build, nil
+}
+
+// buildSortOrders orders the builds list. Builds without stats count as
+// zero; the win_rate and kda sorts first rank builds with enough matches.
+var buildSortOrders = map[string]string{
+	"new":      "builds.created_at DESC, builds.id DESC",
+	"matches":  "COALESCE(build_stats.matches, 0) DESC, builds.created_at DESC, builds.id DESC",
+	"win_rate": "(COALESCE(build_stats.matches, 0) >= %d) DESC, COALESCE(build_stats.win_rate, 0) DESC, builds.created_at DESC, builds.id DESC",
+	"kda":      "(COALESCE(build_stats.matches, 0) >= %d) DESC, COALESCE(build_stats.avg_kda, 0) DESC, builds.created_at DESC, builds.id DESC",
+}
+
+// List returns one page of builds in the filter's sort, with their stats
+// but without their items and ability upgrades, and the total matching the
+// filter.
+func (r *BuildRepository) List(filter domain.BuildFilter) ([]domain.Build, int64, error) {
+	order, ok := buildSortOrders[filter.Sort]
+	if !ok {
+		return nil, 0, fmt.Errorf("unknown build sort %q", filter.Sort)
+	}
+	if strings.Contains(order, "%d") {
+		order = fmt.Sprintf(order, filter.MinSample)
+	}
+
+	query := func() *gorm.DB {
+		q := r.db.Model(&domain.Build{})
+		if filter.AuthorID != uuid.Nil {
+			q = q.Where("builds.author_id = ?", filter.AuthorID)
+		}
+		if filter.HeroID != 0 {
+			q = q.Where("builds.hero_id = ?", filter.HeroID)
+		}
+		if !filter.IncludePrivate {
+			q = q.Where("builds.is_public = true")
+		}
+		return q
+	}
+
+	var total int64
+	if err := query().Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var builds []domain.Build
+	offset := (filter.Page - 1) * filter.Limit
+	err := query().Preload("Author").Preload("Stats").
+		Joins("LEFT JOIN build_stats ON build_stats.build_id = builds.id").
+		Order(order).
+		Offset(offset).Limit(filter.Limit).
+		Find(&builds).Error
+	return builds, total, err
+}
+
+// Update saves the editable fields of build and replaces its items and
+// ability upgrades.
+func (r *BuildRepository) Update(build *domain.Build) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("id").First(&domain.Build{}, "id = ?", build.ID).Error
+		if err != nil {
+			return err
+		}
+
+		build.UpdatedAt = time.Now()
+		err = tx.Model(&domain.Build{}).Where("id = ?", build.ID).Updates(map[string]interface{}{
+			"hero_id":      build.HeroID,
+			"title":        build.Title,
+			"description":  build.Description,
+			"game_version": build.GameVersion,
+			"is_public":    build.IsPublic,
+			"updated_at":   build.UpdatedAt,
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Delete(&domain.BuildItem{}, "build_id = ?", build.ID).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&domain.BuildAbilityUpgrade{}, "build_id = ?", build.ID).Error; err != nil {
+			return err
+		}
+		return saveBuildContents(tx, build)
+	})
+}
+
+// Delete removes a build and its comments.
+func (r *BuildRepository) Delete(id uuid.UUID) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&domain.Build{}, "id = ?", id).Error; err != nil {
+			return err
+		}
+		return deleteContentComments(tx, domain.CommentContentBuild, id)
+	})
+}
+
+// RefreshStats recomputes the stats of every public build from the matches
+// played since the given time. A player followed a build when they played
+// its hero and bought at least minAdherence of its items.
+func (r *BuildRepository) RefreshStats(since time.Time, minAdherence float64) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM build_stats").Error; err != nil {
+			return err
+		}
+		return tx.Exec(`
+			WITH build_set AS (
+				SELECT DISTINCT b.id AS build_id, b.hero_id, bi.item_id
+				FROM builds b
+				JOIN build_items bi ON bi.build_id = b.id
+				WHERE b.is_public = true
+			), build_size AS (
+				SELECT build_id, hero_id, COUNT(*) AS items
+				FROM build_set
+				GROUP BY build_id, hero_id
+			), followed AS (
+				SELECT size.build_id, mp.match_id, mp.account_id,
+					COUNT(DISTINCT mpi.item_id)::double precision / size.items AS adherence
+				FROM build_size size
+				JOIN match_players mp ON mp.hero_id = size.hero_id
+				JOIN match_details md ON md.match_id = mp.match_id AND md.start_time >= ?
+				JOIN match_player_items mpi ON mpi.match_id = mp.match_id AND mpi.account_id = mp.account_id
+				JOIN build_set bs ON bs.build_id = size.build_id AND bs.item_id = mpi.item_id
+				GROUP BY size.build_id, mp.match_id, mp.account_id, size.items
+			)
+			INSERT INTO build_stats (build_id, matches, wins, win_rate, avg_kda, avg_adherence, updated_at)
+			SELECT f.build_id,
+				COUNT(*),
+				COUNT(*) FILTER (WHERE mp.won),
+				AVG(CASE WHEN mp.won THEN 100.0 ELSE 0.0 END),
+				AVG((mp.kills + mp.assists)::double precision / GREATEST(mp.deaths, 1)),
+				AVG(f.adherence),
+				NOW()
+			FROM followed f
+			JOIN match_players mp ON mp.match_id = f.match_id AND mp.account_id = f.account_id
+			WHERE f.adherence >= ?
+			GROUP BY f.build_id
+		`, since, minAdherence).Error
+	})
+}
+
+func saveBuildContents(tx *gorm.DB, build *domain.Build) error {
+	for i := range build.Items {
+		build.Items[i].BuildID = build.ID
+	}
+	for i := range build.AbilityUpgrades {
+		build.AbilityUpgrades[i].BuildID = build.ID
+	}
+
+	if len(build.Items) > 0 {
+		if err := tx.Create(&build.Items).Error; err != nil {
+			return err
+		}
+	}
+	if len(build.AbilityUpgrades) > 0 {
+		if err := tx.Create(&build.AbilityUpgrades).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/quenyu/deadlock-stats/internal/domain"
+	"gorm.io/gorm"
+)
+
+// commentCountTables are the tables holding the comments_count of each
+// content type.
+var commentCountTables = map[string]string{
+	domain.CommentContentCrosshair: "crosshairs",
+	domain.CommentContentBuild:     "builds",
+}
+
+type CommentRepository struct {
+	db *gorm.DB
+}
+
+func NewCommentRepository(db *gorm.DB) *CommentRepository {
+	return &CommentRepository{db: db}
+}
+
+// Create stores a comment and bumps the comments_count of its content in
+// the same transaction.
+func (r *CommentRepository) Create(comment *domain.Comment) error {
+	comment.ID = uuid.New()
+	comment.CreatedAt = time.Now()
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Author", "UpdatedAt", "DeletedAt").Create(comment).Error; err != nil {
+			return err
+		}
+		return changeCommentsCount(tx, comment.ContentType, comment.ContentID, "comments_count + 1")
+	})
+}
+
+func (r *CommentRepository) GetByID(id uuid.UUID) (*domain.Comment, error) {
+	var comment domain.Comment
+	err := r.db.Preload("Author").First(&comment, "id = ?", id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("comment not found")
+		}
+		return nil, err
+	}
+	return &comment, nil
+}
+
+// List returns one page of a thread level with the number of replies of
+// each comment, and the total of the level. Top-level comments are listed
+// newest first and replies oldest first. Deleted comments are only listed
+// while they have replies.
+func (r *CommentRepository) List(filter domain.CommentFilter) ([]domain.Comment, int64, error) {
+	query := func() *gorm.DB {
+		q := r.db.Model(&domain.Comment{}).
+			Where("comments.content_type = ? AND comments.content_id = ?", filter.ContentType, filter.ContentID).
+			Where("comments.deleted_at IS NULL OR EXISTS (SELECT 1 FROM comments replies WHERE replies.parent_id = comments.id)")
+		if filter.ParentID == nil {
+			return q.Where("comments.parent_id IS NULL")
+		}
+		return q.Where("comments.parent_id = ?", *filter.ParentID)
+	}
+
+	var total int64
+	if err := query().Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	order := "comments.created_at DESC, comments.id DESC"
+	if filter.ParentID != nil {
+		order = "comments.created_at, comments.id"
+	}
+
+	var comments []domain.Comment
+	err := query().Preload("Author").
+		Select("comments.*, (SELECT COUNT(*) FROM comments replies WHERE replies.parent_id = comments.id) AS replies_count").
+		Order(order).
+		Offset((filter.Page - 1) * filter.Limit).Limit(filter.Limit).
+		Find(&comments).Error
+	return comments, total, err
+}
+
+// UpdateBody replaces the body of a comment that is not deleted.
+func (r *CommentRepository) UpdateBody(comment *domain.Comment) error {
+	now := time.Now()
+	result := r.db.Model(&domain.Comment{}).
+		Where("id = ? AND deleted_at IS NULL", comment.ID).
+		Updates(map[string]interface{}{"body": comment.Body, "updated_at": now})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("comment not found")
+	}
+	comment.UpdatedAt = &now
+	return nil
+}
+
+// SoftDelete clears the body of a comment, marks it deleted and lowers the
+// comments_count of its content in the same transaction. Deleting a deleted
+// comment is a no-op.
+func (r *CommentRepository) SoftDelete(comment *domain.Comment) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&domain.Comment{}).
+			Where("id = ? AND deleted_at IS NULL", comment.ID).
+			UpdateColumns(map[string]interface{}{"body": "", "deleted_at": time.Now()})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		return changeCommentsCount(tx, comment.ContentType, comment.ContentID, "GREATEST(comments_count - 1, 0)")
+	})
+}
+
+func changeCommentsCount(tx *gorm.DB, contentType string, contentID uuid.UUID, newCount string) error {
+	table, ok := commentCountTables[contentType]
+	if !ok {
+		return fmt.Errorf("unknown comment content type %q", contentType)
+	}
+	return tx.Table(table).Where("id = ?", contentID).
+		Update("comments_count", gorm.Expr(newCount)).Error
+}
+
+// deleteContentComments removes the comments of a deleted crosshair or
+// build, which the comments table does not reference.
+func deleteContentComments(tx *gorm.DB, contentType string, contentID uuid.UUID) error {
+	return tx.Delete(&domain.Comment{}, "content_type = ? AND content_id = ?", contentType, contentID).Error
+}
@@ -0,0 +1,167 @@
+package repositories
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/quenyu/deadlock-stats/internal/domain"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type CrosshairCollectionRepository struct {
+	db *gorm.DB
+}
+
+func NewCrosshairCollectionRepository(db *gorm.DB) *CrosshairCollectionRepository {
+	return &CrosshairCollectionRepository{db: db}
+}
+
+func (r *CrosshairCollectionRepository) Create(collection *domain.CrosshairCollection) error {
+	collection.ID = uuid.New()
+	collection.CreatedAt = time.Now()
+	collection.UpdatedAt = collection.CreatedAt
+	return r.db.Omit("Owner").Create(collection).Error
+}
+
+func (r *CrosshairCollectionRepository) GetByID(id uuid.UUID) (*domain.CrosshairCollection, error) {
+	var collection domain.CrosshairCollection
+	err := r.db.Preload("Owner").First(&collection, "id = ?", id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("collection not found")
+		}
+		return nil, err
+	}
+	return &collection, nil
+}
+
+func (r *CrosshairCollectionRepository) Update(collection *domain.CrosshairCollection) error {
+	collection.UpdatedAt = time.Now()
+	return r.db.Model(&domain.CrosshairCollection{}).Where("id = ?", collection.ID).Updates(map[string]interface{}{
+		"name":        collection.Name,
+		"description": collection.Description,
+		"is_public":   collection.IsPublic,
+		"updated_at":  collection.UpdatedAt,
+	}).Error
+}
+
+func (r *CrosshairCollectionRepository) Delete(id uuid.UUID) error {
+	return r.db.Delete(&domain.CrosshairCollection{}, "id = ?", id).Error
+}
+
+func (r *CrosshairCollectionRepository) CountByOwner(ownerID uuid.UUID) (int64, error) {
+	var count int64
+	err := r.db.Model(&domain.CrosshairCollection{}).Where("owner_id = ?", ownerID).Count(&count).Error
+	return count, err
+}
+
+// NameTaken reports whether the owner has another collection with the same
+// name, ignoring case.
+func (r *CrosshairCollectionRepository) NameTaken(ownerID uuid.UUID, name string, exceptID uuid.UUID) (bool, error) {
+	var count int64
+	err := r.db.Model(&domain.CrosshairCollection{}).
+		Where("owner_id = ? AND LOWER(name) = LOWER(?) AND id <> ?", ownerID, name, exceptID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// ListByOwner returns an owner's collections, most recently updated first.
+// Private collections are only included for the owner, and the item counts
+// only include crosshairs the viewer can see.
+func (r *CrosshairCollectionRepository) ListByOwner(ownerID, viewerID uuid.UUID) ([]domain.CrosshairCollection, error) {
+	var collections []domain.CrosshairCollection
+	query := r.db.Preload("Owner").
+		Select(`crosshair_collections.*, (
+			SELECT COUNT(*) FROM crosshair_collection_items i
+			JOIN crosshairs c ON c.id = i.crosshair_id
+			WHERE i.collection_id = crosshair_collections.id AND (c.is_public = true OR c.author_id = ?)
+		) AS items_count`, viewerID).
+		Where("owner_id = ?", ownerID)
+	if ownerID != viewerID {
+		query = query.Where("is_public = true")
+	}
+	err := query.Order("updated_at DESC").Find(&collections).Error
+	return collections, err
+}
+
+// GetItems returns the crosshairs of a collection in order.
+func (r *CrosshairCollectionRepository) GetItems(collectionID uuid.UUID) ([]domain.CrosshairCollectionItem, error) {
+	var items []domain.CrosshairCollectionItem
+	err := r.db.Preload("Crosshair.Author").
+		Where("collection_id = ?", collectionID).
+		Order("position ASC, added_at ASC").
+		Find(&items).Error
+	return items, err
+}
+
+func (r *CrosshairCollectionRepository) CountItems(collectionID uuid.UUID) (int64, error) {
+	var count int64
+	err := r.db.Model(&domain.CrosshairCollectionItem{}).Where("collection_id = ?", collectionID).Count(&count).Error
+	return count, err
+}
+
+// AddItem appends a crosshair to a collection. Adding a crosshair that is
+// already in it keeps its position.
+func (r *CrosshairCollectionRepository) AddItem(collectionID, crosshairID uuid.UUID) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		// Serializes concurrent appends so positions stay unique
+		var collection domain.CrosshairCollection
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&collection, "id = ?", collectionID).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Exec(`
+			INSERT INTO crosshair_collection_items (collection_id, crosshair_id, position, added_at)
+			SELECT ?, ?, COALESCE(MAX(position), -1) + 1, NOW()
+			FROM crosshair_collection_items WHERE collection_id = ?
+			ON CONFLICT (collection_id, crosshair_id) DO NOTHING
+		`, collectionID, crosshairID, collectionID).Error
+		if err != nil {
+			return err
+		}
+		return touchCollection(tx, collectionID)
+	})
+}
+
+// SetItems replaces the crosshairs of a collection with crosshairIDs, in
+// that order.
+func (r *CrosshairCollectionRepository) SetItems(collectionID uuid.UUID, crosshairIDs []uuid.UUID) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&domain.CrosshairCollectionItem{}, "collection_id = ?", collectionID).Error; err != nil {
+			return err
+		}
+		if len(crosshairIDs) > 0 {
+			now := time.Now()
+			items := make([]domain.CrosshairCollectionItem, len(crosshairIDs))
+			for i, crosshairID := range crosshairIDs {
+				items[i] = domain.CrosshairCollectionItem{
+					CollectionID: collectionID,
+					CrosshairID:  crosshairID,
+					Position:     i,
+					AddedAt:      now,
+				}
+			}
+			if err := tx.Omit("Crosshair").Create(&items).Error; err != nil {
+				return err
+			}
+		}
+		return touchCollection(tx, collectionID)
+	})
+}
+
+func (r *CrosshairCollectionRepository) RemoveItem(collectionID, crosshairID uuid.UUID) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Delete(&domain.CrosshairCollectionItem{}, "collection_id = ? AND crosshair_id = ?", collectionID, crosshairID).Error
+		if err != nil {
+			return err
+		}
+		return touchCollection(tx, collectionID)
+	})
+}
+
+func touchCollection(tx *gorm.DB, collectionID uuid.UUID) error {
+	return tx.Model(&domain.CrosshairCollection{}).Where("id = ?", collectionID).Update("updated_at", time.Now()).Error
+}
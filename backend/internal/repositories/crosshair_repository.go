@@ -2,13 +2,19 @@ package repositories
 
 import (
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 	"github.com/quenyu/deadlock-stats/internal/domain"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// maxCrosshairRevisions is how many previous versions are kept per crosshair.
+const maxCrosshairRevisions = 50
+
 type CrosshairRepository struct {
 	db *gorm.DB
 }
@@ -36,42 +42,424 @@ func (r *CrosshairRepository) GetByID(id uuid.UUID) (*domain.Crosshair, error) {
 	return &crosshair, nil
 }
 
-func (r *CrosshairRepository) GetAll(page, limit int) ([]domain.Crosshair, error) {
+type crosshairSortKey struct {
+	column  string
+	sqlType string
+}
+
+// crosshairSortKeys maps the gallery sorts onto the columns of the gallery
+// query, with the type used to cast keyset values back.
+var crosshairSortKeys = map[string]crosshairSortKey{
+	"new":      {"created_at", "timestamptz"},
+	"top":      {"likes_count", "integer"},
+	"trending": {"trending_score", "double precision"},
+}
+
+// List returns one gallery page, ordered by the filter's sort and then by
+// ID, newest first. The total counts every crosshair matching the filter.
+func (r *CrosshairRepository) List(filter domain.CrosshairFilter) ([]domain.Crosshair, int64, error) {
+	key, ok := crosshairSortKeys[filter.Sort]
+	if !ok {
+		key = crosshairSortKeys["new"]
+	}
+
+	var total int64
+	if err := r.db.Table("(?) AS crosshairs", r.galleryQuery(filter)).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	page := r.db.Table("(?) AS crosshairs", r.galleryQuery(filter)).Preload("Author")
+	if filter.After != nil {
+		page = page.Where(fmt.Sprintf("(%s, id) < (CAST(? AS %s), CAST(? AS uuid))", key.column, key.sqlType),
+			filter.After.Value, filter.After.ID)
+	}
+
 	var crosshairs []domain.Crosshair
-	offset := (page - 1) * limit
-	err := r.db.Preload("Author").Order("created_at DESC").Limit(limit).Offset(offset).Find(&crosshairs).Error
+	err := page.Order(key.column + " DESC, id DESC").Limit(filter.Limit).Find(&crosshairs).Error
+	return crosshairs, total, err
+}
+
+// galleryQuery selects every crosshair matching the filter, with
+// trending_score for the trending sort. Paging is applied on top of it.
+func (r *CrosshairRepository) galleryQuery(filter domain.CrosshairFilter) *gorm.DB {
+	gallery := r.db.Model(&domain.Crosshair{})
+	if filter.Sort == "trending" {
+		at := filter.TrendingAt
+		since := at.Add(-filter.TrendingWindow)
+		halfLife := filter.TrendingHalfLife.Seconds()
+		gallery = gallery.Select(`crosshairs.*, COALESCE((
+			SELECT SUM(POWER(0.5, EXTRACT(EPOCH FROM (CAST(? AS timestamptz) - cl.created_at)) / CAST(? AS double precision)))
+			FROM crosshair_likes cl
+			WHERE cl.crosshair_id = crosshairs.id AND cl.created_at > ? AND cl.created_at <= ?
+		), 0) + CAST(? AS double precision) * COALESCE((
+			SELECT SUM(dv.views * POWER(0.5, EXTRACT(EPOCH FROM (CAST(? AS timestamptz) - CAST(dv.day AS timestamptz))) / CAST(? AS double precision)))
+			FROM crosshair_daily_views dv
+			WHERE dv.crosshair_id = crosshairs.id AND dv.day >= CAST(? AS date) AND dv.day <= CAST(? AS date)
+		), 0) AS trending_score`,
+			at, halfLife, since, at,
+			filter.TrendingViewWeight, at, halfLife, since.Format("2006-01-02"), at.Format("2006-01-02"))
+	}
+
+	if filter.ViewerID == uuid.Nil {
+		gallery = gallery.Where("crosshairs.is_public = true")
+	} else {
+		gallery = gallery.Where("(crosshairs.is_public = true OR crosshairs.author_id = ?)", filter.ViewerID)
+	}
+	if filter.Dot != nil {
+		gallery = gallery.Where("CAST(crosshairs.settings->>'dot' AS boolean) = ?", *filter.Dot)
+	}
+	if filter.ColorFamily != "" {
+		gallery = gallery.Where("crosshair_color_family(crosshairs.settings->>'color') = ?", filter.ColorFamily)
+	}
+	if filter.MinThickness != nil {
+		gallery = gallery.Where("CAST(crosshairs.settings->>'thickness' AS integer) >= ?", *filter.MinThickness)
+	}
+	if filter.MaxThickness != nil {
+		gallery = gallery.Where("CAST(crosshairs.settings->>'thickness' AS integer) <= ?", *filter.MaxThickness)
+	}
+	if filter.Tag != "" {
+		gallery = gallery.Where(`EXISTS (
+			SELECT 1 FROM content_tags ct
+			JOIN tags t ON t.id = ct.tag_id
+			WHERE ct.content_type = 'crosshair' AND ct.content_id = crosshairs.id AND LOWER(t.name) = LOWER(?)
+		)`, filter.Tag)
+	}
+	return gallery
+}
+
+func (r *CrosshairRepository) GetByAuthorID(authorID uuid.UUID, includePrivate bool, limit int) ([]domain.Crosshair, error) {
+	var crosshairs []domain.Crosshair
+	query := r.db.Where("author_id = ?", authorID)
+	if !includePrivate {
+		query = query.Where("is_public = true")
+	}
+	err := query.Order("created_at DESC").Limit(limit).Find(&crosshairs).Error
 	return crosshairs, err
 }
 
-func (r *CrosshairRepository) Count() (int64, error) {
-	var count int64
-	err := r.db.Model(&domain.Crosshair{}).Count(&count).Error
-	return count, err
+// Update saves the editable fields of crosshair and keeps the version it
+// replaces as a revision.
+func (r *CrosshairRepository) Update(crosshair *domain.Crosshair) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var current domain.Crosshair
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&current, "id = ?", crosshair.ID).Error
+		if err != nil {
+			return err
+		}
+
+		revision := domain.CrosshairRevision{
+			ID:          uuid.New(),
+			CrosshairID: current.ID,
+			Title:       current.Title,
+			Description: current.Description,
+			Settings:    current.Settings,
+			IsPublic:    current.IsPublic,
+			CreatedAt:   time.Now(),
+		}
+		if err := tx.Create(&revision).Error; err != nil {
+			return err
+		}
+
+		err = tx.Exec(`
+			DELETE FROM crosshair_revisions
+			WHERE crosshair_id = ? AND id NOT IN (
+				SELECT id FROM crosshair_revisions
+				WHERE crosshair_id = ?
+				ORDER BY created_at DESC
+				LIMIT ?
+			)`, current.ID, current.ID, maxCrosshairRevisions).Error
+		if err != nil {
+			return err
+		}
+
+		crosshair.UpdatedAt = time.Now()
+		return tx.Model(&domain.Crosshair{}).Where("id = ?", crosshair.ID).Updates(map[string]interface{}{
+			"title":       crosshair.Title,
+			"description": crosshair.Description,
+			"settings":    crosshair.Settings,
+			"is_public":   crosshair.IsPublic,
+			"updated_at":  crosshair.UpdatedAt,
+		}).Error
+	})
+}
+
+func (r *CrosshairRepository) GetRevisions(crosshairID uuid.UUID) ([]domain.CrosshairRevision, error) {
+	var revisions []domain.CrosshairRevision
+	err := r.db.Where("crosshair_id = ?", crosshairID).Order("created_at DESC").Find(&revisions).Error
+	return revisions, err
+}
+
+func (r *CrosshairRepository) GetRevision(crosshairID, revisionID uuid.UUID) (*domain.CrosshairRevision, error) {
+	var revision domain.CrosshairRevision
+	err := r.db.First(&revision, "id = ? AND crosshair_id = ?", revisionID, crosshairID).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("crosshair revision not found")
+		}
+		return nil, err
+	}
+	return &revision, nil
+}
+
+// Like records a like and bumps likes_count in the same transaction. Liking
+// twice is a no-op. It returns the resulting likes_count.
+func (r *CrosshairRepository) Like(crosshairID, userID uuid.UUID) (int, error) {
+	return r.changeLike(crosshairID, func(tx *gorm.DB) (*gorm.DB, string) {
+		return tx.Exec(`
+			INSERT INTO crosshair_likes (user_id, crosshair_id)
+			VALUES (?, ?)
+			ON CONFLICT (user_id, crosshair_id) DO NOTHING
+		`, userID, crosshairID), "likes_count + 1"
+	})
+}
+
+// Unlike removes a like and lowers likes_count in the same transaction.
+// Unliking a crosshair that is not liked is a no-op.
+func (r *CrosshairRepository) Unlike(crosshairID, userID uuid.UUID) (int, error) {
+	return r.changeLike(crosshairID, func(tx *gorm.DB) (*gorm.DB, string) {
+		return tx.Exec(`DELETE FROM crosshair_likes WHERE user_id = ? AND crosshair_id = ?`, userID, crosshairID),
+			"GREATEST(likes_count - 1, 0)"
+	})
+}
+
+func (r *CrosshairRepository) changeLike(crosshairID uuid.UUID, change func(tx *gorm.DB) (*gorm.DB, string)) (int, error) {
+	var likesCount int
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result, newCount := change(tx)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected > 0 {
+			err := tx.Model(&domain.Crosshair{}).Where("id = ?", crosshairID).
+				Update("likes_count", gorm.Expr(newCount)).Error
+			if err != nil {
+				return err
+			}
+		}
+		return tx.Model(&domain.Crosshair{}).Where("id = ?", crosshairID).
+			Select("likes_count").Scan(&likesCount).Error
+	})
+	return likesCount, err
+}
+
+// Fork creates a copy of a crosshair and bumps the fork count of the
+// original in the same transaction. ForkedFrom must be set.
+func (r *CrosshairRepository) Fork(fork *domain.Crosshair) error {
+	fork.ID = uuid.New()
+	fork.CreatedAt = time.Now()
+	fork.UpdatedAt = fork.CreatedAt
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(fork).Error; err != nil {
+			return err
+		}
+		return tx.Model(&domain.Crosshair{}).Where("id = ?", *fork.ForkedFrom).
+			Update("forks_count", gorm.Expr("forks_count + 1")).Error
+	})
+}
+
+// GetForks lists the direct forks of a crosshair that the viewer may see,
+// newest first.
+func (r *CrosshairRepository) GetForks(id, viewerID uuid.UUID, limit int) ([]domain.Crosshair, error) {
+	var forks []domain.Crosshair
+	query := r.db.Preload("Author").Where("forked_from = ?", id)
+	if viewerID == uuid.Nil {
+		query = query.Where("is_public = true")
+	} else {
+		query = query.Where("(is_public = true OR author_id = ?)", viewerID)
+	}
+	err := query.Order("created_at DESC").Limit(limit).Find(&forks).Error
+	return forks, err
+}
+
+// GetAncestors follows forked_from up from a crosshair for at most
+// maxDepth steps and returns the ancestors, nearest first.
+func (r *CrosshairRepository) GetAncestors(id uuid.UUID, maxDepth int) ([]domain.Crosshair, error) {
+	var lineage []struct {
+		ID    uuid.UUID
+		Depth int
+	}
+	err := r.db.Raw(`
+		WITH RECURSIVE lineage AS (
+			SELECT forked_from AS id, 1 AS depth FROM crosshairs WHERE id = ?
+			UNION ALL
+			SELECT c.forked_from, l.depth + 1
+			FROM lineage l
+			JOIN crosshairs c ON c.id = l.id
+			WHERE l.depth < ?
+		)
+		SELECT id, depth FROM lineage WHERE id IS NOT NULL ORDER BY depth
+	`, id, maxDepth).Scan(&lineage).Error
+	if err != nil || len(lineage) == 0 {
+		return nil, err
+	}
+
+	ids := make([]uuid.UUID, len(lineage))
+	for i, l := range lineage {
+		ids[i] = l.ID
+	}
+	var crosshairs []domain.Crosshair
+	if err := r.db.Preload("Author").Where("id IN ?", ids).Find(&crosshairs).Error; err != nil {
+		return nil, err
+	}
+
+	byID := make(map[uuid.UUID]domain.Crosshair, len(crosshairs))
+	for _, c := range crosshairs {
+		byID[c.ID] = c
+	}
+	ancestors := make([]domain.Crosshair, 0, len(ids))
+	for _, ancestorID := range ids {
+		if c, ok := byID[ancestorID]; ok {
+			ancestors = append(ancestors, c)
+		}
+	}
+	return ancestors, nil
+}
+
+// LikedCrosshairIDs returns which of crosshairIDs the user has liked.
+func (r *CrosshairRepository) LikedCrosshairIDs(userID uuid.UUID, crosshairIDs []uuid.UUID) (map[uuid.UUID]bool, error) {
+	liked := make(map[uuid.UUID]bool)
+	if len(crosshairIDs) == 0 {
+		return liked, nil
+	}
+
+	var ids []uuid.UUID
+	err := r.db.Model(&domain.CrosshairLike{}).
+		Where("user_id = ? AND crosshair_id IN ?", userID, crosshairIDs).
+		Pluck("crosshair_id", &ids).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		liked[id] = true
+	}
+	return liked, nil
 }
 
-func (r *CrosshairRepository) GetByAuthorID(authorID uuid.UUID, limit int) ([]domain.Crosshair, error) {
+// GetLikedByUser returns the crosshairs a user liked and can still see,
+// most recently liked first.
+func (r *CrosshairRepository) GetLikedByUser(userID uuid.UUID, page, limit int) ([]domain.Crosshair, error) {
 	var crosshairs []domain.Crosshair
-	err := r.db.Where("author_id = ?", authorID).Order("created_at DESC").Limit(limit).Find(&crosshairs).Error
+	offset := (page - 1) * limit
+	err := r.likedByUser(userID).Preload("Author").
+		Select("crosshairs.*").
+		Order("cl.created_at DESC").
+		Limit(limit).Offset(offset).
+		Find(&crosshairs).Error
 	return crosshairs, err
 }
 
-func (r *CrosshairRepository) Like(crosshairID, userID uuid.UUID) error {
-	// TODO: Implement
-	return nil
+// CountLikedByUser counts the crosshairs GetLikedByUser lists.
+func (r *CrosshairRepository) CountLikedByUser(userID uuid.UUID) (int64, error) {
+	var count int64
+	err := r.likedByUser(userID).Count(&count).Error
+	return count, err
 }
 
-func (r *CrosshairRepository) Unlike(crosshairID, userID uuid.UUID) error {
-	// TODO: Implement
-	return nil
+// likedByUser selects the crosshairs a user liked that are public or their
+// own: crosshairs made private after the like are left out.
+func (r *CrosshairRepository) likedByUser(userID uuid.UUID) *gorm.DB {
+	return r.db.Model(&domain.Crosshair{}).
+		Joins("JOIN crosshair_likes cl ON cl.crosshair_id = crosshairs.id").
+		Where("cl.user_id = ?", userID).
+		Where("crosshairs.is_public = true OR crosshairs.author_id = ?", userID)
 }
 
-func (r *CrosshairRepository) Delete(id, authorID uuid.UUID) error {
-	result := r.db.Where("id = ? AND author_id = ?", id, authorID).Delete(&domain.Crosshair{})
-	if result.Error != nil {
-		return result.Error
+// AddViews adds flushed view counts to the daily totals and to view_count
+// in one transaction. Views of crosshairs deleted meanwhile are dropped.
+func (r *CrosshairRepository) AddViews(views []domain.CrosshairDailyViews) error {
+	if len(views) == 0 {
+		return nil
 	}
-	if result.RowsAffected == 0 {
-		return errors.New("crosshair not found or not owned by user")
+
+	ids := make([]string, len(views))
+	days := make([]string, len(views))
+	counts := make([]int64, len(views))
+	for i, v := range views {
+		ids[i] = v.CrosshairID.String()
+		days[i] = v.Day.Format("2006-01-02")
+		counts[i] = int64(v.Views)
 	}
-	return nil
+
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec(`
+			INSERT INTO crosshair_daily_views (crosshair_id, day, views)
+			SELECT v.crosshair_id, v.day, SUM(v.views)
+			FROM unnest($1::uuid[], $2::date[], $3::int[]) AS v(crosshair_id, day, views)
+			JOIN crosshairs c ON c.id = v.crosshair_id
+			GROUP BY v.crosshair_id, v.day
+			ON CONFLICT (crosshair_id, day) DO UPDATE SET views = crosshair_daily_views.views + EXCLUDED.views
+		`, pq.Array(ids), pq.Array(days), pq.Array(counts)).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Exec(`
+			UPDATE crosshairs c SET view_count = c.view_count + v.views
+			FROM (
+				SELECT crosshair_id, SUM(views) AS views
+				FROM unnest($1::uuid[], $2::int[]) AS v(crosshair_id, views)
+				GROUP BY crosshair_id
+			) v
+			WHERE c.id = v.crosshair_id
+		`, pq.Array(ids), pq.Array(counts)).Error
+	})
+}
+
+// GetViewStats lists an author's crosshairs, most viewed first, with their
+// views since the given day.
+func (r *CrosshairRepository) GetViewStats(authorID uuid.UUID, since time.Time) ([]domain.CrosshairViewStats, error) {
+	var stats []domain.CrosshairViewStats
+	err := r.db.Raw(`
+		SELECT c.id, c.title, c.is_public, c.view_count, c.likes_count, c.created_at,
+			COALESCE(SUM(dv.views), 0) AS recent_views
+		FROM crosshairs c
+		LEFT JOIN crosshair_daily_views dv ON dv.crosshair_id = c.id AND dv.day >= ?
+		WHERE c.author_id = ?
+		GROUP BY c.id
+		ORDER BY c.view_count DESC, c.created_at DESC
+	`, since.Format("2006-01-02"), authorID).Scan(&stats).Error
+	return stats, err
+}
+
+// GetDailyViews sums the daily views of an author's crosshairs since the
+// given day. Days without views are left out.
+func (r *CrosshairRepository) GetDailyViews(authorID uuid.UUID, since time.Time) ([]domain.CrosshairDailyViews, error) {
+	var views []domain.CrosshairDailyViews
+	err := r.db.Raw(`
+		SELECT dv.day, SUM(dv.views) AS views
+		FROM crosshair_daily_views dv
+		JOIN crosshairs c ON c.id = dv.crosshair_id
+		WHERE c.author_id = ? AND dv.day >= ?
+		GROUP BY dv.day
+		ORDER BY dv.day
+	`, authorID, since.Format("2006-01-02")).Scan(&views).Error
+	return views, err
+}
+
+// Delete removes an author's crosshair and its comments. Deleting a fork
+// lowers the fork count of the crosshair it was forked from.
+func (r *CrosshairRepository) Delete(id, authorID uuid.UUID) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var crosshair domain.Crosshair
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND author_id = ?", id, authorID).First(&crosshair).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("crosshair not found or not owned by user")
+			}
+			return err
+		}
+
+		if err := tx.Delete(&domain.Crosshair{}, "id = ?", id).Error; err != nil {
+			return err
+		}
+		if err := deleteContentComments(tx, domain.CommentContentCrosshair, id); err != nil {
+			return err
+		}
+		if crosshair.ForkedFrom == nil {
+			return nil
+		}
+		return tx.Model(&domain.Crosshair{}).Where("id = ?", *crosshair.ForkedFrom).
+			Update("forks_count", gorm.Expr("GREATEST(forks_count - 1, 0)")).Error
+	})
 }
@@ -0,0 +1,135 @@
+package repositories
+
+import (
+	"github.com/lib/pq"
+	"github.com/quenyu/deadlock-stats/internal/domain"
+	"gorm.io/gorm"
+)
+
+type MatchDetailsRepository struct {
+	db *gorm.DB
+}
+
+func NewMatchDetailsRepository(db *gorm.DB) *MatchDetailsRepository {
+	return &MatchDetailsRepository{db: db}
+}
+
+// PendingMatchIDs lists matches of tracked players, newest first, whose
+// details have not been ingested yet and that have failed fewer than
+// maxAttempts times.
+func (r *MatchDetailsRepository) PendingMatchIDs(limit, maxAttempts int) ([]int64, error) {
+	var ids []int64
+	err := r.db.Raw(`
+		SELECT m.match_id
+		FROM (
+			SELECT CASE WHEN id ~ '^[0-9]{1,18}$' THEN id::bigint END AS match_id, match_time
+			FROM matches
+		) m
+		WHERE m.match_id IS NOT NULL
+		  AND NOT EXISTS (SELECT 1 FROM match_details md WHERE md.match_id = m.match_id)
+		  AND NOT EXISTS (
+			SELECT 1 FROM match_details_failures f
+			WHERE f.match_id = m.match_id AND f.attempts >= ?
+		  )
+		ORDER BY m.match_time DESC
+		LIMIT ?
+	`, maxAttempts, limit).Scan(&ids).Error
+	return ids, err
+}
+
+// RecordFailure counts a failed attempt at ingesting a match and returns how
+// many attempts have failed so far.
+func (r *MatchDetailsRepository) RecordFailure(matchID int64, reason string) (int, error) {
+	var attempts int
+	err := r.db.Raw(`
+		INSERT INTO match_details_failures (match_id, last_error) VALUES (?, ?)
+		ON CONFLICT (match_id) DO UPDATE
+		SET attempts = match_details_failures.attempts + 1,
+		    last_error = EXCLUDED.last_error,
+		    last_attempt_at = NOW()
+		RETURNING attempts
+	`, matchID, reason).Scan(&attempts).Error
+	return attempts, err
+}
+
+// SaveMissing records that the Deadlock API does not have a match, so that
+// it is not fetched again.
+func (r *MatchDetailsRepository) SaveMissing(matchID int64) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM match_details_failures WHERE match_id = ?", matchID).Error; err != nil {
+			return err
+		}
+		return tx.Exec(`
+			INSERT INTO match_details (match_id, found) VALUES (?, false)
+			ON CONFLICT (match_id) DO NOTHING
+		`, matchID).Error
+	})
+}
+
+// Save stores a match with its players and their item purchases. A match
+// that is already stored is left as is.
+func (r *MatchDetailsRepository) Save(match domain.MatchDetails) error {
+	var (
+		accountIDs []int64
+		heroIDs    []int64
+		teams      []int64
+		won        []bool
+		kills      []int64
+		deaths     []int64
+		assists    []int64
+
+		itemAccountIDs []int64
+		itemIDs        []int64
+		itemTimes      []int64
+	)
+	for _, player := range match.Players {
+		accountIDs = append(accountIDs, player.AccountID)
+		heroIDs = append(heroIDs, int64(player.HeroID))
+		teams = append(teams, int64(player.Team))
+		won = append(won, player.Won)
+		kills = append(kills, int64(player.Kills))
+		deaths = append(deaths, int64(player.Deaths))
+		assists = append(assists, int64(player.Assists))
+		for _, item := range player.Items {
+			itemAccountIDs = append(itemAccountIDs, player.AccountID)
+			itemIDs = append(itemIDs, item.ItemID)
+			itemTimes = append(itemTimes, int64(item.GameTimeS))
+		}
+	}
+
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM match_details_failures WHERE match_id = ?", match.MatchID).Error; err != nil {
+			return err
+		}
+		result := tx.Exec(`
+			INSERT INTO match_details (match_id, found, start_time, duration_s)
+			VALUES (?, true, ?, ?)
+			ON CONFLICT (match_id) DO NOTHING
+		`, match.MatchID, match.StartTime, match.DurationS)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		if len(accountIDs) == 0 {
+			return nil
+		}
+
+		err := tx.Exec(`
+			INSERT INTO match_players (match_id, account_id, hero_id, team, won, kills, deaths, assists)
+			SELECT $1, p.account_id, p.hero_id, p.team, p.won, p.kills, p.deaths, p.assists
+			FROM unnest($2::bigint[], $3::int[], $4::smallint[], $5::boolean[], $6::int[], $7::int[], $8::int[])
+				AS p(account_id, hero_id, team, won, kills, deaths, assists)
+			ON CONFLICT DO NOTHING
+		`, match.MatchID, pq.Array(accountIDs), pq.Array(heroIDs), pq.Array(teams), pq.Array(won),
+			pq.Array(kills), pq.Array(deaths), pq.Array(assists)).Error
+		if err != nil || len(itemIDs) == 0 {
+			return err
+		}
+
+		return tx.Exec(`
+			INSERT INTO match_player_items (match_id, account_id, item_id, game_time_s)
+			SELECT $1, i.account_id, i.item_id, i.game_time_s
+			FROM unnest($2::bigint[], $3::bigint[], $4::int[]) AS i(account_id, item_id, game_time_s)
+			ON CONFLICT DO NOTHING
+		`, match.MatchID, pq.Array(itemAccountIDs), pq.Array(itemIDs), pq.Array(itemTimes)).Error
+	})
+}
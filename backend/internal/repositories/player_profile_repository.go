@@ -4,9 +4,14 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"math"
 	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 	"github.com/quenyu/deadlock-stats/internal/domain"
 	"gorm.io/gorm"
 )
@@ -197,32 +202,415 @@ func (r *PlayerProfilePostgresRepository) FindRecentMatchesBySteamID(ctx context
 	return matches, nil
 }
 
+// nicknameRelevanceSQL scores a folded nickname column against the folded
+// query: exact and prefix matches first, then trigram/word similarity (which
+// tolerates typos) plus a full-text rank for multi-word names.
+const nicknameRelevanceSQL = `
+	CASE
+		WHEN %[1]s = q.term THEN 1.0
+		WHEN %[1]s LIKE q.prefix ESCAPE '\' THEN 0.9
+		ELSE 0.8 * GREATEST(similarity(%[1]s, q.term), word_similarity(q.term, %[1]s))
+	END
+`
+
+// nicknameMatchSQL is indexable through the pg_trgm GIN indexes.
+const nicknameMatchSQL = `(%[1]s LIKE q.contains ESCAPE '\' OR %[1]s %% q.term OR q.term <%% %[1]s)`
+
 func (r *PlayerProfilePostgresRepository) SearchByNickname(ctx context.Context, query string) ([]domain.User, error) {
-	var users []domain.User
-	err := r.db.WithContext(ctx).
-		Where("nickname ILIKE ?", fmt.Sprintf("%%%s%%", query)).
-		Limit(10).
-		Find(&users).Error
+	return r.searchUsers(ctx, query, 10)
+}
+
+func (r *PlayerProfilePostgresRepository) SearchByNicknamePartial(ctx context.Context, query string, limit int) ([]domain.User, error) {
+	return r.searchUsers(ctx, query, limit)
+}
+
+func (r *PlayerProfilePostgresRepository) searchUsers(ctx context.Context, query string, limit int) ([]domain.User, error) {
+	ranked, err := r.SearchByNicknameRanked(ctx, query, limit)
 	if err != nil {
 		return nil, err
 	}
+
+	users := make([]domain.User, len(ranked))
+	for i, user := range ranked {
+		users[i] = user.User
+	}
 	return users, nil
 }
 
-func (r *PlayerProfilePostgresRepository) SearchByNicknamePartial(ctx context.Context, query string, limit int) ([]domain.User, error) {
-	var users []domain.User
+// nicknameSearchSQL selects every user whose current nickname matches the
+// query, together with its relevance score. It expects the args built by
+// searchTermArgs.
+var nicknameSearchSQL = fmt.Sprintf(`
+	WITH q AS (
+		SELECT search_fold(@term) AS term,
+		       search_fold(@prefix) AS prefix,
+		       search_fold(@contains) AS contains
+	)
+	SELECT u.id, u.steam_id, u.nickname, u.avatar_url, u.profile_url, u.country_code, u.created_at, u.updated_at,
+	       (%s) + 0.1 * ts_rank(u.nickname_tsv, plainto_tsquery('simple', q.term)) AS relevance
+	FROM users u, q
+	WHERE %s OR u.nickname_tsv @@ plainto_tsquery('simple', q.term)
+`, fmt.Sprintf(nicknameRelevanceSQL, "u.nickname_search"), fmt.Sprintf(nicknameMatchSQL, "u.nickname_search"))
+
+// SearchByNicknameRanked performs an accent/case-insensitive, typo-tolerant
+// nickname search and returns users ordered by relevance.
+func (r *PlayerProfilePostgresRepository) SearchByNicknameRanked(ctx context.Context, query string, limit int) ([]domain.RankedUser, error) {
+	var users []domain.RankedUser
+
+	sqlQuery := nicknameSearchSQL + " ORDER BY relevance DESC, u.nickname ASC LIMIT @limit"
+
+	err := r.db.WithContext(ctx).Raw(sqlQuery, searchTermArgs(query, limit)).Scan(&users).Error
+	if err != nil {
+		return nil, err
+	}
 
-	err := r.db.WithContext(ctx).
-		Where("nickname ILIKE ?", fmt.Sprintf("%s%%", query)).
-		Or("nickname ILIKE ?", fmt.Sprintf("%%%s%%", query)).
-		Limit(limit).
-		Find(&users).Error
+	return users, nil
+}
+
+// SearchByNicknamePage returns one page of the nickname search ordered by
+// sortBy ("relevance", "nickname", "created_at" or "updated_at"), starting
+// after the given keyset or, without one, at offset. Pages without a keyset
+// also return the total number of matches.
+func (r *PlayerProfilePostgresRepository) SearchByNicknamePage(ctx context.Context, query, sortBy string, desc bool, after *domain.SearchKeyset, offset, limit int) ([]domain.RankedUser, int, error) {
+	var rows []struct {
+		domain.RankedUser `gorm:"embedded"`
+		TotalCount        int
+	}
 
+	args := searchTermArgs(query, limit)
+	total, err := r.searchPage(ctx, nicknameSearchSQL, args, sortBy, desc, after, offset, &rows, func() int {
+		if len(rows) == 0 {
+			return -1
+		}
+		return rows[0].TotalCount
+	})
 	if err != nil {
+		return nil, 0, err
+	}
+
+	users := make([]domain.RankedUser, len(rows))
+	for i, row := range rows {
+		users[i] = row.RankedUser
+	}
+	return users, total, nil
+}
+
+// FilterNicknameMatches returns the subset of steamIDs whose current
+// nickname matches the query.
+func (r *PlayerProfilePostgresRepository) FilterNicknameMatches(ctx context.Context, query string, steamIDs []string) (map[string]bool, error) {
+	matched := make(map[string]bool)
+	if len(steamIDs) == 0 {
+		return matched, nil
+	}
+
+	var found []string
+	args := searchTermArgs(query, 0)
+	args["steamIDs"] = steamIDs
+
+	sqlQuery := "SELECT steam_id FROM (" + nicknameSearchSQL + ") matches WHERE steam_id IN @steamIDs"
+	if err := r.db.WithContext(ctx).Raw(sqlQuery, args).Scan(&found).Error; err != nil {
 		return nil, err
 	}
 
-	return users, nil
+	for _, steamID := range found {
+		matched[steamID] = true
+	}
+	return matched, nil
+}
+
+// FindMatchParticipants returns the tracked users that played in a stored
+// match, ordered by nickname.
+func (r *PlayerProfilePostgresRepository) FindMatchParticipants(ctx context.Context, matchID string) ([]domain.MatchParticipant, error) {
+	var participants []domain.MatchParticipant
+	err := r.db.WithContext(ctx).Table("player_match_stats as pms").
+		Select("u.*, pms.hero_name, m.match_time").
+		Joins("JOIN users as u ON u.id = pms.user_id").
+		Joins("JOIN matches as m ON m.id = pms.match_id").
+		Where("pms.match_id = ?", matchID).
+		Order("u.nickname ASC").
+		Find(&participants).Error
+	return participants, err
+}
+
+// DeadlockActivity reports for every known user among steamIDs whether they
+// have played at least one match.
+func (r *PlayerProfilePostgresRepository) DeadlockActivity(ctx context.Context, steamIDs []string) (map[string]bool, error) {
+	activity := make(map[string]bool, len(steamIDs))
+	if len(steamIDs) == 0 {
+		return activity, nil
+	}
+
+	var rows []struct {
+		SteamID string
+		Active  bool
+	}
+	query := `
+		SELECT u.steam_id, COALESCE(ps.total_matches, 0) > 0 AS active
+		FROM users u
+		LEFT JOIN player_stats ps ON ps.user_id = u.id
+		WHERE u.steam_id = ANY($1)
+	`
+	if err := r.db.WithContext(ctx).Raw(query, pq.Array(steamIDs)).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		activity[row.SteamID] = activity[row.SteamID] || row.Active
+	}
+	return activity, nil
+}
+
+// FindStatsBySteamIDs returns the known users among steamIDs that have a
+// player_stats row.
+func (r *PlayerProfilePostgresRepository) FindStatsBySteamIDs(ctx context.Context, steamIDs []string) ([]domain.UserWithStats, error) {
+	var users []domain.UserWithStats
+	if len(steamIDs) == 0 {
+		return users, nil
+	}
+
+	query := `
+		SELECT u.*, ps.player_rank, ps.total_matches, ps.win_rate, ps.kd_ratio
+		FROM users u
+		JOIN player_stats ps ON ps.user_id = u.id
+		WHERE u.steam_id = ANY($1)
+	`
+	err := r.db.WithContext(ctx).Raw(query, pq.Array(steamIDs)).Scan(&users).Error
+	return users, err
+}
+
+type searchSortKey struct {
+	column  string
+	sqlType string
+}
+
+// searchSortKeys maps the public sort names onto the columns produced by the
+// search queries, with the type used to cast keyset values back.
+var searchSortKeys = map[string]searchSortKey{
+	"relevance":  {"relevance", "double precision"},
+	"nickname":   {"nickname", "text"},
+	"created_at": {"created_at", "timestamptz"},
+	"updated_at": {"updated_at", "timestamptz"},
+	"rank":       {"player_rank", "integer"},
+	"matches":    {"total_matches", "integer"},
+	"win_rate":   {"win_rate", "real"},
+	"kd_ratio":   {"kd_ratio", "real"},
+}
+
+func lookupSearchSortKey(sortBy string) searchSortKey {
+	if key, ok := searchSortKeys[sortBy]; ok {
+		return key
+	}
+	return searchSortKeys["nickname"]
+}
+
+// ValidKeysetValue reports whether a keyset value can be cast to the type of
+// the sortBy column. Values are produced by the Keyset methods, so numbers
+// must be rendered the way those methods render them.
+func (r *PlayerProfilePostgresRepository) ValidKeysetValue(sortBy, value string) bool {
+	switch key := lookupSearchSortKey(sortBy); key.sqlType {
+	case "integer":
+		_, err := strconv.ParseInt(value, 10, 32)
+		return err == nil
+	case "real", "double precision":
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil || math.IsNaN(f) || math.IsInf(f, 0) || strconv.FormatFloat(f, 'g', -1, 64) != value {
+			return false
+		}
+		return key.sqlType != "real" || math.Abs(f) <= math.MaxFloat32
+	case "timestamptz":
+		_, err := time.Parse(time.RFC3339Nano, value)
+		return err == nil
+	default:
+		return !strings.ContainsRune(value, 0)
+	}
+}
+
+// searchPage runs inner as a paged query. Without a keyset, total_count is
+// computed over all matches; totalOf reads it from the scanned rows and
+// returns -1 when the page is empty, in which case the total is counted
+// separately. Pages after a keyset are not counted and return a total of 0:
+// callers carry the total of the first page forward.
+func (r *PlayerProfilePostgresRepository) searchPage(ctx context.Context, inner string, args map[string]interface{}, sortBy string, desc bool, after *domain.SearchKeyset, offset int, dest interface{}, totalOf func() int) (int, error) {
+	key := lookupSearchSortKey(sortBy)
+
+	direction, comparison := "ASC", ">"
+	if desc {
+		direction, comparison = "DESC", "<"
+	}
+
+	if after != nil {
+		args["afterValue"] = after.Value
+		args["afterID"] = after.ID.String()
+		sqlQuery := fmt.Sprintf(`
+			SELECT * FROM (%s) page
+			WHERE (%s, id) %s (CAST(@afterValue AS %s), CAST(@afterID AS uuid))
+			ORDER BY %s %s, id %s
+			LIMIT @limit
+		`, inner, key.column, comparison, key.sqlType, key.column, direction, direction)
+		return 0, r.db.WithContext(ctx).Raw(sqlQuery, args).Scan(dest).Error
+	}
+	args["offset"] = offset
+
+	sqlQuery := fmt.Sprintf(`
+		SELECT page.*, COUNT(*) OVER() AS total_count FROM (%s) page
+		ORDER BY %s %s, id %s
+		LIMIT @limit OFFSET @offset
+	`, inner, key.column, direction, direction)
+
+	if err := r.db.WithContext(ctx).Raw(sqlQuery, args).Scan(dest).Error; err != nil {
+		return 0, err
+	}
+
+	if total := totalOf(); total >= 0 {
+		return total, nil
+	}
+	if offset == 0 {
+		return 0, nil
+	}
+	return r.countSearch(ctx, inner, args)
+}
+
+func (r *PlayerProfilePostgresRepository) countSearch(ctx context.Context, inner string, args map[string]interface{}) (int, error) {
+	var total int64
+	if err := r.db.WithContext(ctx).Raw("SELECT COUNT(*) FROM ("+inner+") matches", args).Scan(&total).Error; err != nil {
+		return 0, err
+	}
+	return int(total), nil
+}
+
+func searchTermArgs(query string, limit int) map[string]interface{} {
+	escaped := escapeLikePattern(query)
+	return map[string]interface{}{
+		"term":     query,
+		"prefix":   escaped + "%",
+		"contains": "%" + escaped + "%",
+		"limit":    limit,
+	}
+}
+
+func escapeLikePattern(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
+}
+
+// SearchByNicknameHistory finds users whose former nicknames match the query.
+// Only the best matching alias is returned for each user, and aliases equal to
+// the current nickname are skipped.
+func (r *PlayerProfilePostgresRepository) SearchByNicknameHistory(ctx context.Context, query string, limit int) ([]domain.NicknameAliasMatch, error) {
+	var matches []domain.NicknameAliasMatch
+
+	sqlQuery := fmt.Sprintf(`
+		WITH q AS (
+			SELECT search_fold(@term) AS term,
+			       search_fold(@prefix) AS prefix,
+			       search_fold(@contains) AS contains
+		)
+		SELECT * FROM (
+			SELECT DISTINCT ON (u.id)
+				u.id, u.steam_id, u.nickname, u.avatar_url, u.profile_url, u.country_code, u.created_at, u.updated_at,
+				h.nickname AS matched_alias,
+				h.last_seen_at AS alias_last_seen_at,
+				%s AS relevance
+			FROM user_nickname_history h
+			JOIN users u ON u.id = h.user_id
+			CROSS JOIN q
+			WHERE %s AND h.nickname <> u.nickname
+			ORDER BY u.id, relevance DESC, h.last_seen_at DESC
+		) aliases
+		ORDER BY relevance DESC, alias_last_seen_at DESC
+		LIMIT @limit
+	`, fmt.Sprintf(nicknameRelevanceSQL, "search_fold(h.nickname)"), fmt.Sprintf(nicknameMatchSQL, "search_fold(h.nickname)"))
+
+	err := r.db.WithContext(ctx).Raw(sqlQuery, searchTermArgs(query, limit)).Scan(&matches).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return matches, nil
+}
+
+// SearchWithStats searches users that have player_stats, applying the stat
+// bounds and ordering in SQL. matchSteamID switches the text match from
+// nicknames to a partial SteamID; an empty query matches every player. Paging
+// works like SearchByNicknamePage.
+func (r *PlayerProfilePostgresRepository) SearchWithStats(ctx context.Context, query string, matchSteamID bool, filter domain.PlayerStatsFilter, after *domain.SearchKeyset, offset, limit int) ([]domain.UserWithStats, int, error) {
+	var rows []struct {
+		domain.UserWithStats `gorm:"embedded"`
+		TotalCount           int
+	}
+
+	conditions := []string{"TRUE"}
+	switch {
+	case query == "":
+	case matchSteamID:
+		conditions = append(conditions, "u.steam_id LIKE @rawContains ESCAPE '\\'")
+	default:
+		conditions = append(conditions, fmt.Sprintf("(%s OR u.nickname_tsv @@ plainto_tsquery('simple', q.term))",
+			fmt.Sprintf(nicknameMatchSQL, "u.nickname_search")))
+	}
+
+	args := searchTermArgs(query, limit)
+	args["rawContains"] = "%" + escapeLikePattern(query) + "%"
+
+	addBound := func(column, op, name string, value interface{}, set bool) {
+		if set {
+			conditions = append(conditions, fmt.Sprintf("%s %s @%s", column, op, name))
+			args[name] = value
+		}
+	}
+	// Rank bounds and the rank sort only consider ranked players.
+	if filter.MinRank > 0 || filter.MaxRank > 0 || filter.SortBy == "rank" {
+		conditions = append(conditions, "ps.player_rank BETWEEN @minPlayerRank AND @maxPlayerRank")
+		args["minPlayerRank"] = domain.MinPlayerRank
+		args["maxPlayerRank"] = domain.MaxPlayerRank
+	}
+	addBound("ps.player_rank", ">=", "minRank", filter.MinRank, filter.MinRank > 0)
+	addBound("ps.player_rank", "<=", "maxRank", filter.MaxRank, filter.MaxRank > 0)
+	addBound("ps.total_matches", ">=", "minMatches", filter.MinMatches, filter.MinMatches > 0)
+	addBound("ps.total_matches", "<=", "maxMatches", filter.MaxMatches, filter.MaxMatches > 0)
+	addBound("ps.win_rate", ">=", "minWinRate", filter.MinWinRate, filter.MinWinRate > 0)
+	addBound("ps.win_rate", "<=", "maxWinRate", filter.MaxWinRate, filter.MaxWinRate > 0)
+	addBound("ps.kd_ratio", ">=", "minKDRatio", filter.MinKDRatio, filter.MinKDRatio > 0)
+	addBound("ps.kd_ratio", "<=", "maxKDRatio", filter.MaxKDRatio, filter.MaxKDRatio > 0)
+	addBound("u.country_code", "=", "country", strings.ToUpper(filter.Country), filter.Country != "")
+
+	if filter.Hero != "" {
+		conditions = append(conditions, `(lower(ps.favorite_hero) = lower(@hero) OR EXISTS (
+			SELECT 1 FROM player_match_stats pms
+			WHERE pms.user_id = u.id AND lower(pms.hero_name) = lower(@hero)
+		))`)
+		args["hero"] = filter.Hero
+	}
+
+	inner := fmt.Sprintf(`
+		WITH q AS (
+			SELECT search_fold(@term) AS term,
+			       search_fold(@prefix) AS prefix,
+			       search_fold(@contains) AS contains
+		)
+		SELECT u.id, u.steam_id, u.nickname, u.avatar_url, u.profile_url, u.country_code, u.created_at, u.updated_at,
+		       ps.player_rank, ps.total_matches, ps.win_rate, ps.kd_ratio,
+		       (%s) AS relevance
+		FROM users u
+		JOIN player_stats ps ON ps.user_id = u.id
+		CROSS JOIN q
+		WHERE %s
+	`, fmt.Sprintf(nicknameRelevanceSQL, "u.nickname_search"), strings.Join(conditions, " AND "))
+
+	total, err := r.searchPage(ctx, inner, args, filter.SortBy, filter.SortOrder == "desc", after, offset, &rows, func() int {
+		if len(rows) == 0 {
+			return -1
+		}
+		return rows[0].TotalCount
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	users := make([]domain.UserWithStats, len(rows))
+	for i, row := range rows {
+		users[i] = row.UserWithStats
+	}
+	return users, total, nil
 }
 
 func (r *PlayerProfilePostgresRepository) SearchBySteamIDPartial(ctx context.Context, query string, limit int) ([]domain.User, error) {
@@ -1,7 +1,9 @@
 package repositories
 
 import (
+	"context"
 	"errors"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/quenyu/deadlock-stats/internal/domain"
@@ -9,13 +11,31 @@ import (
 )
 
 type UserRepository struct {
-	db *gorm.DB
+	db        *gorm.DB
+	onChanges []func(steamID string, nicknameChanged bool)
 }
 
 func NewUserRepository(db *gorm.DB) *UserRepository {
 	return &UserRepository{db: db}
 }
 
+// OnChange registers fn to be called after a user record has been written.
+// nicknameChanged is true for new users and for users whose nickname differs
+// from the last one recorded. It is meant to be wired up once at startup.
+func (r *UserRepository) OnChange(fn func(steamID string, nicknameChanged bool)) {
+	r.onChanges = append(r.onChanges, fn)
+}
+
+func (r *UserRepository) notifyChange(steamID string, nicknameChanged bool, err error) error {
+	if err != nil {
+		return err
+	}
+	for _, fn := range r.onChanges {
+		fn(steamID, nicknameChanged)
+	}
+	return nil
+}
+
 func (r *UserRepository) FindBySteamID(steamID string) (*domain.User, error) {
 	var user domain.User
 	err := r.db.Where("steam_id = ?", steamID).First(&user).Error
@@ -28,36 +48,76 @@ func (r *UserRepository) FindBySteamID(steamID string) (*domain.User, error) {
 	return &user, nil
 }
 
+// FindBySteamIDs returns the known users among steamIDs. Unknown IDs are
+// simply absent from the result.
+func (r *UserRepository) FindBySteamIDs(ctx context.Context, steamIDs []string) ([]domain.User, error) {
+	var users []domain.User
+	if len(steamIDs) == 0 {
+		return users, nil
+	}
+	err := r.db.WithContext(ctx).Where("steam_id IN ?", steamIDs).Find(&users).Error
+	return users, err
+}
+
 func (r *UserRepository) Create(user *domain.User) error {
-	return r.db.Create(user).Error
+	var nicknameChanged bool
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+
+		var err error
+		nicknameChanged, err = r.recordNickname(tx, user.ID, user.Nickname)
+		return err
+	})
+	return r.notifyChange(user.SteamID, nicknameChanged, err)
 }
 
+// Update saves every field of the user. The nickname is reported as changed
+// when it differs from the stored one.
 func (r *UserRepository) Update(user *domain.User) error {
-	return r.db.Save(user).Error
+	var nicknameChanged bool
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var stored []string
+		if err := tx.Raw(`SELECT nickname FROM users WHERE id = $1 FOR UPDATE`, user.ID).Scan(&stored).Error; err != nil {
+			return err
+		}
+		nicknameChanged = len(stored) == 0 || stored[0] != user.Nickname
+		return tx.Save(user).Error
+	})
+	return r.notifyChange(user.SteamID, nicknameChanged, err)
 }
 
 func (r *UserRepository) FindOrCreate(user *domain.User) error {
-	return r.db.Transaction(func(tx *gorm.DB) error {
+	var nicknameChanged bool
+	err := r.db.Transaction(func(tx *gorm.DB) error {
 		if err := r.insertOrUpdateUser(tx, user); err != nil {
 			return err
 		}
 
+		var err error
+		if nicknameChanged, err = r.recordNickname(tx, user.ID, user.Nickname); err != nil {
+			return err
+		}
+
 		return r.ensurePlayerStats(tx, user.ID)
 	})
+	return r.notifyChange(user.SteamID, nicknameChanged, err)
 }
 
 func (r *UserRepository) insertOrUpdateUser(tx *gorm.DB, user *domain.User) error {
 	query := `
-			INSERT INTO users (id, steam_id, nickname, avatar_url, profile_url, created_at, updated_at) 
-			VALUES ($1, $2, $3, $4, $5, $6, $7) 
+			INSERT INTO users (id, steam_id, nickname, avatar_url, profile_url, created_at, updated_at, country_code) 
+			VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, '')) 
 			ON CONFLICT (steam_id) 
-			DO UPDATE SET nickname = $3, avatar_url = $4, profile_url = $5, updated_at = $7 
+			DO UPDATE SET nickname = $3, avatar_url = $4, profile_url = $5, updated_at = $7,
+				country_code = COALESCE(NULLIF($8, ''), users.country_code)
 			RETURNING id
 		`
 
 	return tx.Raw(query,
 		user.ID, user.SteamID, user.Nickname, user.AvatarURL,
-		user.ProfileURL, user.CreatedAt, user.UpdatedAt,
+		user.ProfileURL, user.CreatedAt, user.UpdatedAt, user.CountryCode,
 	).Scan(user).Error
 }
 
@@ -71,6 +131,88 @@ func (r *UserRepository) ensurePlayerStats(tx *gorm.DB, userID uuid.UUID) error
 	return tx.Exec(statsQuery, userID).Error
 }
 
+// recordNickname adds the nickname to the user's history and reports whether
+// it differs from the nickname recorded last.
+func (r *UserRepository) recordNickname(tx *gorm.DB, userID uuid.UUID, nickname string) (bool, error) {
+	if nickname == "" {
+		return false, nil
+	}
+
+	var latest []string
+	err := tx.Raw(`
+		SELECT nickname FROM user_nickname_history
+		WHERE user_id = $1
+		ORDER BY last_seen_at DESC
+		LIMIT 1
+	`, userID).Scan(&latest).Error
+	if err != nil {
+		return false, err
+	}
+
+	query := `
+		INSERT INTO user_nickname_history (user_id, nickname, first_seen_at, last_seen_at)
+		VALUES ($1, $2, NOW(), NOW())
+		ON CONFLICT (user_id, nickname)
+		DO UPDATE SET last_seen_at = NOW()
+	`
+	if err := tx.Exec(query, userID, nickname).Error; err != nil {
+		return false, err
+	}
+	return len(latest) == 0 || latest[0] != nickname, nil
+}
+
+// FindStaleUsers returns users whose Steam data was last refreshed before the
+// given time, oldest first.
+func (r *UserRepository) FindStaleUsers(ctx context.Context, before time.Time, limit int) ([]domain.User, error) {
+	var users []domain.User
+	err := r.db.WithContext(ctx).
+		Where("updated_at < ?", before).
+		Order("updated_at ASC").
+		Limit(limit).
+		Find(&users).Error
+	return users, err
+}
+
+// RefreshSteamProfile stores freshly fetched Steam data for an existing user
+// and keeps the nickname history in sync.
+func (r *UserRepository) RefreshSteamProfile(ctx context.Context, user *domain.User) error {
+	var nicknameChanged bool
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		query := `
+			UPDATE users
+			SET nickname = $2, avatar_url = $3, profile_url = $4, updated_at = NOW(),
+				country_code = COALESCE(NULLIF($5, ''), country_code)
+			WHERE id = $1
+		`
+		if err := tx.Exec(query, user.ID, user.Nickname, user.AvatarURL, user.ProfileURL, user.CountryCode).Error; err != nil {
+			return err
+		}
+
+		var err error
+		nicknameChanged, err = r.recordNickname(tx, user.ID, user.Nickname)
+		return err
+	})
+	return r.notifyChange(user.SteamID, nicknameChanged, err)
+}
+
+// TouchUsers bumps updated_at so users Steam no longer knows about are not
+// selected again on every refresh run.
+func (r *UserRepository) TouchUsers(ctx context.Context, ids []uuid.UUID) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Exec("UPDATE users SET updated_at = NOW() WHERE id IN ?", ids).Error
+}
+
+func (r *UserRepository) GetNicknameHistory(ctx context.Context, userID uuid.UUID) ([]domain.NicknameHistory, error) {
+	var history []domain.NicknameHistory
+	err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("last_seen_at DESC").
+		Find(&history).Error
+	return history, err
+}
+
 func (r *UserRepository) FindByID(id string) (*domain.User, error) {
 	var user domain.User
 	query := `
@@ -1,7 +1,7 @@
 package services
 
 import (
-	"encoding/json"
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -13,22 +13,24 @@ import (
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
+	"github.com/quenyu/deadlock-stats/internal/clients/steam"
 	"github.com/quenyu/deadlock-stats/internal/config"
 	"github.com/quenyu/deadlock-stats/internal/domain"
-	"github.com/quenyu/deadlock-stats/internal/dto"
 	"github.com/quenyu/deadlock-stats/internal/repositories"
 	"go.uber.org/zap"
 )
 
 type AuthService struct {
 	userRepository *repositories.UserRepository
+	steamClient    *steam.Client
 	config         *config.Config
 	logger         *zap.Logger
 }
 
-func NewAuthService(userRepository *repositories.UserRepository, config *config.Config, logger *zap.Logger) *AuthService {
+func NewAuthService(userRepository *repositories.UserRepository, steamClient *steam.Client, config *config.Config, logger *zap.Logger) *AuthService {
 	return &AuthService{
 		userRepository: userRepository,
+		steamClient:    steamClient,
 		config:         config,
 		logger:         logger.Named("AuthService"),
 	}
@@ -80,7 +82,7 @@ func (s *AuthService) HandleSteamCallback(r *http.Request) (string, error) {
 		return "", err
 	}
 
-	user, err := s.findOrCreateUser(steamID)
+	user, err := s.findOrCreateUser(r.Context(), steamID)
 	if err != nil {
 		return "", err
 	}
@@ -132,20 +134,20 @@ func (s *AuthService) extractSteamID(r *http.Request) (string, error) {
 	return steamID, nil
 }
 
-func (s *AuthService) findOrCreateUser(steamID string) (*domain.User, error) {
+func (s *AuthService) findOrCreateUser(ctx context.Context, steamID string) (*domain.User, error) {
 	user, _ := s.userRepository.FindBySteamID(steamID)
 	if user == nil {
-		return s.createNewUser(steamID)
+		return s.createNewUser(ctx, steamID)
 	}
 
 	s.logger.Info("Found existing user", zap.String("userID", user.ID.String()))
 	return user, nil
 }
 
-func (s *AuthService) createNewUser(steamID string) (*domain.User, error) {
+func (s *AuthService) createNewUser(ctx context.Context, steamID string) (*domain.User, error) {
 	s.logger.Warn("User not found, creating new user", zap.String("steamID", steamID))
 
-	playerSummaries, err := s.GetPlayerSummaries(steamID)
+	playerSummaries, err := s.GetPlayerSummaries(ctx, steamID)
 	if err != nil {
 		s.logger.Error("Failed to get player summaries from Steam API", zap.String("steamID", steamID), zap.Error(err))
 		return nil, err
@@ -184,134 +186,32 @@ func (s *AuthService) generateAndReturnToken(userID uuid.UUID) (string, error) {
 	return jwtToken, nil
 }
 
-func (s *AuthService) ResolveVanityURL(vanityURL string) (string, error) {
-	url := s.buildSteamAPIURLv1("ResolveVanityURL", map[string]string{"vanityurl": vanityURL})
-
-	body, err := s.makeSteamAPIRequest(url)
-	if err != nil {
-		return "", err
-	}
-
-	return s.parseVanityURLResponse(body)
-}
-
-func (s *AuthService) GetPlayerSummaries(steamID string) (*domain.User, error) {
-	url := s.buildSteamAPIURL("GetPlayerSummaries", map[string]string{"steamids": steamID})
-
-	body, err := s.makeSteamAPIRequest(url)
-	if err != nil {
-		return nil, err
-	}
-
-	return s.parsePlayerSummariesResponse(body, steamID)
-}
-
-func (s *AuthService) buildSteamAPIURL(method string, params map[string]string) string {
-	baseURL := fmt.Sprintf("https://api.steampowered.com/ISteamUser/%s/v0002/", method)
-
-	urlParams := url.Values{}
-	urlParams.Add("key", s.config.Steam.APIKey)
-
-	for key, value := range params {
-		urlParams.Add(key, value)
-	}
-
-	return baseURL + "?" + urlParams.Encode()
-}
-
-func (s *AuthService) buildSteamAPIURLv1(method string, params map[string]string) string {
-	baseURL := fmt.Sprintf("https://api.steampowered.com/ISteamUser/%s/v1/", method)
-
-	urlParams := url.Values{}
-	urlParams.Add("key", s.config.Steam.APIKey)
-
-	for key, value := range params {
-		urlParams.Add(key, value)
-	}
-
-	return baseURL + "?" + urlParams.Encode()
+func (s *AuthService) ResolveVanityURL(ctx context.Context, vanityURL string) (string, error) {
+	return s.steamClient.ResolveVanityURL(ctx, vanityURL)
 }
 
-func (s *AuthService) makeSteamAPIRequest(url string) ([]byte, error) {
-	response, err := http.Get(url)
-	if err != nil {
-		return nil, err
-	}
-	defer response.Body.Close()
-
-	body, err := io.ReadAll(response.Body)
+func (s *AuthService) GetPlayerSummaries(ctx context.Context, steamID string) (*domain.User, error) {
+	summary, err := s.steamClient.GetPlayerSummary(ctx, steamID)
 	if err != nil {
 		return nil, err
 	}
 
-	return body, nil
-}
-
-func (s *AuthService) parsePlayerSummariesResponse(body []byte, steamID string) (*domain.User, error) {
-	var resp struct {
-		Response struct {
-			Players json.RawMessage `json:"players"`
-		} `json:"response"`
-	}
-
-	err := json.Unmarshal(body, &resp)
-	if err != nil {
-		return nil, err
-	}
-
-	if resp.Response.Players == nil || string(resp.Response.Players) == "null" {
-		return nil, errors.New("no player data found for steam id")
-	}
-
-	var players []struct {
-		Nickname   string `json:"personaname"`
-		AvatarURL  string `json:"avatarfull"`
-		ProfileURL string `json:"profileurl"`
-	}
-
-	err = json.Unmarshal(resp.Response.Players, &players)
-	if err != nil {
-		return nil, err
-	}
-
-	if len(players) == 0 {
-		return nil, errors.New("no player data found for steam id")
-	}
-
 	s.logger.Info("Steam API GetPlayerSummaries successful",
 		zap.String("steamID", steamID),
-		zap.String("personaname", players[0].Nickname),
-		zap.String("avatarfull", players[0].AvatarURL),
+		zap.String("personaname", summary.PersonaName),
+		zap.String("avatarfull", summary.AvatarFull),
 	)
 
-	playerData := players[0]
-	return s.createUserFromSteamData(steamID, playerData), nil
+	return s.createUserFromSteamData(steamID, summary), nil
 }
 
-func (s *AuthService) parseVanityURLResponse(body []byte) (string, error) {
-	var resp dto.ResolveVanityURLResponse
-	err := json.Unmarshal(body, &resp)
-	if err != nil {
-		return "", err
-	}
-
-	if resp.Response.Success != 1 {
-		return "", errors.New("vanity URL could not be resolved")
-	}
-
-	return resp.Response.SteamID, nil
-}
-
-func (s *AuthService) createUserFromSteamData(steamID string, playerData struct {
-	Nickname   string `json:"personaname"`
-	AvatarURL  string `json:"avatarfull"`
-	ProfileURL string `json:"profileurl"`
-}) *domain.User {
+func (s *AuthService) createUserFromSteamData(steamID string, summary *steam.PlayerSummary) *domain.User {
 	return &domain.User{
-		SteamID:    steamID,
-		Nickname:   playerData.Nickname,
-		AvatarURL:  playerData.AvatarURL,
-		ProfileURL: playerData.ProfileURL,
+		SteamID:     steamID,
+		Nickname:    summary.PersonaName,
+		AvatarURL:   summary.AvatarFull,
+		ProfileURL:  summary.ProfileURL,
+		CountryCode: summary.LocCountryCode,
 	}
 }
 
@@ -333,13 +233,13 @@ func (s *AuthService) GetUserByID(id string) (*domain.User, error) {
 	return s.userRepository.FindByID(id)
 }
 
-func (s *AuthService) SearchPlayersBySteamID(steamID string) (*domain.User, error) {
+func (s *AuthService) SearchPlayersBySteamID(ctx context.Context, steamID string) (*domain.User, error) {
 	localUser, err := s.userRepository.FindBySteamID(steamID)
 	if err == nil && localUser != nil {
 		return localUser, nil
 	}
 
-	steamUser, err := s.GetPlayerSummaries(steamID)
+	steamUser, err := s.GetPlayerSummaries(ctx, steamID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get player summaries for steamID %s: %w", steamID, err)
 	}
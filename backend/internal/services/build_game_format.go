@@ -0,0 +1,284 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/quenyu/deadlock-stats/internal/clients/deadlockapi"
+	"github.com/quenyu/deadlock-stats/internal/domain"
+	cErrors "github.com/quenyu/deadlock-stats/internal/errors"
+	"github.com/quenyu/deadlock-stats/internal/validators"
+)
+
+const maxBuildImportSize = 256 << 10
+
+// Currencies spent by the steps of a game build's ability order.
+const (
+	gameCurrencyAbilityPoints  = 1
+	gameCurrencyAbilityUnlocks = 2
+)
+
+// abilityUpgradeCosts are the ability points of an ability's three upgrades,
+// bought after unlocking it.
+var abilityUpgradeCosts = [maxAbilityUpgradesPerSlot - 1]int{1, 2, 5}
+
+// GameHeroBuild is a hero build as the game client exports and imports it.
+type GameHeroBuild struct {
+	HeroBuild GameHeroBuildDetails `json:"hero_build"`
+}
+
+type GameHeroBuildDetails struct {
+	HeroID      kvInt            `json:"hero_id"`
+	Language    kvInt            `json:"language"`
+	Version     kvInt            `json:"version"`
+	Name        string           `json:"name"`
+	Description string           `json:"description"`
+	Details     GameBuildContent `json:"details"`
+}
+
+type GameBuildContent struct {
+	ModCategories []GameModCategory `json:"mod_categories"`
+	AbilityOrder  GameAbilityOrder  `json:"ability_order"`
+}
+
+// GameModCategory is a named group of items ("mods" in game).
+type GameModCategory struct {
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	Mods        []GameMod `json:"mods"`
+}
+
+type GameMod struct {
+	AbilityID  kvInt  `json:"ability_id"`
+	Annotation string `json:"annotation,omitempty"`
+}
+
+type GameAbilityOrder struct {
+	CurrencyChanges []GameCurrencyChange `json:"currency_changes"`
+}
+
+// GameCurrencyChange spends ability unlocks or points on an ability; Delta
+// is negative.
+type GameCurrencyChange struct {
+	AbilityID    kvInt `json:"ability_id"`
+	CurrencyType kvInt `json:"currency_type"`
+	Delta        kvInt `json:"delta"`
+}
+
+type BuildExport struct {
+	Content     []byte
+	ContentType string
+	Filename    string
+}
+
+// kvInt reads numbers written as JSON numbers or, as KeyValues files write
+// them, as strings.
+type kvInt int64
+
+func (n *kvInt) UnmarshalJSON(data []byte) error {
+	value := strings.Trim(string(data), `"`)
+	if value == "" || value == "null" {
+		*n = 0
+		return nil
+	}
+	parsed, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid number %q", value)
+	}
+	*n = kvInt(parsed)
+	return nil
+}
+
+// ParseGameHeroBuild reads a game hero build from its JSON or KeyValues
+// export. The "hero_build" wrapper is optional.
+func ParseGameHeroBuild(data []byte) (*GameHeroBuild, error) {
+	if len(data) > maxBuildImportSize {
+		return nil, importError("input is larger than %d bytes", maxBuildImportSize)
+	}
+	data = bytes.TrimSpace(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")))
+	if len(data) == 0 {
+		return nil, importError("input is empty")
+	}
+
+	if data[0] != '{' {
+		values, err := parseKeyValues(string(data))
+		if err != nil {
+			return nil, importError("invalid KeyValues: %v", err)
+		}
+		if data, err = json.Marshal(values); err != nil {
+			return nil, err
+		}
+	}
+
+	var wrapper map[string]json.RawMessage
+	if err := json.Unmarshal(data, &wrapper); err != nil {
+		return nil, importError("invalid JSON: %v", err)
+	}
+	if heroBuild, ok := wrapper["hero_build"]; ok {
+		data = heroBuild
+	}
+
+	var build GameHeroBuild
+	if err := json.Unmarshal(data, &build.HeroBuild); err != nil {
+		return nil, importError("invalid hero build: %v", err)
+	}
+	if build.HeroBuild.HeroID <= 0 {
+		return nil, importError("hero_id is missing")
+	}
+	return &build, nil
+}
+
+// ImportGameBuild maps a hero build exported by the game onto a build
+// request. Nothing is saved; the caller checks the request like a created
+// build. Builds have no place for per-mod annotations or category
+// descriptions, and a phase needs items, so annotations, descriptions and
+// empty categories are dropped.
+func (s *BuildService) ImportGameBuild(data []byte) (*BuildRequest, error) {
+	game, err := ParseGameHeroBuild(data)
+	if err != nil {
+		return nil, err
+	}
+	if !s.staticData.GameDataLoaded() {
+		return nil, fmt.Errorf("%w: game data is not loaded yet", cErrors.ErrAPIUnavailable)
+	}
+
+	heroBuild := game.HeroBuild
+	req := &BuildRequest{
+		Title:       strings.TrimSpace(heroBuild.Name),
+		Description: strings.TrimSpace(heroBuild.Description),
+		HeroID:      int(heroBuild.HeroID),
+	}
+
+	for i, category := range heroBuild.Details.ModCategories {
+		if len(category.Mods) == 0 {
+			continue
+		}
+		phase := BuildPhase{Name: strings.TrimSpace(category.Name)}
+		if phase.Name == "" {
+			phase.Name = fmt.Sprintf("Phase %d", i+1)
+		}
+		for _, mod := range category.Mods {
+			phase.Items = append(phase.Items, int(mod.AbilityID))
+		}
+		req.Phases = append(req.Phases, phase)
+	}
+
+	errs := validators.NewFieldErrors(cErrors.ErrInvalidBuild)
+	abilities, ok := s.staticData.HeroAbilities(req.HeroID)
+	if !ok {
+		errs.Add("hero_id", "unknown_hero", strconv.Itoa(req.HeroID), "unknown hero")
+		return nil, errs
+	}
+	slots := make(map[int]int, HeroAbilitySlots)
+	for i, ability := range abilities {
+		if ability.ID != 0 {
+			slots[ability.ID] = i + 1
+		}
+	}
+	for i, change := range heroBuild.Details.AbilityOrder.CurrencyChanges {
+		if change.Delta >= 0 {
+			continue
+		}
+		slot, ok := slots[int(change.AbilityID)]
+		if !ok {
+			errs.Add(fmt.Sprintf("details.ability_order.currency_changes[%d]", i), "hero_ability",
+				strconv.FormatInt(int64(change.AbilityID), 10), "ability does not belong to the hero")
+			continue
+		}
+		req.AbilityOrder = append(req.AbilityOrder, slot)
+	}
+	if err := errs.Err(); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// ExportGameBuild renders a build as a hero build file the game imports.
+func (s *BuildService) ExportGameBuild(id, viewerID uuid.UUID) (*BuildExport, error) {
+	build, err := s.getVisible(id, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	if !s.staticData.GameDataLoaded() {
+		return nil, fmt.Errorf("%w: game data is not loaded yet", cErrors.ErrAPIUnavailable)
+	}
+	abilities, ok := s.staticData.HeroAbilities(build.HeroID)
+	if !ok {
+		return nil, fmt.Errorf("%w: hero %d is unknown", cErrors.ErrInvalidBuild, build.HeroID)
+	}
+
+	game, err := toGameHeroBuild(build, abilities)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.MarshalIndent(game, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return &BuildExport{
+		Content:     data,
+		ContentType: "application/json",
+		Filename:    buildFileName(build.Title) + "_build.json",
+	}, nil
+}
+
+// toGameHeroBuild converts a build to the game's format. The first step on
+// an ability unlocks it; the next ones spend ability points.
+func toGameHeroBuild(build *domain.Build, abilities [HeroAbilitySlots]deadlockapi.ItemV2) (*GameHeroBuild, error) {
+	game := &GameHeroBuild{HeroBuild: GameHeroBuildDetails{
+		HeroID:      kvInt(build.HeroID),
+		Version:     1,
+		Name:        build.Title,
+		Description: build.Description,
+		Details: GameBuildContent{
+			ModCategories: []GameModCategory{},
+			AbilityOrder:  GameAbilityOrder{CurrencyChanges: []GameCurrencyChange{}},
+		},
+	}}
+
+	for _, phase := range buildPhases(build.Items) {
+		category := GameModCategory{Name: phase.Name, Mods: make([]GameMod, len(phase.Items))}
+		for i, itemID := range phase.Items {
+			category.Mods[i] = GameMod{AbilityID: kvInt(itemID)}
+		}
+		game.HeroBuild.Details.ModCategories = append(game.HeroBuild.Details.ModCategories, category)
+	}
+
+	steps := make(map[int]int, HeroAbilitySlots)
+	for _, upgrade := range build.AbilityUpgrades {
+		ability := abilities[upgrade.AbilitySlot-1]
+		if ability.ID == 0 {
+			return nil, fmt.Errorf("%w: the hero has no ability in slot %d", cErrors.ErrInvalidBuild, upgrade.AbilitySlot)
+		}
+		change := GameCurrencyChange{
+			AbilityID:    kvInt(ability.ID),
+			CurrencyType: gameCurrencyAbilityUnlocks,
+			Delta:        -1,
+		}
+		step := steps[upgrade.AbilitySlot]
+		if step > len(abilityUpgradeCosts) {
+			return nil, fmt.Errorf("%w: ability %d is upgraded too often", cErrors.ErrInvalidBuild, upgrade.AbilitySlot)
+		}
+		if step > 0 {
+			change.CurrencyType = gameCurrencyAbilityPoints
+			change.Delta = kvInt(-abilityUpgradeCosts[step-1])
+		}
+		steps[upgrade.AbilitySlot]++
+		game.HeroBuild.Details.AbilityOrder.CurrencyChanges = append(game.HeroBuild.Details.AbilityOrder.CurrencyChanges, change)
+	}
+
+	return game, nil
+}
+
+func buildFileName(title string) string {
+	name := strings.ToLower(crosshairFileNamePattern.ReplaceAllString(title, "_"))
+	if name == "" {
+		return "build"
+	}
+	return name
+}
@@ -0,0 +1,121 @@
+package services
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/quenyu/deadlock-stats/internal/domain"
+	"go.uber.org/zap"
+)
+
+const heroBuildFixtures = "testdata/hero_builds"
+
+func newFixtureBuildService(t *testing.T) *BuildService {
+	t.Helper()
+	staticData := NewStaticDataService(zap.NewNop())
+	if err := staticData.parseAndStoreHeroes(readFixture(t, "heroes.json")); err != nil {
+		t.Fatalf("loading heroes: %v", err)
+	}
+	if err := staticData.parseAndStoreItems(readFixture(t, "items.json")); err != nil {
+		t.Fatalf("loading items: %v", err)
+	}
+	return NewBuildService(nil, staticData)
+}
+
+func readFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(heroBuildFixtures, name))
+	if err != nil {
+		t.Fatalf("reading fixture: %v", err)
+	}
+	return data
+}
+
+func TestImportGameBuildRoundTrip(t *testing.T) {
+	s := newFixtureBuildService(t)
+
+	fromJSON, err := s.ImportGameBuild(readFixture(t, "haze_build.json"))
+	if err != nil {
+		t.Fatalf("importing JSON: %v", err)
+	}
+	fromKV, err := s.ImportGameBuild(readFixture(t, "haze_build.vdf"))
+	if err != nil {
+		t.Fatalf("importing KeyValues: %v", err)
+	}
+	if !reflect.DeepEqual(fromJSON, fromKV) {
+		t.Fatalf("JSON and KeyValues imports differ:\n%+v\n%+v", fromJSON, fromKV)
+	}
+	if len(fromJSON.Phases) == 0 || len(fromJSON.AbilityOrder) == 0 {
+		t.Fatalf("import lost the build contents: %+v", fromJSON)
+	}
+	for _, phase := range fromJSON.Phases {
+		if phase.Name == "Situational" {
+			t.Errorf("empty category imported as a phase: %+v", phase)
+		}
+	}
+	if err := s.ValidateGameData(fromJSON); err != nil {
+		t.Fatalf("imported build is invalid: %v", err)
+	}
+
+	for name, req := range map[string]*BuildRequest{"json": fromJSON, "vdf": fromKV} {
+		t.Run(name, func(t *testing.T) {
+			build := &domain.Build{}
+			applyBuildRequest(build, req)
+			abilities, ok := s.staticData.HeroAbilities(req.HeroID)
+			if !ok {
+				t.Fatalf("hero %d is not in the fixtures", req.HeroID)
+			}
+
+			game, err := toGameHeroBuild(build, abilities)
+			if err != nil {
+				t.Fatalf("exporting: %v", err)
+			}
+			exported, err := json.Marshal(game)
+			if err != nil {
+				t.Fatalf("encoding export: %v", err)
+			}
+
+			reimported, err := s.ImportGameBuild(exported)
+			if err != nil {
+				t.Fatalf("re-importing: %v", err)
+			}
+			if !reflect.DeepEqual(req.Phases, reimported.Phases) {
+				t.Errorf("phases changed:\n%+v\n%+v", req.Phases, reimported.Phases)
+			}
+			if !reflect.DeepEqual(req.AbilityOrder, reimported.AbilityOrder) {
+				t.Errorf("ability order changed: %v, %v", req.AbilityOrder, reimported.AbilityOrder)
+			}
+			if !reflect.DeepEqual(req, reimported) {
+				t.Errorf("build request changed:\n%+v\n%+v", req, reimported)
+			}
+		})
+	}
+}
+
+func TestParseKeyValuesMalformed(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"unterminated string", `"hero_build" { "name" "Haze`, "unterminated string"},
+		{"stray closing brace", `"hero_build" { "name" "Haze" } }`, "unexpected closing brace"},
+		{"missing closing brace", `"hero_build" { "name" "Haze"`, "missing closing brace"},
+		{"too deep", strings.Repeat(`"a" { `, maxKeyValuesDepth+2) + strings.Repeat("} ", maxKeyValuesDepth+2), "nested too deeply"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseKeyValues(tt.input)
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error %q does not mention %q", err, tt.want)
+			}
+		})
+	}
+}
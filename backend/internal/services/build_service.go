@@ -0,0 +1,252 @@
+package services
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/quenyu/deadlock-stats/internal/domain"
+	cErrors "github.com/quenyu/deadlock-stats/internal/errors"
+	"github.com/quenyu/deadlock-stats/internal/repositories"
+)
+
+const (
+	MaxBuildPhases          = 8
+	MaxBuildPhaseItems      = 20
+	MaxBuildAbilityUpgrades = 16
+)
+
+type BuildService struct {
+	repo       *repositories.BuildRepository
+	staticData *StaticDataService
+}
+
+func NewBuildService(repo *repositories.BuildRepository, staticData *StaticDataService) *BuildService {
+	return &BuildService{repo: repo, staticData: staticData}
+}
+
+// BuildPhase is a named group of items, bought in order.
+type BuildPhase struct {
+	Name  string `json:"name"`
+	Items []int  `json:"items"`
+}
+
+type BuildRequest struct {
+	Title        string       `json:"title"`
+	Description  string       `json:"description"`
+	HeroID       int          `json:"hero_id"`
+	GameVersion  string       `json:"game_version"`
+	IsPublic     bool         `json:"is_public"`
+	Phases       []BuildPhase `json:"phases"`
+	AbilityOrder []int        `json:"ability_order"`
+}
+
+type BuildResponse struct {
+	ID            uuid.UUID           `json:"id"`
+	AuthorID      uuid.UUID           `json:"author_id"`
+	AuthorName    string              `json:"author_name"`
+	AuthorAvatar  string              `json:"author_avatar"`
+	HeroID        int                 `json:"hero_id"`
+	Title         string              `json:"title"`
+	Description   string              `json:"description"`
+	GameVersion   string              `json:"game_version"`
+	IsPublic      bool                `json:"is_public"`
+	ViewCount     int                 `json:"view_count"`
+	CommentsCount int                 `json:"comments_count"`
+	Phases        []BuildPhase        `json:"phases,omitempty"`
+	AbilityOrder  []int               `json:"ability_order,omitempty"`
+	Stats         *BuildStatsResponse `json:"stats,omitempty"`
+	CreatedAt     time.Time           `json:"created_at"`
+	UpdatedAt     time.Time           `json:"updated_at"`
+}
+
+type BuildListQuery struct {
+	AuthorID uuid.UUID
+	HeroID   int
+	Sort     string
+	Page     int
+	Limit    int
+}
+
+type BuildListResponse struct {
+	Builds []BuildResponse `json:"builds"`
+	Total  int64           `json:"total"`
+	Page   int             `json:"page"`
+	Limit  int             `json:"limit"`
+}
+
+func (s *BuildService) Create(authorID uuid.UUID, req *BuildRequest) (*BuildResponse, error) {
+	if err := s.ValidateGameData(req); err != nil {
+		return nil, err
+	}
+	build := &domain.Build{AuthorID: authorID}
+	applyBuildRequest(build, req)
+	if err := s.repo.Create(build); err != nil {
+		return nil, err
+	}
+	return toBuildResponse(build), nil
+}
+
+// GetByID returns a build. viewerID is uuid.Nil for anonymous viewers;
+// private builds are only found by their author.
+func (s *BuildService) GetByID(id, viewerID uuid.UUID) (*BuildResponse, error) {
+	build, err := s.getVisible(id, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	return toBuildResponse(build), nil
+}
+
+// List returns one page of public builds, or of an author's builds when
+// query.AuthorID is set, including private ones for the author.
+func (s *BuildService) List(query BuildListQuery, viewerID uuid.UUID) (*BuildListResponse, error) {
+	if query.Sort == "" {
+		query.Sort = "new"
+	}
+	builds, total, err := s.repo.List(domain.BuildFilter{
+		AuthorID:       query.AuthorID,
+		HeroID:         query.HeroID,
+		IncludePrivate: query.AuthorID != uuid.Nil && query.AuthorID == viewerID,
+		Sort:           query.Sort,
+		MinSample:      MinBuildStatsSample,
+		Page:           query.Page,
+		Limit:          query.Limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &BuildListResponse{
+		Builds: make([]BuildResponse, len(builds)),
+		Total:  total,
+		Page:   query.Page,
+		Limit:  query.Limit,
+	}
+	for i := range builds {
+		resp.Builds[i] = *toBuildResponse(&builds[i])
+	}
+	return resp, nil
+}
+
+func (s *BuildService) Update(id, authorID uuid.UUID, req *BuildRequest) (*BuildResponse, error) {
+	build, err := s.getOwned(id, authorID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.ValidateGameData(req); err != nil {
+		return nil, err
+	}
+	applyBuildRequest(build, req)
+	if err := s.repo.Update(build); err != nil {
+		return nil, err
+	}
+	return toBuildResponse(build), nil
+}
+
+func (s *BuildService) Delete(id, authorID uuid.UUID) error {
+	if _, err := s.getOwned(id, authorID); err != nil {
+		return err
+	}
+	return s.repo.Delete(id)
+}
+
+func (s *BuildService) getVisible(id, viewerID uuid.UUID) (*domain.Build, error) {
+	build, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, cErrors.ErrBuildNotFound
+	}
+	if !build.IsPublic && build.AuthorID != viewerID {
+		return nil, cErrors.ErrBuildNotFound
+	}
+	return build, nil
+}
+
+func (s *BuildService) getOwned(id, authorID uuid.UUID) (*domain.Build, error) {
+	build, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, cErrors.ErrBuildNotFound
+	}
+	if build.AuthorID != authorID {
+		return nil, cErrors.ErrBuildForbidden
+	}
+	return build, nil
+}
+
+func applyBuildRequest(build *domain.Build, req *BuildRequest) {
+	build.Title = req.Title
+	build.Description = req.Description
+	build.HeroID = req.HeroID
+	build.GameVersion = req.GameVersion
+	build.IsPublic = req.IsPublic
+
+	build.Items = build.Items[:0]
+	for phasePosition, phase := range req.Phases {
+		for position, itemID := range phase.Items {
+			build.Items = append(build.Items, domain.BuildItem{
+				PhasePosition: phasePosition,
+				Phase:         phase.Name,
+				Position:      position,
+				ItemID:        itemID,
+			})
+		}
+	}
+
+	build.AbilityUpgrades = build.AbilityUpgrades[:0]
+	for position, slot := range req.AbilityOrder {
+		build.AbilityUpgrades = append(build.AbilityUpgrades, domain.BuildAbilityUpgrade{
+			Position:    position,
+			AbilitySlot: slot,
+		})
+	}
+}
+
+// buildPhases regroups a build's items, which are ordered by phase and
+// position, into phases.
+func buildPhases(items []domain.BuildItem) []BuildPhase {
+	var phases []BuildPhase
+	for i, item := range items {
+		if i == 0 || item.PhasePosition != items[i-1].PhasePosition {
+			phases = append(phases, BuildPhase{Name: item.Phase})
+		}
+		last := &phases[len(phases)-1]
+		last.Items = append(last.Items, item.ItemID)
+	}
+	return phases
+}
+
+func toBuildResponse(b *domain.Build) *BuildResponse {
+	authorName := ""
+	authorAvatar := ""
+	if b.Author != nil {
+		authorName = b.Author.Nickname
+		authorAvatar = b.Author.AvatarURL
+	}
+
+	var abilityOrder []int
+	for _, upgrade := range b.AbilityUpgrades {
+		abilityOrder = append(abilityOrder, upgrade.AbilitySlot)
+	}
+
+	var stats *BuildStatsResponse
+	if b.Stats != nil {
+		stats = toBuildStatsResponse(b.Stats)
+	}
+
+	return &BuildResponse{
+		ID:            b.ID,
+		AuthorID:      b.AuthorID,
+		AuthorName:    authorName,
+		AuthorAvatar:  authorAvatar,
+		HeroID:        b.HeroID,
+		Title:         b.Title,
+		Description:   b.Description,
+		GameVersion:   b.GameVersion,
+		IsPublic:      b.IsPublic,
+		ViewCount:     b.ViewCount,
+		CommentsCount: b.CommentsCount,
+		Phases:        buildPhases(b.Items),
+		AbilityOrder:  abilityOrder,
+		Stats:         stats,
+		CreatedAt:     b.CreatedAt,
+		UpdatedAt:     b.UpdatedAt,
+	}
+}
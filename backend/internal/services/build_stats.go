@@ -0,0 +1,198 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/quenyu/deadlock-stats/internal/clients/deadlockapi"
+	"github.com/quenyu/deadlock-stats/internal/domain"
+	"github.com/quenyu/deadlock-stats/internal/repositories"
+	"go.uber.org/zap"
+)
+
+const (
+	buildStatsInterval   = 30 * time.Minute
+	buildStatsWindow     = 30 * 24 * time.Hour
+	matchDetailsPerRun   = 100
+	matchDetailsFetchGap = 200 * time.Millisecond
+
+	// maxMatchDetailsAttempts is how many times a match is tried before it
+	// is skipped for good.
+	maxMatchDetailsAttempts = 5
+
+	// A match follows a build when the player bought at least this share
+	// of the build's items.
+	buildFollowAdherence = 0.6
+
+	// MinBuildStatsSample is how many matches a build needs before its win
+	// rate and KDA rank it in the builds list.
+	MinBuildStatsSample = 20
+)
+
+// BuildSorts are the orders of the builds list.
+var BuildSorts = []string{"new", "win_rate", "matches", "kda"}
+
+type BuildStatsResponse struct {
+	BuildID      uuid.UUID  `json:"build_id"`
+	SampleSize   int        `json:"sample_size"`
+	Wins         int        `json:"wins"`
+	WinRate      float64    `json:"win_rate"`
+	AvgKDA       float64    `json:"avg_kda"`
+	AvgAdherence float64    `json:"avg_adherence"`
+	Reliable     bool       `json:"reliable"`
+	UpdatedAt    *time.Time `json:"updated_at,omitempty"`
+}
+
+// GetStats returns how the recent matches that followed a build went.
+// Private builds, and builds no match followed, have zero stats.
+func (s *BuildService) GetStats(id, viewerID uuid.UUID) (*BuildStatsResponse, error) {
+	build, err := s.getVisible(id, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	if build.Stats == nil {
+		return &BuildStatsResponse{BuildID: build.ID}, nil
+	}
+	return toBuildStatsResponse(build.Stats), nil
+}
+
+func toBuildStatsResponse(stats *domain.BuildStats) *BuildStatsResponse {
+	updatedAt := stats.UpdatedAt
+	return &BuildStatsResponse{
+		BuildID:      stats.BuildID,
+		SampleSize:   stats.Matches,
+		Wins:         stats.Wins,
+		WinRate:      stats.WinRate,
+		AvgKDA:       stats.AvgKDA,
+		AvgAdherence: stats.AvgAdherence,
+		Reliable:     stats.Matches >= MinBuildStatsSample,
+		UpdatedAt:    &updatedAt,
+	}
+}
+
+// BuildStatsJob ingests the details of tracked players' matches, with the
+// items every player bought, and recomputes build stats from them.
+type BuildStatsJob struct {
+	builds  *repositories.BuildRepository
+	matches *repositories.MatchDetailsRepository
+	api     *deadlockapi.Client
+	logger  *zap.Logger
+}
+
+func NewBuildStatsJob(builds *repositories.BuildRepository, matches *repositories.MatchDetailsRepository, api *deadlockapi.Client, logger *zap.Logger) *BuildStatsJob {
+	return &BuildStatsJob{
+		builds:  builds,
+		matches: matches,
+		api:     api,
+		logger:  logger.Named("BuildStatsJob"),
+	}
+}
+
+// Start ingests matches and refreshes build stats until ctx is cancelled.
+func (j *BuildStatsJob) Start(ctx context.Context) {
+	ticker := time.NewTicker(buildStatsInterval)
+	defer ticker.Stop()
+
+	for {
+		j.ingestMatches(ctx)
+		if err := j.builds.RefreshStats(time.Now().Add(-buildStatsWindow), buildFollowAdherence); err != nil {
+			j.logger.Error("Failed to refresh build stats", zap.Error(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ingestMatches fetches the details of pending matches. A match that fails
+// is retried on later runs until maxMatchDetailsAttempts; the run stops when
+// the API is rate limited or unreachable.
+func (j *BuildStatsJob) ingestMatches(ctx context.Context) {
+	matchIDs, err := j.matches.PendingMatchIDs(matchDetailsPerRun, maxMatchDetailsAttempts)
+	if err != nil {
+		j.logger.Error("Failed to list pending matches", zap.Error(err))
+		return
+	}
+
+	ingested := 0
+	for _, matchID := range matchIDs {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(matchDetailsFetchGap):
+		}
+
+		match, err := j.api.FetchMatchMetadata(matchID)
+		if errors.Is(err, deadlockapi.ErrNotFound) {
+			if err := j.matches.SaveMissing(matchID); err != nil {
+				j.logger.Error("Failed to store missing match", zap.Int64("matchID", matchID), zap.Error(err))
+			}
+			continue
+		}
+		if errors.Is(err, deadlockapi.ErrRateLimited) || errors.Is(err, deadlockapi.ErrUnavailable) {
+			j.logger.Warn("Deadlock API unavailable, stopping match ingestion", zap.Int64("matchID", matchID), zap.Error(err))
+			break
+		}
+		if err != nil {
+			j.recordFailure(matchID, "Failed to fetch match details", err)
+			continue
+		}
+
+		if err := j.matches.Save(toMatchDetails(matchID, match)); err != nil {
+			j.recordFailure(matchID, "Failed to store match details", err)
+			continue
+		}
+		ingested++
+	}
+
+	if ingested > 0 {
+		j.logger.Info("Ingested match details", zap.Int("matches", ingested))
+	}
+}
+
+func (j *BuildStatsJob) recordFailure(matchID int64, msg string, err error) {
+	attempts, recordErr := j.matches.RecordFailure(matchID, err.Error())
+	if recordErr != nil {
+		j.logger.Error("Failed to record match failure", zap.Int64("matchID", matchID), zap.Error(recordErr))
+	}
+	if attempts >= maxMatchDetailsAttempts {
+		j.logger.Error(msg+", skipping the match", zap.Int64("matchID", matchID), zap.Int("attempts", attempts), zap.Error(err))
+		return
+	}
+	j.logger.Warn(msg, zap.Int64("matchID", matchID), zap.Int("attempts", attempts), zap.Error(err))
+}
+
+func toMatchDetails(matchID int64, match *deadlockapi.MatchInfo) domain.MatchDetails {
+	details := domain.MatchDetails{
+		MatchID:   matchID,
+		StartTime: time.Unix(match.StartTime, 0).UTC(),
+		DurationS: match.DurationS,
+	}
+	for _, player := range match.Players {
+		if player.AccountID <= 0 {
+			continue
+		}
+		p := domain.MatchDetailsPlayer{
+			AccountID: int64(player.AccountID),
+			HeroID:    player.HeroID,
+			Team:      player.Team,
+			Won:       player.Team == match.WinningTeam,
+			Kills:     player.Kills,
+			Deaths:    player.Deaths,
+			Assists:   player.Assists,
+		}
+		for _, item := range player.Items {
+			if item.ItemID <= 0 {
+				continue
+			}
+			p.Items = append(p.Items, domain.MatchItemPurchase{ItemID: item.ItemID, GameTimeS: item.GameTimeS})
+		}
+		details.Players = append(details.Players, p)
+	}
+	return details
+}
@@ -0,0 +1,95 @@
+package services
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	cErrors "github.com/quenyu/deadlock-stats/internal/errors"
+	"github.com/quenyu/deadlock-stats/internal/validators"
+)
+
+// An ability is unlocked once and then upgraded up to three times.
+const maxAbilityUpgradesPerSlot = 4
+
+// buildPhaseSoulBudgets caps the souls that can have been spent by the end
+// of the well-known phases; items of a phase count with those of every
+// earlier phase. Other phase names only count against maxBuildSouls.
+var buildPhaseSoulBudgets = map[string]int{
+	"early": 12000,
+	"mid":   35000,
+	"late":  maxBuildSouls,
+}
+
+// maxBuildSouls is more souls than a player holds in a long game; a build
+// costing more can never be completed.
+const maxBuildSouls = 90000
+
+// ValidateGameData checks a build request against the heroes, items and
+// abilities of the game and reports every failing field.
+func (s *BuildService) ValidateGameData(req *BuildRequest) error {
+	if !s.staticData.GameDataLoaded() {
+		return fmt.Errorf("%w: game data is not loaded yet", cErrors.ErrAPIUnavailable)
+	}
+	errs := validators.NewFieldErrors(cErrors.ErrInvalidBuild)
+
+	abilities, ok := s.staticData.HeroAbilities(req.HeroID)
+	if !ok {
+		errs.Add("hero_id", "unknown_hero", strconv.Itoa(req.HeroID), "unknown hero")
+	}
+
+	seen := make(map[int]string)
+	spent := 0
+	for i, phase := range req.Phases {
+		for j, itemID := range phase.Items {
+			field := fmt.Sprintf("phases[%d].items[%d]", i, j)
+			value := strconv.Itoa(itemID)
+
+			item, ok := s.staticData.GetItem(itemID)
+			if !ok {
+				errs.Add(field, "unknown_item", value, "unknown item")
+				continue
+			}
+			if item.Type != "upgrade" || !item.Shopable || item.Disabled {
+				errs.Add(field, "not_purchasable", value, "item cannot be bought in the shop")
+				continue
+			}
+			if first, ok := seen[itemID]; ok {
+				errs.Add(field, "duplicate", value, "item is already bought at "+first)
+				continue
+			}
+			seen[itemID] = field
+			spent += item.Cost
+		}
+
+		budget, ok := buildPhaseSoulBudgets[strings.ToLower(phase.Name)]
+		if !ok {
+			budget = maxBuildSouls
+		}
+		if spent > budget {
+			errs.Add(fmt.Sprintf("phases[%d]", i), "soul_budget", strconv.Itoa(spent),
+				fmt.Sprintf("items up to this phase cost %d souls, more than the %d available by then", spent, budget))
+		}
+	}
+
+	upgrades := make(map[int]int, HeroAbilitySlots)
+	for i, slot := range req.AbilityOrder {
+		field := fmt.Sprintf("ability_order[%d]", i)
+		value := strconv.Itoa(slot)
+		if slot < 1 || slot > HeroAbilitySlots {
+			errs.Add(field, "ability_slot", value, "unknown ability slot")
+			continue
+		}
+		if ok && abilities[slot-1].ID == 0 {
+			errs.Add(field, "hero_ability", value, "the hero has no ability in this slot")
+			continue
+		}
+		upgrades[slot]++
+		if upgrades[slot] > maxAbilityUpgradesPerSlot {
+			errs.Add(field, "slot_order", value,
+				fmt.Sprintf("ability %d is already unlocked and upgraded %d times", slot, maxAbilityUpgradesPerSlot-1))
+		}
+	}
+
+	return errs.Err()
+}
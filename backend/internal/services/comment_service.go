@@ -0,0 +1,286 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/quenyu/deadlock-stats/internal/domain"
+	cErrors "github.com/quenyu/deadlock-stats/internal/errors"
+	"github.com/quenyu/deadlock-stats/internal/repositories"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// MaxCommentDepth is the deepest reply level; top-level comments have
+// depth 0.
+const MaxCommentDepth = 5
+
+const commentRatePrefix = "comment-rate:"
+
+type commentRateLimit struct {
+	name   string
+	window time.Duration
+	limit  int64
+}
+
+// commentRateLimits cap how many comments a user writes, edits included.
+var commentRateLimits = []commentRateLimit{
+	{name: "minute", window: time.Minute, limit: 5},
+	{name: "hour", window: time.Hour, limit: 60},
+}
+
+// countCommentWrite counts a write in a fixed window that starts with the
+// window's first write.
+var countCommentWrite = redis.NewScript(`
+local count = redis.call('INCR', KEYS[1])
+if count == 1 then
+	redis.call('EXPIRE', KEYS[1], ARGV[1])
+end
+return count
+`)
+
+type CommentService struct {
+	repo        *repositories.CommentRepository
+	crosshairs  *CrosshairService
+	builds      *BuildService
+	redisClient *redis.Client
+	logger      *zap.Logger
+}
+
+func NewCommentService(repo *repositories.CommentRepository, crosshairs *CrosshairService, builds *BuildService, redisClient *redis.Client, logger *zap.Logger) *CommentService {
+	return &CommentService{
+		repo:        repo,
+		crosshairs:  crosshairs,
+		builds:      builds,
+		redisClient: redisClient,
+		logger:      logger.Named("CommentService"),
+	}
+}
+
+type CommentRequest struct {
+	Body     string     `json:"body"`
+	ParentID *uuid.UUID `json:"parent_id"`
+}
+
+type UpdateCommentRequest struct {
+	Body string `json:"body"`
+}
+
+// CommentResponse is a comment of a thread. Deleted comments have no body
+// and no author.
+type CommentResponse struct {
+	ID           uuid.UUID  `json:"id"`
+	ParentID     *uuid.UUID `json:"parent_id,omitempty"`
+	AuthorID     *uuid.UUID `json:"author_id,omitempty"`
+	AuthorName   string     `json:"author_name,omitempty"`
+	AuthorAvatar string     `json:"author_avatar,omitempty"`
+	Depth        int        `json:"depth"`
+	Body         string     `json:"body"`
+	Edited       bool       `json:"edited"`
+	Deleted      bool       `json:"deleted"`
+	RepliesCount int        `json:"replies_count"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    *time.Time `json:"updated_at,omitempty"`
+}
+
+// CommentListQuery selects one page of top-level comments, or of the
+// replies to ParentID.
+type CommentListQuery struct {
+	ParentID *uuid.UUID
+	Page     int
+	Limit    int
+}
+
+type CommentListResponse struct {
+	Comments []CommentResponse `json:"comments"`
+	Total    int64             `json:"total"`
+	Page     int               `json:"page"`
+	Limit    int               `json:"limit"`
+}
+
+// List returns one page of a thread level of a crosshair or build the
+// viewer can see. viewerID is uuid.Nil for anonymous viewers.
+func (s *CommentService) List(contentType string, contentID uuid.UUID, query CommentListQuery, viewerID uuid.UUID) (*CommentListResponse, error) {
+	if err := s.checkContent(contentType, contentID, viewerID); err != nil {
+		return nil, err
+	}
+	if query.ParentID != nil {
+		if _, err := s.getInContent(*query.ParentID, contentType, contentID); err != nil {
+			return nil, err
+		}
+	}
+
+	comments, total, err := s.repo.List(domain.CommentFilter{
+		ContentType: contentType,
+		ContentID:   contentID,
+		ParentID:    query.ParentID,
+		Page:        query.Page,
+		Limit:       query.Limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &CommentListResponse{
+		Comments: make([]CommentResponse, len(comments)),
+		Total:    total,
+		Page:     query.Page,
+		Limit:    query.Limit,
+	}
+	for i := range comments {
+		resp.Comments[i] = *toCommentResponse(&comments[i])
+	}
+	return resp, nil
+}
+
+// Create comments on a crosshair or build the author can see, or replies
+// to one of its comments that is not deleted.
+func (s *CommentService) Create(contentType string, contentID, authorID uuid.UUID, req *CommentRequest) (*CommentResponse, error) {
+	if err := s.checkContent(contentType, contentID, authorID); err != nil {
+		return nil, err
+	}
+
+	comment := &domain.Comment{
+		AuthorID:    authorID,
+		ContentType: contentType,
+		ContentID:   contentID,
+		Body:        req.Body,
+	}
+	if req.ParentID != nil {
+		parent, err := s.getInContent(*req.ParentID, contentType, contentID)
+		if err != nil {
+			return nil, err
+		}
+		if parent.DeletedAt != nil {
+			return nil, fmt.Errorf("%w: cannot reply to a deleted comment", cErrors.ErrInvalidComment)
+		}
+		if parent.Depth >= MaxCommentDepth {
+			return nil, fmt.Errorf("%w: replies are nested at most %d levels deep", cErrors.ErrInvalidComment, MaxCommentDepth)
+		}
+		comment.ParentID = &parent.ID
+		comment.Depth = parent.Depth + 1
+	}
+
+	if err := s.checkRateLimit(authorID); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Create(comment); err != nil {
+		return nil, err
+	}
+
+	created, err := s.repo.GetByID(comment.ID)
+	if err != nil {
+		return nil, err
+	}
+	return toCommentResponse(created), nil
+}
+
+func (s *CommentService) Update(id, authorID uuid.UUID, req *UpdateCommentRequest) (*CommentResponse, error) {
+	comment, err := s.getOwned(id, authorID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkRateLimit(authorID); err != nil {
+		return nil, err
+	}
+	comment.Body = req.Body
+	if err := s.repo.UpdateBody(comment); err != nil {
+		return nil, cErrors.ErrCommentNotFound
+	}
+	return toCommentResponse(comment), nil
+}
+
+// Delete soft deletes a comment; its replies stay in the thread.
+func (s *CommentService) Delete(id, authorID uuid.UUID) error {
+	comment, err := s.getOwned(id, authorID)
+	if err != nil {
+		return err
+	}
+	return s.repo.SoftDelete(comment)
+}
+
+func (s *CommentService) checkContent(contentType string, contentID, viewerID uuid.UUID) error {
+	var err error
+	switch contentType {
+	case domain.CommentContentCrosshair:
+		_, err = s.crosshairs.getVisible(contentID, viewerID)
+	case domain.CommentContentBuild:
+		_, err = s.builds.getVisible(contentID, viewerID)
+	default:
+		err = fmt.Errorf("unknown comment content type %q", contentType)
+	}
+	return err
+}
+
+func (s *CommentService) getInContent(id uuid.UUID, contentType string, contentID uuid.UUID) (*domain.Comment, error) {
+	comment, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, cErrors.ErrCommentNotFound
+	}
+	if comment.ContentType != contentType || comment.ContentID != contentID {
+		return nil, cErrors.ErrCommentNotFound
+	}
+	return comment, nil
+}
+
+// getOwned returns a comment of the author that is not deleted, on content
+// the author can still see.
+func (s *CommentService) getOwned(id, authorID uuid.UUID) (*domain.Comment, error) {
+	comment, err := s.repo.GetByID(id)
+	if err != nil || comment.DeletedAt != nil {
+		return nil, cErrors.ErrCommentNotFound
+	}
+	if comment.AuthorID != authorID {
+		return nil, cErrors.ErrCommentForbidden
+	}
+	if err := s.checkContent(comment.ContentType, comment.ContentID, authorID); err != nil {
+		return nil, err
+	}
+	return comment, nil
+}
+
+// checkRateLimit counts a comment write by the user and fails once a limit
+// is exceeded. Comments are allowed when Redis is unavailable.
+func (s *CommentService) checkRateLimit(userID uuid.UUID) error {
+	ctx := context.Background()
+	for _, rateLimit := range commentRateLimits {
+		window := int64(rateLimit.window.Seconds())
+		key := commentRatePrefix + userID.String() + ":" + rateLimit.name
+		count, err := countCommentWrite.Run(ctx, s.redisClient, []string{key}, window).Int64()
+		if err != nil {
+			s.logger.Warn("Failed to check comment rate limit", zap.String("userID", userID.String()), zap.Error(err))
+			return nil
+		}
+		if count > rateLimit.limit {
+			return fmt.Errorf("%w: at most %d comments per %s", cErrors.ErrRateLimited, rateLimit.limit, rateLimit.name)
+		}
+	}
+	return nil
+}
+
+func toCommentResponse(c *domain.Comment) *CommentResponse {
+	resp := &CommentResponse{
+		ID:           c.ID,
+		ParentID:     c.ParentID,
+		Depth:        c.Depth,
+		Edited:       c.UpdatedAt != nil,
+		Deleted:      c.DeletedAt != nil,
+		RepliesCount: c.RepliesCount,
+		CreatedAt:    c.CreatedAt,
+		UpdatedAt:    c.UpdatedAt,
+	}
+	if resp.Deleted {
+		return resp
+	}
+
+	authorID := c.AuthorID
+	resp.AuthorID = &authorID
+	resp.Body = c.Body
+	if c.Author != nil {
+		resp.AuthorName = c.Author.Nickname
+		resp.AuthorAvatar = c.Author.AvatarURL
+	}
+	return resp
+}
@@ -0,0 +1,246 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/quenyu/deadlock-stats/internal/domain"
+	cErrors "github.com/quenyu/deadlock-stats/internal/errors"
+	"github.com/quenyu/deadlock-stats/internal/repositories"
+)
+
+const (
+	maxCollectionsPerUser = 50
+	maxCollectionItems    = 100
+)
+
+type CrosshairCollectionService struct {
+	repo       *repositories.CrosshairCollectionRepository
+	crosshairs *CrosshairService
+}
+
+func NewCrosshairCollectionService(repo *repositories.CrosshairCollectionRepository, crosshairs *CrosshairService) *CrosshairCollectionService {
+	return &CrosshairCollectionService{repo: repo, crosshairs: crosshairs}
+}
+
+type CrosshairCollectionRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	IsPublic    bool   `json:"is_public"`
+}
+
+type CrosshairCollectionResponse struct {
+	ID          uuid.UUID           `json:"id"`
+	OwnerID     uuid.UUID           `json:"owner_id"`
+	OwnerName   string              `json:"owner_name"`
+	OwnerAvatar string              `json:"owner_avatar"`
+	Name        string              `json:"name"`
+	Description string              `json:"description"`
+	IsPublic    bool                `json:"is_public"`
+	ItemsCount  int                 `json:"items_count"`
+	Crosshairs  []CrosshairResponse `json:"crosshairs,omitempty"`
+	CreatedAt   time.Time           `json:"created_at"`
+	UpdatedAt   time.Time           `json:"updated_at"`
+}
+
+func (s *CrosshairCollectionService) Create(ownerID uuid.UUID, req *CrosshairCollectionRequest) (*CrosshairCollectionResponse, error) {
+	count, err := s.repo.CountByOwner(ownerID)
+	if err != nil {
+		return nil, err
+	}
+	if count >= maxCollectionsPerUser {
+		return nil, fmt.Errorf("%w: at most %d collections", cErrors.ErrCollectionLimit, maxCollectionsPerUser)
+	}
+	if err := s.checkName(ownerID, req.Name, uuid.Nil); err != nil {
+		return nil, err
+	}
+
+	collection := &domain.CrosshairCollection{
+		OwnerID:     ownerID,
+		Name:        req.Name,
+		Description: req.Description,
+		IsPublic:    req.IsPublic,
+	}
+	if err := s.repo.Create(collection); err != nil {
+		return nil, err
+	}
+	resp := toCollectionResponse(collection)
+	resp.Crosshairs = []CrosshairResponse{}
+	return resp, nil
+}
+
+// Get returns a collection with the crosshairs the viewer may see, in
+// order. Private collections are only found by their owner.
+func (s *CrosshairCollectionService) Get(id, viewerID uuid.UUID) (*CrosshairCollectionResponse, error) {
+	collection, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, cErrors.ErrCollectionNotFound
+	}
+	if !collection.IsPublic && collection.OwnerID != viewerID {
+		return nil, cErrors.ErrCollectionNotFound
+	}
+
+	items, err := s.repo.GetItems(id)
+	if err != nil {
+		return nil, err
+	}
+	crosshairs := make([]domain.Crosshair, 0, len(items))
+	for _, item := range items {
+		if item.Crosshair == nil {
+			continue
+		}
+		if item.Crosshair.IsPublic || item.Crosshair.AuthorID == viewerID {
+			crosshairs = append(crosshairs, *item.Crosshair)
+		}
+	}
+
+	resp := toCollectionResponse(collection)
+	resp.ItemsCount = len(crosshairs)
+	if resp.Crosshairs, err = s.crosshairs.toCrosshairResponses(crosshairs, viewerID); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// ListByOwner lists a user's collections, private ones only for the owner.
+func (s *CrosshairCollectionService) ListByOwner(ownerID, viewerID uuid.UUID) ([]CrosshairCollectionResponse, error) {
+	collections, err := s.repo.ListByOwner(ownerID, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	resp := make([]CrosshairCollectionResponse, len(collections))
+	for i, c := range collections {
+		resp[i] = *toCollectionResponse(&c)
+		resp[i].ItemsCount = c.ItemsCount
+	}
+	return resp, nil
+}
+
+func (s *CrosshairCollectionService) Update(id, ownerID uuid.UUID, req *CrosshairCollectionRequest) (*CrosshairCollectionResponse, error) {
+	collection, err := s.getOwned(id, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkName(ownerID, req.Name, id); err != nil {
+		return nil, err
+	}
+
+	collection.Name = req.Name
+	collection.Description = req.Description
+	collection.IsPublic = req.IsPublic
+	if err := s.repo.Update(collection); err != nil {
+		return nil, err
+	}
+	return s.Get(id, ownerID)
+}
+
+func (s *CrosshairCollectionService) Delete(id, ownerID uuid.UUID) error {
+	if _, err := s.getOwned(id, ownerID); err != nil {
+		return err
+	}
+	return s.repo.Delete(id)
+}
+
+// AddCrosshair appends a crosshair the owner can see to the collection.
+func (s *CrosshairCollectionService) AddCrosshair(id, ownerID, crosshairID uuid.UUID) (*CrosshairCollectionResponse, error) {
+	if _, err := s.getOwned(id, ownerID); err != nil {
+		return nil, err
+	}
+	if _, err := s.crosshairs.getVisible(crosshairID, ownerID); err != nil {
+		return nil, err
+	}
+
+	count, err := s.repo.CountItems(id)
+	if err != nil {
+		return nil, err
+	}
+	if count >= maxCollectionItems {
+		return nil, fmt.Errorf("%w: at most %d crosshairs per collection", cErrors.ErrCollectionLimit, maxCollectionItems)
+	}
+
+	if err := s.repo.AddItem(id, crosshairID); err != nil {
+		return nil, err
+	}
+	return s.Get(id, ownerID)
+}
+
+// SetCrosshairs replaces the crosshairs of the collection, in the given
+// order. It is also how crosshairs are reordered.
+func (s *CrosshairCollectionService) SetCrosshairs(id, ownerID uuid.UUID, crosshairIDs []uuid.UUID) (*CrosshairCollectionResponse, error) {
+	if _, err := s.getOwned(id, ownerID); err != nil {
+		return nil, err
+	}
+	if len(crosshairIDs) > maxCollectionItems {
+		return nil, fmt.Errorf("%w: at most %d crosshairs per collection", cErrors.ErrCollectionLimit, maxCollectionItems)
+	}
+
+	seen := make(map[uuid.UUID]bool, len(crosshairIDs))
+	for _, crosshairID := range crosshairIDs {
+		if seen[crosshairID] {
+			return nil, fmt.Errorf("%w: crosshair %s is listed twice", cErrors.ErrInvalidRequestBody, crosshairID)
+		}
+		seen[crosshairID] = true
+		if _, err := s.crosshairs.getVisible(crosshairID, ownerID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.repo.SetItems(id, crosshairIDs); err != nil {
+		return nil, err
+	}
+	return s.Get(id, ownerID)
+}
+
+func (s *CrosshairCollectionService) RemoveCrosshair(id, ownerID, crosshairID uuid.UUID) (*CrosshairCollectionResponse, error) {
+	if _, err := s.getOwned(id, ownerID); err != nil {
+		return nil, err
+	}
+	if err := s.repo.RemoveItem(id, crosshairID); err != nil {
+		return nil, err
+	}
+	return s.Get(id, ownerID)
+}
+
+func (s *CrosshairCollectionService) getOwned(id, ownerID uuid.UUID) (*domain.CrosshairCollection, error) {
+	collection, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, cErrors.ErrCollectionNotFound
+	}
+	if collection.OwnerID != ownerID {
+		return nil, cErrors.ErrCollectionForbidden
+	}
+	return collection, nil
+}
+
+func (s *CrosshairCollectionService) checkName(ownerID uuid.UUID, name string, exceptID uuid.UUID) error {
+	taken, err := s.repo.NameTaken(ownerID, name, exceptID)
+	if err != nil {
+		return err
+	}
+	if taken {
+		return cErrors.ErrCollectionNameTaken
+	}
+	return nil
+}
+
+func toCollectionResponse(c *domain.CrosshairCollection) *CrosshairCollectionResponse {
+	ownerName := ""
+	ownerAvatar := ""
+	if c.Owner != nil {
+		ownerName = c.Owner.Nickname
+		ownerAvatar = c.Owner.AvatarURL
+	}
+
+	return &CrosshairCollectionResponse{
+		ID:          c.ID,
+		OwnerID:     c.OwnerID,
+		OwnerName:   ownerName,
+		OwnerAvatar: ownerAvatar,
+		Name:        c.Name,
+		Description: c.Description,
+		IsPublic:    c.IsPublic,
+		CreatedAt:   c.CreatedAt,
+		UpdatedAt:   c.UpdatedAt,
+	}
+}
@@ -0,0 +1,298 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/quenyu/deadlock-stats/internal/domain"
+	cErrors "github.com/quenyu/deadlock-stats/internal/errors"
+)
+
+// Deadlock crosshair convars. The order is the order of the export, the same
+// as the copy button on the crosshair page.
+const (
+	convarColorR            = "citadel_crosshair_color_r"
+	convarColorG            = "citadel_crosshair_color_g"
+	convarColorB            = "citadel_crosshair_color_b"
+	convarPipBorder         = "citadel_crosshair_pip_border"
+	convarPipGapStatic      = "citadel_crosshair_pip_gap_static"
+	convarPipOpacity        = "citadel_crosshair_pip_opacity"
+	convarPipWidth          = "citadel_crosshair_pip_width"
+	convarPipHeight         = "citadel_crosshair_pip_height"
+	convarPipGap            = "citadel_crosshair_pip_gap"
+	convarDotOpacity        = "citadel_crosshair_dot_opacity"
+	convarDotOutlineOpacity = "citadel_crosshair_dot_outline_opacity"
+	convarHitMarkerDuration = "citadel_crosshair_hit_marker_duration"
+)
+
+const maxCrosshairImportLength = 4096
+
+// requiredCrosshairConvars must all be present in an import. The hit marker
+// duration is optional because older exports do not include it.
+var requiredCrosshairConvars = []string{
+	convarColorR, convarColorG, convarColorB,
+	convarPipBorder, convarPipGapStatic, convarPipOpacity,
+	convarPipWidth, convarPipHeight, convarPipGap,
+	convarDotOpacity, convarDotOutlineOpacity,
+}
+
+var (
+	crosshairColorPattern    = regexp.MustCompile(`^#?([0-9a-fA-F]{2})([0-9a-fA-F]{2})([0-9a-fA-F]{2})$`)
+	crosshairFileNamePattern = regexp.MustCompile(`[^a-zA-Z0-9]`)
+)
+
+type CrosshairExport struct {
+	Content     []byte
+	ContentType string
+	Filename    string
+}
+
+// Export renders a crosshair as a one-line console command ("console"), an
+// autoexec snippet ("cfg") or its settings JSON ("json").
+func (s *CrosshairService) Export(id, viewerID uuid.UUID, format string) (*CrosshairExport, error) {
+	crosshair, err := s.getVisible(id, viewerID)
+	if err != nil {
+		return nil, err
+	}
+
+	settings := decodeCrosshairSettings(crosshair.Settings)
+	name := crosshairFileName(crosshair.Title)
+
+	switch format {
+	case "", "console":
+		return &CrosshairExport{
+			Content:     []byte(strings.Join(CrosshairCommands(settings), "; ")),
+			ContentType: "text/plain; charset=utf-8",
+		}, nil
+	case "cfg":
+		var cfg strings.Builder
+		author := "unknown"
+		if crosshair.Author != nil {
+			author = crosshair.Author.Nickname
+		}
+		fmt.Fprintf(&cfg, "// %s by %s\n", singleLine(crosshair.Title), singleLine(author))
+		for _, command := range CrosshairCommands(settings) {
+			cfg.WriteString(command)
+			cfg.WriteByte('\n')
+		}
+		return &CrosshairExport{
+			Content:     []byte(cfg.String()),
+			ContentType: "text/plain; charset=utf-8",
+			Filename:    name + "_crosshair.cfg",
+		}, nil
+	case "json":
+		data, err := json.MarshalIndent(settings, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return &CrosshairExport{
+			Content:     data,
+			ContentType: "application/json",
+			Filename:    name + "_crosshair.json",
+		}, nil
+	default:
+		return nil, fmt.Errorf("%w: format must be console, cfg or json", cErrors.ErrInvalidQuery)
+	}
+}
+
+// CrosshairCommands returns the console commands that apply settings in game.
+// The game has no convar for Opacity on its own; it is written as the dot
+// opacity, which is 0 when the dot is off.
+func CrosshairCommands(settings domain.CrosshairSettings) []string {
+	r, g, b := crosshairColorRGB(settings.Color)
+
+	dotOpacity := 0.0
+	if settings.Dot {
+		dotOpacity = settings.Opacity
+	}
+
+	return []string{
+		fmt.Sprintf("%s %d", convarColorR, r),
+		fmt.Sprintf("%s %d", convarColorG, g),
+		fmt.Sprintf("%s %d", convarColorB, b),
+		fmt.Sprintf("%s %t", convarPipBorder, settings.PipBorder),
+		fmt.Sprintf("%s %t", convarPipGapStatic, settings.PipGapStatic),
+		fmt.Sprintf("%s %s", convarPipOpacity, formatConvarFloat(settings.PipOpacity)),
+		fmt.Sprintf("%s %d", convarPipWidth, settings.Thickness),
+		fmt.Sprintf("%s %d", convarPipHeight, settings.Length),
+		fmt.Sprintf("%s %d", convarPipGap, settings.Gap),
+		fmt.Sprintf("%s %s", convarDotOpacity, formatConvarFloat(dotOpacity)),
+		fmt.Sprintf("%s %s", convarDotOutlineOpacity, formatConvarFloat(settings.DotOutlineOpacity)),
+		fmt.Sprintf("%s %s", convarHitMarkerDuration, formatConvarFloat(settings.HitMarkerDuration)),
+	}
+}
+
+// ParseCrosshairCommands reads pasted console commands back into settings.
+// Commands may be separated by semicolons or newlines and values may be
+// quoted; // comments are ignored. Unknown or repeated convars, malformed
+// values and missing required convars are rejected. Ranges are checked by
+// the caller with the same rules as a created crosshair.
+//
+// Opacity is read from the dot opacity, so importing an export of a
+// crosshair with the dot off resets Opacity to 1: the original value is not
+// part of the commands.
+func ParseCrosshairCommands(input string) (*domain.CrosshairSettings, error) {
+	if len(input) > maxCrosshairImportLength {
+		return nil, importError("input is longer than %d characters", maxCrosshairImportLength)
+	}
+
+	values := make(map[string]string)
+	for _, line := range strings.Split(input, "\n") {
+		if comment := strings.Index(line, "//"); comment >= 0 {
+			line = line[:comment]
+		}
+		for _, command := range strings.Split(line, ";") {
+			fields := strings.Fields(command)
+			if len(fields) == 0 {
+				continue
+			}
+			if len(fields) != 2 {
+				return nil, importError("expected \"<convar> <value>\", got %q", strings.TrimSpace(command))
+			}
+
+			name := strings.ToLower(fields[0])
+			if !isCrosshairConvar(name) {
+				return nil, importError("unknown command %q", fields[0])
+			}
+			if _, seen := values[name]; seen {
+				return nil, importError("%s is set more than once", name)
+			}
+			values[name] = strings.Trim(fields[1], `"`)
+		}
+	}
+
+	for _, name := range requiredCrosshairConvars {
+		if _, ok := values[name]; !ok {
+			return nil, importError("%s is missing", name)
+		}
+	}
+
+	var settings domain.CrosshairSettings
+	var r, g, b int
+	var dotOpacity float64
+
+	parsers := []error{
+		parseConvarInt(values, convarColorR, &r),
+		parseConvarInt(values, convarColorG, &g),
+		parseConvarInt(values, convarColorB, &b),
+		parseConvarBool(values, convarPipBorder, &settings.PipBorder),
+		parseConvarBool(values, convarPipGapStatic, &settings.PipGapStatic),
+		parseConvarFloat(values, convarPipOpacity, &settings.PipOpacity),
+		parseConvarInt(values, convarPipWidth, &settings.Thickness),
+		parseConvarInt(values, convarPipHeight, &settings.Length),
+		parseConvarInt(values, convarPipGap, &settings.Gap),
+		parseConvarFloat(values, convarDotOpacity, &dotOpacity),
+		parseConvarFloat(values, convarDotOutlineOpacity, &settings.DotOutlineOpacity),
+		parseConvarFloat(values, convarHitMarkerDuration, &settings.HitMarkerDuration),
+	}
+	for _, err := range parsers {
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, component := range []int{r, g, b} {
+		if component < 0 || component > 255 {
+			return nil, importError("color components must be between 0 and 255")
+		}
+	}
+	if settings.HitMarkerDuration < 0 {
+		return nil, importError("%s cannot be negative", convarHitMarkerDuration)
+	}
+	if dotOpacity < 0 {
+		return nil, importError("%s cannot be negative", convarDotOpacity)
+	}
+
+	settings.Color = fmt.Sprintf("#%02x%02x%02x", r, g, b)
+	settings.Dot = dotOpacity > 0
+	settings.Opacity = 1
+	if settings.Dot {
+		settings.Opacity = dotOpacity
+	}
+
+	return &settings, nil
+}
+
+func isCrosshairConvar(name string) bool {
+	return name == convarHitMarkerDuration || slices.Contains(requiredCrosshairConvars, name)
+}
+
+func parseConvarInt(values map[string]string, name string, dest *int) error {
+	value, ok := values[name]
+	if !ok {
+		return nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return importError("%s must be a whole number, got %q", name, value)
+	}
+	*dest = n
+	return nil
+}
+
+func parseConvarFloat(values map[string]string, name string, dest *float64) error {
+	value, ok := values[name]
+	if !ok {
+		return nil
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		return importError("%s must be a number, got %q", name, value)
+	}
+	*dest = f
+	return nil
+}
+
+func parseConvarBool(values map[string]string, name string, dest *bool) error {
+	value, ok := values[name]
+	if !ok {
+		return nil
+	}
+	switch strings.ToLower(value) {
+	case "true", "1":
+		*dest = true
+	case "false", "0":
+		*dest = false
+	default:
+		return importError("%s must be true or false, got %q", name, value)
+	}
+	return nil
+}
+
+func importError(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", cErrors.ErrInvalidRequestBody, fmt.Sprintf(format, args...))
+}
+
+func crosshairColorRGB(color string) (int, int, int) {
+	match := crosshairColorPattern.FindStringSubmatch(color)
+	if match == nil {
+		return 0, 0, 0
+	}
+	r, _ := strconv.ParseInt(match[1], 16, 0)
+	g, _ := strconv.ParseInt(match[2], 16, 0)
+	b, _ := strconv.ParseInt(match[3], 16, 0)
+	return int(r), int(g), int(b)
+}
+
+func formatConvarFloat(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+// crosshairFileName mirrors the download name used by the frontend.
+func crosshairFileName(title string) string {
+	name := strings.ToLower(crosshairFileNamePattern.ReplaceAllString(title, "_"))
+	if name == "" {
+		return "crosshair"
+	}
+	return name
+}
+
+func singleLine(value string) string {
+	return strings.Join(strings.Fields(value), " ")
+}
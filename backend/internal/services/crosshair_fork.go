@@ -0,0 +1,97 @@
+package services
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/quenyu/deadlock-stats/internal/domain"
+)
+
+const (
+	maxCrosshairLineageDepth = 20
+	maxCrosshairForksListed  = 50
+	maxCrosshairTitleLength  = 100
+)
+
+type ForkCrosshairRequest struct {
+	Title    string `json:"title"`
+	IsPublic bool   `json:"is_public"`
+}
+
+type CrosshairLineageResponse struct {
+	// Ancestors run from the crosshair it was forked from up to the
+	// original; ones the viewer may not see are left out.
+	Ancestors  []CrosshairResponse `json:"ancestors"`
+	Forks      []CrosshairResponse `json:"forks"`
+	ForksCount int                 `json:"forks_count"`
+}
+
+// Fork copies a crosshair the user can see into a new crosshair owned by
+// them. Without a title the fork is named after the original.
+func (s *CrosshairService) Fork(id, userID uuid.UUID, req *ForkCrosshairRequest) (*CrosshairResponse, error) {
+	original, err := s.getVisible(id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	title := req.Title
+	if title == "" {
+		title = forkTitle(original.Title)
+	}
+
+	fork := &domain.Crosshair{
+		AuthorID:    userID,
+		Title:       title,
+		Description: original.Description,
+		Settings:    original.Settings,
+		IsPublic:    req.IsPublic,
+		ForkedFrom:  &original.ID,
+	}
+	if err := s.repo.Fork(fork); err != nil {
+		return nil, err
+	}
+	return toCrosshairResponse(fork), nil
+}
+
+// GetLineage returns where a crosshair was forked from and its direct forks.
+func (s *CrosshairService) GetLineage(id, viewerID uuid.UUID) (*CrosshairLineageResponse, error) {
+	crosshair, err := s.getVisible(id, viewerID)
+	if err != nil {
+		return nil, err
+	}
+
+	ancestors, err := s.repo.GetAncestors(id, maxCrosshairLineageDepth)
+	if err != nil {
+		return nil, err
+	}
+	visible := ancestors[:0]
+	for _, ancestor := range ancestors {
+		if ancestor.IsPublic || ancestor.AuthorID == viewerID {
+			visible = append(visible, ancestor)
+		}
+	}
+
+	forks, err := s.repo.GetForks(id, viewerID, maxCrosshairForksListed)
+	if err != nil {
+		return nil, err
+	}
+
+	lineage := &CrosshairLineageResponse{ForksCount: crosshair.ForksCount}
+	if lineage.Ancestors, err = s.toCrosshairResponses(visible, viewerID); err != nil {
+		return nil, err
+	}
+	if lineage.Forks, err = s.toCrosshairResponses(forks, viewerID); err != nil {
+		return nil, err
+	}
+	return lineage, nil
+}
+
+func forkTitle(title string) string {
+	const suffix = " (fork)"
+	for len(title)+len(suffix) > maxCrosshairTitleLength {
+		_, size := utf8.DecodeLastRuneInString(title)
+		title = title[:len(title)-size]
+	}
+	return strings.TrimSpace(title) + suffix
+}
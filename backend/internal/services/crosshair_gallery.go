@@ -0,0 +1,219 @@
+package services
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/quenyu/deadlock-stats/internal/domain"
+	cErrors "github.com/quenyu/deadlock-stats/internal/errors"
+)
+
+const (
+	crosshairTrendingWindow   = 7 * 24 * time.Hour
+	crosshairTrendingHalfLife = 24 * time.Hour
+
+	// crosshairTrendingViewWeight makes twenty views worth one like.
+	crosshairTrendingViewWeight = 0.05
+
+	MaxCrosshairStatsDays = 90
+)
+
+var (
+	CrosshairSorts         = []string{"new", "top", "trending"}
+	CrosshairColorFamilies = []string{"red", "orange", "yellow", "green", "cyan", "blue", "purple", "pink", "white", "gray", "black"}
+)
+
+// CrosshairGalleryQuery holds the gallery parameters of GET /crosshairs.
+// Nil pointers and empty strings leave a filter out.
+type CrosshairGalleryQuery struct {
+	Sort         string
+	Cursor       string
+	Limit        int
+	Dot          *bool
+	ColorFamily  string
+	MinThickness *int
+	MaxThickness *int
+	Tag          string
+}
+
+type CrosshairGalleryResponse struct {
+	Crosshairs []CrosshairResponse `json:"crosshairs"`
+	Total      int64               `json:"total"`
+	Limit      int                 `json:"limit"`
+	NextCursor string              `json:"next_cursor,omitempty"`
+}
+
+// crosshairCursor is the opaque position handed out as next_cursor. The
+// trending sort keeps the time its scores were computed at so that later
+// pages rank likes the same way as the first.
+type crosshairCursor struct {
+	Sort  string `json:"s"`
+	Value string `json:"v"`
+	ID    string `json:"id"`
+	At    int64  `json:"at,omitempty"`
+}
+
+// List returns one page of the gallery. viewerID is uuid.Nil for anonymous
+// viewers, who only see public crosshairs.
+func (s *CrosshairService) List(query CrosshairGalleryQuery, viewerID uuid.UUID) (*CrosshairGalleryResponse, error) {
+	if query.Sort == "" {
+		query.Sort = "new"
+	}
+	if !slices.Contains(CrosshairSorts, query.Sort) {
+		return nil, fmt.Errorf("%w: sort must be new, top or trending", cErrors.ErrInvalidQuery)
+	}
+	if query.ColorFamily != "" && !slices.Contains(CrosshairColorFamilies, query.ColorFamily) {
+		return nil, fmt.Errorf("%w: unknown color %q", cErrors.ErrInvalidQuery, query.ColorFamily)
+	}
+
+	filter := domain.CrosshairFilter{
+		ViewerID:           viewerID,
+		Sort:               query.Sort,
+		Limit:              query.Limit,
+		TrendingAt:         time.Now().UTC().Truncate(time.Second),
+		TrendingWindow:     crosshairTrendingWindow,
+		TrendingHalfLife:   crosshairTrendingHalfLife,
+		TrendingViewWeight: crosshairTrendingViewWeight,
+		Dot:                query.Dot,
+		ColorFamily:        query.ColorFamily,
+		MinThickness:       query.MinThickness,
+		MaxThickness:       query.MaxThickness,
+		Tag:                query.Tag,
+	}
+
+	cursor, err := decodeCrosshairCursor(query.Cursor)
+	if err != nil {
+		return nil, err
+	}
+	if cursor != nil {
+		if cursor.Sort != query.Sort {
+			return nil, fmt.Errorf("%w: cursor belongs to another sort", cErrors.ErrInvalidQuery)
+		}
+		id, _ := uuid.Parse(cursor.ID)
+		filter.After = &domain.CrosshairKeyset{Value: cursor.Value, ID: id}
+		if cursor.At > 0 {
+			filter.TrendingAt = time.Unix(cursor.At, 0).UTC()
+		}
+	}
+
+	crosshairs, total, err := s.repo.List(filter)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.toCrosshairResponses(crosshairs, viewerID)
+	if err != nil {
+		return nil, err
+	}
+
+	gallery := &CrosshairGalleryResponse{Crosshairs: resp, Total: total, Limit: query.Limit}
+	if len(crosshairs) == query.Limit {
+		last := crosshairs[len(crosshairs)-1]
+		next := crosshairCursor{Sort: query.Sort, ID: last.ID.String()}
+		switch query.Sort {
+		case "top":
+			next.Value = strconv.Itoa(last.LikesCount)
+		case "trending":
+			next.Value = strconv.FormatFloat(last.TrendingScore, 'g', -1, 64)
+			next.At = filter.TrendingAt.Unix()
+		default:
+			next.Value = last.CreatedAt.Format(time.RFC3339Nano)
+		}
+		gallery.NextCursor = next.encode()
+	}
+	return gallery, nil
+}
+
+func (c crosshairCursor) encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCrosshairCursor returns nil for an empty cursor.
+func decodeCrosshairCursor(value string) (*crosshairCursor, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor", cErrors.ErrInvalidQuery)
+	}
+
+	var cursor crosshairCursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.Value == "" {
+		return nil, fmt.Errorf("%w: malformed cursor", cErrors.ErrInvalidQuery)
+	}
+	if _, err := uuid.Parse(cursor.ID); err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor", cErrors.ErrInvalidQuery)
+	}
+
+	// Values are cast by the database, so check them here to answer 400
+	// rather than 500.
+	switch cursor.Sort {
+	case "top":
+		_, err = strconv.Atoi(cursor.Value)
+	case "trending":
+		_, err = strconv.ParseFloat(cursor.Value, 64)
+	default:
+		_, err = time.Parse(time.RFC3339Nano, cursor.Value)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor", cErrors.ErrInvalidQuery)
+	}
+
+	return &cursor, nil
+}
+
+type CrosshairDailyViewCount struct {
+	Day   string `json:"day"`
+	Views int    `json:"views"`
+}
+
+type CrosshairAuthorStats struct {
+	Days        int                         `json:"days"`
+	TotalViews  int                         `json:"total_views"`
+	TotalLikes  int                         `json:"total_likes"`
+	RecentViews int                         `json:"recent_views"`
+	Crosshairs  []domain.CrosshairViewStats `json:"crosshairs"`
+	Daily       []CrosshairDailyViewCount   `json:"daily"`
+}
+
+// AuthorStats summarizes the views and likes of an author's crosshairs over
+// the last days, today included. Views reach the database in batches, so
+// the latest ones may be missing.
+func (s *CrosshairService) AuthorStats(authorID uuid.UUID, days int) (*CrosshairAuthorStats, error) {
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	since := today.AddDate(0, 0, -(days - 1))
+
+	crosshairs, err := s.repo.GetViewStats(authorID, since)
+	if err != nil {
+		return nil, err
+	}
+	daily, err := s.repo.GetDailyViews(authorID, since)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &CrosshairAuthorStats{Days: days, Crosshairs: crosshairs}
+	for _, c := range crosshairs {
+		stats.TotalViews += c.ViewCount
+		stats.TotalLikes += c.LikesCount
+		stats.RecentViews += c.RecentViews
+	}
+
+	viewsByDay := make(map[string]int, len(daily))
+	for _, d := range daily {
+		viewsByDay[d.Day.Format("2006-01-02")] = d.Views
+	}
+	stats.Daily = make([]CrosshairDailyViewCount, days)
+	for i := range stats.Daily {
+		day := since.AddDate(0, 0, i).Format("2006-01-02")
+		stats.Daily[i] = CrosshairDailyViewCount{Day: day, Views: viewsByDay[day]}
+	}
+	return stats, nil
+}
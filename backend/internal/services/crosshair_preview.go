@@ -0,0 +1,344 @@
+package services
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/quenyu/deadlock-stats/internal/domain"
+	cErrors "github.com/quenyu/deadlock-stats/internal/errors"
+)
+
+const (
+	// crosshairPreviewScene is the size of the viewBox the frontend draws
+	// crosshairs in; previews use the same coordinates.
+	crosshairPreviewScene = 120
+
+	// CrosshairPreviewSize is the width and height of rendered previews.
+	CrosshairPreviewSize = 480
+
+	// crosshairPreviewVersion is part of the cache key, so bump it whenever
+	// the drawing changes.
+	crosshairPreviewVersion = 1
+
+	maxCachedCrosshairPreviews = 512
+
+	// previewHorizonLine is where the sample background turns from sky to
+	// ground, as a fraction of the height.
+	previewHorizonLine = 0.55
+)
+
+var CrosshairPreviewBackgrounds = []string{"neutral", "sample"}
+
+var (
+	previewNeutral    = color.RGBA{0x11, 0x18, 0x27, 0xff}
+	previewSkyTop     = color.RGBA{0x6b, 0x8c, 0xae, 0xff}
+	previewSkyHorizon = color.RGBA{0xc9, 0xd6, 0xdf, 0xff}
+	previewGroundNear = color.RGBA{0x5b, 0x53, 0x45, 0xff}
+	previewGroundFar  = color.RGBA{0x3d, 0x37, 0x2d, 0xff}
+	previewOutline    = color.RGBA{0x00, 0x00, 0x00, 0xff}
+)
+
+// CrosshairPreview is a rendered crosshair. Renders are shared by every
+// crosshair with the same settings; Public tells whether this one may be
+// cached by shared caches.
+type CrosshairPreview struct {
+	Content     []byte
+	ContentType string
+	ETag        string
+	Public      bool
+}
+
+// previewShape is a rectangle, or a circle when radius is set, in scene
+// coordinates.
+type previewShape struct {
+	x, y, w, h float64
+	radius     float64
+	color      color.RGBA
+	opacity    float64
+}
+
+// crosshairPreviewCache keeps rendered previews by settings hash. When it
+// is full an arbitrary entry makes room; previews are cheap to redraw.
+type crosshairPreviewCache struct {
+	mu       sync.Mutex
+	previews map[string]*CrosshairPreview
+}
+
+func newCrosshairPreviewCache() *crosshairPreviewCache {
+	return &crosshairPreviewCache{previews: make(map[string]*CrosshairPreview)}
+}
+
+func (c *crosshairPreviewCache) get(key string) (*CrosshairPreview, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	preview, ok := c.previews[key]
+	return preview, ok
+}
+
+func (c *crosshairPreviewCache) set(key string, preview *CrosshairPreview) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.previews) >= maxCachedCrosshairPreviews {
+		for evict := range c.previews {
+			delete(c.previews, evict)
+			break
+		}
+	}
+	c.previews[key] = preview
+}
+
+// Preview renders a crosshair as "svg" or "png" over a "neutral" or
+// "sample" background.
+func (s *CrosshairService) Preview(id, viewerID uuid.UUID, format, background string) (*CrosshairPreview, error) {
+	if background == "" {
+		background = "neutral"
+	}
+	if !slices.Contains(CrosshairPreviewBackgrounds, background) {
+		return nil, fmt.Errorf("%w: background must be neutral or sample", cErrors.ErrInvalidQuery)
+	}
+	if format != "svg" && format != "png" {
+		return nil, fmt.Errorf("%w: format must be svg or png", cErrors.ErrInvalidQuery)
+	}
+
+	crosshair, err := s.getVisible(id, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	settings := decodeCrosshairSettings(crosshair.Settings)
+
+	key, err := crosshairPreviewKey(settings, format, background)
+	if err != nil {
+		return nil, err
+	}
+	preview, ok := s.previews.get(key)
+	if !ok {
+		preview = &CrosshairPreview{ETag: `"` + key + `"`}
+		if format == "svg" {
+			preview.Content = RenderCrosshairSVG(settings, background)
+			preview.ContentType = "image/svg+xml"
+		} else {
+			if preview.Content, err = RenderCrosshairPNG(settings, background); err != nil {
+				return nil, err
+			}
+			preview.ContentType = "image/png"
+		}
+		s.previews.set(key, preview)
+	}
+
+	result := *preview
+	result.Public = crosshair.IsPublic
+	return &result, nil
+}
+
+func crosshairPreviewKey(settings domain.CrosshairSettings, format, background string) (string, error) {
+	data, err := json.Marshal(settings)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%d:%s:%s:%s", crosshairPreviewVersion, format, background, data)))
+	return hex.EncodeToString(sum[:16]), nil
+}
+
+// crosshairShapes lays the crosshair out like the frontend preview: the dot
+// under the four pips, each pip over its border when PipBorder is set.
+func crosshairShapes(settings domain.CrosshairSettings) []previewShape {
+	r, g, b := crosshairColorRGB(settings.Color)
+	fill := color.RGBA{uint8(r), uint8(g), uint8(b), 0xff}
+
+	center := float64(crosshairPreviewScene) / 2
+	thickness := float64(settings.Thickness)
+	length := float64(settings.Length)
+	gap := float64(settings.Gap)
+	pipOpacity := clampOpacity(settings.PipOpacity)
+
+	var shapes []previewShape
+	if settings.Dot {
+		shapes = append(shapes,
+			previewShape{x: center, y: center, radius: 4, color: previewOutline, opacity: clampOpacity(settings.DotOutlineOpacity)},
+			previewShape{x: center, y: center, radius: 2, color: fill, opacity: clampOpacity(settings.Opacity)},
+		)
+	}
+
+	pips := []previewShape{
+		{x: center - thickness/2, y: center - gap/2 - length, w: thickness, h: length},
+		{x: center - thickness/2, y: center + gap/2, w: thickness, h: length},
+		{x: center - gap/2 - length, y: center - thickness/2, w: length, h: thickness},
+		{x: center + gap/2, y: center - thickness/2, w: length, h: thickness},
+	}
+	if settings.PipBorder {
+		for _, pip := range pips {
+			shapes = append(shapes, previewShape{
+				x: pip.x - 1, y: pip.y - 1, w: pip.w + 2, h: pip.h + 2,
+				color: previewOutline, opacity: pipOpacity,
+			})
+		}
+	}
+	for _, pip := range pips {
+		pip.color = fill
+		pip.opacity = pipOpacity
+		shapes = append(shapes, pip)
+	}
+
+	return shapes
+}
+
+// RenderCrosshairSVG draws the crosshair in the frontend's 120x120 viewBox.
+func RenderCrosshairSVG(settings domain.CrosshairSettings, background string) []byte {
+	var svg strings.Builder
+	fmt.Fprintf(&svg, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`,
+		CrosshairPreviewSize, CrosshairPreviewSize, crosshairPreviewScene, crosshairPreviewScene)
+
+	scene := crosshairPreviewScene
+	if background == "sample" {
+		horizon := previewHorizonLine * float64(scene)
+		fmt.Fprintf(&svg, `<defs>`+
+			`<linearGradient id="sky" x1="0" y1="0" x2="0" y2="1"><stop offset="0" stop-color="%s"/><stop offset="1" stop-color="%s"/></linearGradient>`+
+			`<linearGradient id="ground" x1="0" y1="0" x2="0" y2="1"><stop offset="0" stop-color="%s"/><stop offset="1" stop-color="%s"/></linearGradient>`+
+			`</defs>`,
+			svgColor(previewSkyTop), svgColor(previewSkyHorizon), svgColor(previewGroundNear), svgColor(previewGroundFar))
+		fmt.Fprintf(&svg, `<rect width="%d" height="%s" fill="url(#sky)"/>`, scene, svgNumber(horizon))
+		fmt.Fprintf(&svg, `<rect y="%s" width="%d" height="%s" fill="url(#ground)"/>`,
+			svgNumber(horizon), scene, svgNumber(float64(scene)-horizon))
+	} else {
+		fmt.Fprintf(&svg, `<rect width="%d" height="%d" fill="%s"/>`, scene, scene, svgColor(previewNeutral))
+	}
+
+	for _, shape := range crosshairShapes(settings) {
+		if shape.radius > 0 {
+			fmt.Fprintf(&svg, `<circle cx="%s" cy="%s" r="%s" fill="%s" opacity="%s"/>`,
+				svgNumber(shape.x), svgNumber(shape.y), svgNumber(shape.radius), svgColor(shape.color), svgNumber(shape.opacity))
+			continue
+		}
+		if shape.w <= 0 || shape.h <= 0 {
+			continue
+		}
+		fmt.Fprintf(&svg, `<rect x="%s" y="%s" width="%s" height="%s" fill="%s" opacity="%s"/>`,
+			svgNumber(shape.x), svgNumber(shape.y), svgNumber(shape.w), svgNumber(shape.h), svgColor(shape.color), svgNumber(shape.opacity))
+	}
+
+	svg.WriteString(`</svg>`)
+	return []byte(svg.String())
+}
+
+// RenderCrosshairPNG rasterizes the same shapes as RenderCrosshairSVG with
+// anti-aliased edges.
+func RenderCrosshairPNG(settings domain.CrosshairSettings, background string) ([]byte, error) {
+	img := image.NewRGBA(image.Rect(0, 0, CrosshairPreviewSize, CrosshairPreviewSize))
+	fillPreviewBackground(img, background)
+
+	scale := float64(CrosshairPreviewSize) / crosshairPreviewScene
+	for _, shape := range crosshairShapes(settings) {
+		if shape.radius > 0 {
+			drawPreviewCircle(img, shape.x*scale, shape.y*scale, shape.radius*scale, shape.color, shape.opacity)
+			continue
+		}
+		drawPreviewRect(img, shape.x*scale, shape.y*scale, shape.w*scale, shape.h*scale, shape.color, shape.opacity)
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func fillPreviewBackground(img *image.RGBA, background string) {
+	size := img.Bounds().Dy()
+	horizon := int(previewHorizonLine * float64(size))
+	for y := 0; y < size; y++ {
+		c := previewNeutral
+		if background == "sample" {
+			if y < horizon {
+				c = mixColor(previewSkyTop, previewSkyHorizon, float64(y)/float64(horizon))
+			} else {
+				c = mixColor(previewGroundNear, previewGroundFar, float64(y-horizon)/float64(size-horizon))
+			}
+		}
+		for x := 0; x < img.Bounds().Dx(); x++ {
+			img.SetRGBA(x, y, c)
+		}
+	}
+}
+
+// drawPreviewRect blends every pixel by how much of it the rectangle covers.
+func drawPreviewRect(img *image.RGBA, x, y, w, h float64, c color.RGBA, opacity float64) {
+	if w <= 0 || h <= 0 || opacity <= 0 {
+		return
+	}
+	bounds := img.Bounds()
+	x0, y0 := max(int(math.Floor(x)), bounds.Min.X), max(int(math.Floor(y)), bounds.Min.Y)
+	x1, y1 := min(int(math.Ceil(x+w)), bounds.Max.X), min(int(math.Ceil(y+h)), bounds.Max.Y)
+
+	for py := y0; py < y1; py++ {
+		coverY := math.Min(y+h, float64(py+1)) - math.Max(y, float64(py))
+		for px := x0; px < x1; px++ {
+			coverX := math.Min(x+w, float64(px+1)) - math.Max(x, float64(px))
+			blendPixel(img, px, py, c, opacity*coverX*coverY)
+		}
+	}
+}
+
+// drawPreviewCircle estimates coverage with a 4x4 grid of samples per pixel.
+func drawPreviewCircle(img *image.RGBA, cx, cy, radius float64, c color.RGBA, opacity float64) {
+	if radius <= 0 || opacity <= 0 {
+		return
+	}
+	const samples = 4
+	bounds := img.Bounds()
+	x0, y0 := max(int(math.Floor(cx-radius)), bounds.Min.X), max(int(math.Floor(cy-radius)), bounds.Min.Y)
+	x1, y1 := min(int(math.Ceil(cx+radius)), bounds.Max.X), min(int(math.Ceil(cy+radius)), bounds.Max.Y)
+
+	for py := y0; py < y1; py++ {
+		for px := x0; px < x1; px++ {
+			inside := 0
+			for sy := 0; sy < samples; sy++ {
+				for sx := 0; sx < samples; sx++ {
+					dx := float64(px) + (float64(sx)+0.5)/samples - cx
+					dy := float64(py) + (float64(sy)+0.5)/samples - cy
+					if dx*dx+dy*dy <= radius*radius {
+						inside++
+					}
+				}
+			}
+			blendPixel(img, px, py, c, opacity*float64(inside)/(samples*samples))
+		}
+	}
+}
+
+func blendPixel(img *image.RGBA, x, y int, c color.RGBA, alpha float64) {
+	if alpha <= 0 {
+		return
+	}
+	dst := img.RGBAAt(x, y)
+	img.SetRGBA(x, y, mixColor(dst, c, math.Min(alpha, 1)))
+}
+
+func mixColor(from, to color.RGBA, t float64) color.RGBA {
+	mix := func(a, b uint8) uint8 {
+		return uint8(math.Round(float64(a) + (float64(b)-float64(a))*t))
+	}
+	return color.RGBA{mix(from.R, to.R), mix(from.G, to.G), mix(from.B, to.B), 0xff}
+}
+
+func clampOpacity(opacity float64) float64 {
+	return math.Max(0, math.Min(1, opacity))
+}
+
+func svgColor(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+func svgNumber(value float64) string {
+	return formatConvarFloat(math.Round(value*1000) / 1000)
+}
@@ -6,15 +6,17 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/quenyu/deadlock-stats/internal/domain"
+	cErrors "github.com/quenyu/deadlock-stats/internal/errors"
 	"github.com/quenyu/deadlock-stats/internal/repositories"
 )
 
 type CrosshairService struct {
-	repo *repositories.CrosshairRepository
+	repo     *repositories.CrosshairRepository
+	previews *crosshairPreviewCache
 }
 
 func NewCrosshairService(repo *repositories.CrosshairRepository) *CrosshairService {
-	return &CrosshairService{repo: repo}
+	return &CrosshairService{repo: repo, previews: newCrosshairPreviewCache()}
 }
 
 type CreateCrosshairRequest struct {
@@ -24,19 +26,40 @@ type CreateCrosshairRequest struct {
 	IsPublic    bool                     `json:"is_public"`
 }
 
+type UpdateCrosshairRequest struct {
+	Title       string                   `json:"title"`
+	Description string                   `json:"description"`
+	Settings    domain.CrosshairSettings `json:"settings"`
+	IsPublic    bool                     `json:"is_public"`
+}
+
+type CrosshairRevisionResponse struct {
+	ID          uuid.UUID                `json:"id"`
+	CrosshairID uuid.UUID                `json:"crosshair_id"`
+	Title       string                   `json:"title"`
+	Description string                   `json:"description"`
+	Settings    domain.CrosshairSettings `json:"settings"`
+	IsPublic    bool                     `json:"is_public"`
+	CreatedAt   time.Time                `json:"created_at"`
+}
+
 type CrosshairResponse struct {
-	ID           uuid.UUID                `json:"id"`
-	AuthorID     uuid.UUID                `json:"author_id"`
-	AuthorName   string                   `json:"author_name"`
-	AuthorAvatar string                   `json:"author_avatar"`
-	Title        string                   `json:"title"`
-	Description  string                   `json:"description"`
-	Settings     domain.CrosshairSettings `json:"settings"`
-	LikesCount   int                      `json:"likes_count"`
-	IsPublic     bool                     `json:"is_public"`
-	ViewCount    int                      `json:"view_count"`
-	CreatedAt    time.Time                `json:"created_at"`
-	UpdatedAt    time.Time                `json:"updated_at"`
+	ID            uuid.UUID                `json:"id"`
+	AuthorID      uuid.UUID                `json:"author_id"`
+	AuthorName    string                   `json:"author_name"`
+	AuthorAvatar  string                   `json:"author_avatar"`
+	Title         string                   `json:"title"`
+	Description   string                   `json:"description"`
+	Settings      domain.CrosshairSettings `json:"settings"`
+	LikesCount    int                      `json:"likes_count"`
+	IsPublic      bool                     `json:"is_public"`
+	ViewCount     int                      `json:"view_count"`
+	LikedByMe     bool                     `json:"liked_by_me"`
+	ForkedFrom    *uuid.UUID               `json:"forked_from,omitempty"`
+	ForksCount    int                      `json:"forks_count"`
+	CommentsCount int                      `json:"comments_count"`
+	CreatedAt     time.Time                `json:"created_at"`
+	UpdatedAt     time.Time                `json:"updated_at"`
 }
 
 func (s *CrosshairService) Create(authorID uuid.UUID, req *CreateCrosshairRequest) (*CrosshairResponse, error) {
@@ -60,59 +83,213 @@ func (s *CrosshairService) Create(authorID uuid.UUID, req *CreateCrosshairReques
 	return toCrosshairResponse(crosshair), nil
 }
 
-func (s *CrosshairService) GetByID(id uuid.UUID) (*CrosshairResponse, error) {
-	crosshair, err := s.repo.GetByID(id)
+// GetByID returns a crosshair. viewerID is uuid.Nil for anonymous viewers;
+// private crosshairs are only found by their author.
+func (s *CrosshairService) GetByID(id, viewerID uuid.UUID) (*CrosshairResponse, error) {
+	crosshair, err := s.getVisible(id, viewerID)
 	if err != nil {
 		return nil, err
 	}
-	return toCrosshairResponse(crosshair), nil
+	resp := []CrosshairResponse{*toCrosshairResponse(crosshair)}
+	if err := s.markLiked(viewerID, resp); err != nil {
+		return nil, err
+	}
+	return &resp[0], nil
 }
 
-func (s *CrosshairService) GetAll(page, limit int) ([]CrosshairResponse, error) {
-	crosshairs, err := s.repo.GetAll(page, limit)
+// GetByAuthorID lists an author's crosshairs, private ones only for the
+// author.
+func (s *CrosshairService) GetByAuthorID(authorID uuid.UUID, limit int, viewerID uuid.UUID) ([]CrosshairResponse, error) {
+	crosshairs, err := s.repo.GetByAuthorID(authorID, viewerID == authorID, limit)
 	if err != nil {
 		return nil, err
 	}
+	return s.toCrosshairResponses(crosshairs, viewerID)
+}
+
+// GetLiked lists the crosshairs a user liked, most recently liked first.
+func (s *CrosshairService) GetLiked(userID uuid.UUID, page, limit int) ([]CrosshairResponse, int64, error) {
+	crosshairs, err := s.repo.GetLikedByUser(userID, page, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	total, err := s.repo.CountLikedByUser(userID)
+	if err != nil {
+		return nil, 0, err
+	}
+	resp := make([]CrosshairResponse, len(crosshairs))
+	for i, c := range crosshairs {
+		resp[i] = *toCrosshairResponse(&c)
+		resp[i].LikedByMe = true
+	}
+	return resp, total, nil
+}
+
+func (s *CrosshairService) toCrosshairResponses(crosshairs []domain.Crosshair, viewerID uuid.UUID) ([]CrosshairResponse, error) {
 	resp := make([]CrosshairResponse, len(crosshairs))
 	for i, c := range crosshairs {
 		resp[i] = *toCrosshairResponse(&c)
 	}
+	if err := s.markLiked(viewerID, resp); err != nil {
+		return nil, err
+	}
 	return resp, nil
 }
 
-func (s *CrosshairService) Count() (int64, error) {
-	return s.repo.Count()
+// markLiked sets LikedByMe with a single query for the whole page.
+func (s *CrosshairService) markLiked(viewerID uuid.UUID, crosshairs []CrosshairResponse) error {
+	if viewerID == uuid.Nil || len(crosshairs) == 0 {
+		return nil
+	}
+	ids := make([]uuid.UUID, len(crosshairs))
+	for i, c := range crosshairs {
+		ids[i] = c.ID
+	}
+	liked, err := s.repo.LikedCrosshairIDs(viewerID, ids)
+	if err != nil {
+		return err
+	}
+	for i := range crosshairs {
+		crosshairs[i].LikedByMe = liked[crosshairs[i].ID]
+	}
+	return nil
 }
 
-func (s *CrosshairService) GetByAuthorID(authorID uuid.UUID, limit int) ([]CrosshairResponse, error) {
-	crosshairs, err := s.repo.GetByAuthorID(authorID, limit)
+// Update replaces the editable fields of an author's crosshair. A request
+// that changes nothing does not create a revision.
+func (s *CrosshairService) Update(id, authorID uuid.UUID, req *UpdateCrosshairRequest) (*CrosshairResponse, error) {
+	crosshair, err := s.getOwned(id, authorID)
 	if err != nil {
 		return nil, err
 	}
-	resp := make([]CrosshairResponse, len(crosshairs))
-	for i, c := range crosshairs {
-		resp[i] = *toCrosshairResponse(&c)
+
+	if crosshair.Title == req.Title && crosshair.Description == req.Description &&
+		crosshair.IsPublic == req.IsPublic && decodeCrosshairSettings(crosshair.Settings) == req.Settings {
+		return toCrosshairResponse(crosshair), nil
+	}
+
+	settingsJSON, err := json.Marshal(req.Settings)
+	if err != nil {
+		return nil, err
+	}
+
+	crosshair.Title = req.Title
+	crosshair.Description = req.Description
+	crosshair.Settings = settingsJSON
+	crosshair.IsPublic = req.IsPublic
+	if err := s.repo.Update(crosshair); err != nil {
+		return nil, err
+	}
+	return toCrosshairResponse(crosshair), nil
+}
+
+// GetRevisions lists the previous versions of an author's crosshair, newest
+// first.
+func (s *CrosshairService) GetRevisions(id, authorID uuid.UUID) ([]CrosshairRevisionResponse, error) {
+	if _, err := s.getOwned(id, authorID); err != nil {
+		return nil, err
+	}
+
+	revisions, err := s.repo.GetRevisions(id)
+	if err != nil {
+		return nil, err
+	}
+	resp := make([]CrosshairRevisionResponse, len(revisions))
+	for i, r := range revisions {
+		resp[i] = CrosshairRevisionResponse{
+			ID:          r.ID,
+			CrosshairID: r.CrosshairID,
+			Title:       r.Title,
+			Description: r.Description,
+			Settings:    decodeCrosshairSettings(r.Settings),
+			IsPublic:    r.IsPublic,
+			CreatedAt:   r.CreatedAt,
+		}
 	}
 	return resp, nil
 }
 
-func (s *CrosshairService) Like(crosshairID, userID uuid.UUID) error {
-	return s.repo.Like(crosshairID, userID)
+// RestoreRevision makes a previous version current again. The version being
+// replaced becomes a revision itself, so a restore can be undone.
+func (s *CrosshairService) RestoreRevision(id, revisionID, authorID uuid.UUID) (*CrosshairResponse, error) {
+	if _, err := s.getOwned(id, authorID); err != nil {
+		return nil, err
+	}
+
+	revision, err := s.repo.GetRevision(id, revisionID)
+	if err != nil {
+		return nil, cErrors.ErrRevisionNotFound
+	}
+
+	return s.Update(id, authorID, &UpdateCrosshairRequest{
+		Title:       revision.Title,
+		Description: revision.Description,
+		Settings:    decodeCrosshairSettings(revision.Settings),
+		IsPublic:    revision.IsPublic,
+	})
 }
 
-func (s *CrosshairService) Unlike(crosshairID, userID uuid.UUID) error {
-	return s.repo.Unlike(crosshairID, userID)
+func (s *CrosshairService) getVisible(id, viewerID uuid.UUID) (*domain.Crosshair, error) {
+	crosshair, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, cErrors.ErrCrosshairNotFound
+	}
+	if !crosshair.IsPublic && crosshair.AuthorID != viewerID {
+		return nil, cErrors.ErrCrosshairNotFound
+	}
+	return crosshair, nil
+}
+
+func (s *CrosshairService) getOwned(id, authorID uuid.UUID) (*domain.Crosshair, error) {
+	crosshair, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, cErrors.ErrCrosshairNotFound
+	}
+	if crosshair.AuthorID != authorID {
+		return nil, cErrors.ErrCrosshairForbidden
+	}
+	return crosshair, nil
+}
+
+// Like is idempotent: liking a crosshair twice counts once.
+func (s *CrosshairService) Like(crosshairID, userID uuid.UUID) (*CrosshairResponse, error) {
+	return s.changeLike(crosshairID, userID, true, s.repo.Like)
+}
+
+// Unlike is idempotent: unliking a crosshair that is not liked is a no-op.
+func (s *CrosshairService) Unlike(crosshairID, userID uuid.UUID) (*CrosshairResponse, error) {
+	return s.changeLike(crosshairID, userID, false, s.repo.Unlike)
+}
+
+func (s *CrosshairService) changeLike(crosshairID, userID uuid.UUID, liked bool, change func(crosshairID, userID uuid.UUID) (int, error)) (*CrosshairResponse, error) {
+	crosshair, err := s.getVisible(crosshairID, userID)
+	if err != nil {
+		return nil, err
+	}
+	likesCount, err := change(crosshairID, userID)
+	if err != nil {
+		return nil, err
+	}
+	crosshair.LikesCount = likesCount
+	resp := toCrosshairResponse(crosshair)
+	resp.LikedByMe = liked
+	return resp, nil
 }
 
 func (s *CrosshairService) Delete(id, authorID uuid.UUID) error {
 	return s.repo.Delete(id, authorID)
 }
 
-func toCrosshairResponse(c *domain.Crosshair) *CrosshairResponse {
+func decodeCrosshairSettings(raw json.RawMessage) domain.CrosshairSettings {
 	var settings domain.CrosshairSettings
-	if c.Settings != nil {
-		json.Unmarshal(c.Settings, &settings)
+	if raw != nil {
+		json.Unmarshal(raw, &settings)
 	}
+	return settings
+}
+
+func toCrosshairResponse(c *domain.Crosshair) *CrosshairResponse {
+	settings := decodeCrosshairSettings(c.Settings)
 
 	authorName := ""
 	authorAvatar := ""
@@ -122,17 +299,20 @@ func toCrosshairResponse(c *domain.Crosshair) *CrosshairResponse {
 	}
 
 	return &CrosshairResponse{
-		ID:           c.ID,
-		AuthorID:     c.AuthorID,
-		AuthorName:   authorName,
-		AuthorAvatar: authorAvatar,
-		Title:        c.Title,
-		Description:  c.Description,
-		Settings:     settings,
-		LikesCount:   c.LikesCount,
-		IsPublic:     c.IsPublic,
-		ViewCount:    c.ViewCount,
-		CreatedAt:    c.CreatedAt,
-		UpdatedAt:    c.UpdatedAt,
+		ID:            c.ID,
+		AuthorID:      c.AuthorID,
+		AuthorName:    authorName,
+		AuthorAvatar:  authorAvatar,
+		Title:         c.Title,
+		Description:   c.Description,
+		Settings:      settings,
+		LikesCount:    c.LikesCount,
+		IsPublic:      c.IsPublic,
+		ViewCount:     c.ViewCount,
+		ForkedFrom:    c.ForkedFrom,
+		ForksCount:    c.ForksCount,
+		CommentsCount: c.CommentsCount,
+		CreatedAt:     c.CreatedAt,
+		UpdatedAt:     c.UpdatedAt,
 	}
 }
@@ -0,0 +1,232 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/quenyu/deadlock-stats/internal/domain"
+	"github.com/quenyu/deadlock-stats/internal/repositories"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+const (
+	crosshairViewQueueSize      = 1024
+	crosshairViewBatchSize      = 200
+	crosshairViewRecordInterval = time.Second
+	crosshairViewFlushInterval  = 30 * time.Second
+
+	// A viewer counts once per crosshair within this window.
+	crosshairViewDedupWindow = 6 * time.Hour
+
+	crosshairViewSeenPrefix   = "crosshair-view:"
+	crosshairViewsPendingKey  = "crosshair-views:pending"
+	crosshairViewsFlushingKey = "crosshair-views:flushing"
+)
+
+// countCrosshairView marks the viewer as seen and, if they were not seen
+// within the window, counts the view in the pending hash.
+var countCrosshairView = redis.NewScript(`
+if redis.call('SET', KEYS[1], '1', 'NX', 'EX', ARGV[1]) then
+	return redis.call('HINCRBY', KEYS[2], ARGV[2], 1)
+end
+return 0
+`)
+
+// crawlerAgents mark user agents that never count as views.
+var crawlerAgents = []string{"bot", "crawler", "spider", "curl", "wget", "python-requests", "go-http-client"}
+
+type crosshairViewEvent struct {
+	crosshairID uuid.UUID
+	viewer      string
+	at          time.Time
+}
+
+// CrosshairViews counts crosshair page views. Views are deduplicated per
+// viewer in Redis and kept in a pending hash there; Start moves the pending
+// counts to Postgres in batches. Recording never blocks the request: views
+// are dropped when the queue is full.
+type CrosshairViews struct {
+	redisClient *redis.Client
+	repo        *repositories.CrosshairRepository
+	logger      *zap.Logger
+
+	// secret keys the hash of anonymous viewers' IP addresses so that
+	// they are not stored in the clear.
+	secret []byte
+	events chan crosshairViewEvent
+}
+
+func NewCrosshairViews(redisClient *redis.Client, repo *repositories.CrosshairRepository, secret string, logger *zap.Logger) *CrosshairViews {
+	return &CrosshairViews{
+		redisClient: redisClient,
+		repo:        repo,
+		logger:      logger.Named("CrosshairViews"),
+		secret:      []byte(secret),
+		events:      make(chan crosshairViewEvent, crosshairViewQueueSize),
+	}
+}
+
+// RecordView queues a view by a signed in user, or by an anonymous viewer
+// identified by IP address when userID is uuid.Nil. Crawlers are ignored.
+func (v *CrosshairViews) RecordView(crosshairID, userID uuid.UUID, ip, userAgent string) {
+	agent := strings.ToLower(userAgent)
+	if agent == "" {
+		return
+	}
+	for _, crawler := range crawlerAgents {
+		if strings.Contains(agent, crawler) {
+			return
+		}
+	}
+
+	viewer := "u:" + userID.String()
+	if userID == uuid.Nil {
+		if ip == "" {
+			return
+		}
+		mac := hmac.New(sha256.New, v.secret)
+		mac.Write([]byte(ip))
+		viewer = "ip:" + hex.EncodeToString(mac.Sum(nil)[:16])
+	}
+
+	select {
+	case v.events <- crosshairViewEvent{crosshairID: crosshairID, viewer: viewer, at: time.Now()}:
+	default:
+		v.logger.Debug("Crosshair view queue is full, dropping view")
+	}
+}
+
+// Start counts queued views until ctx is cancelled, then counts what is
+// left and flushes the pending views.
+func (v *CrosshairViews) Start(ctx context.Context) {
+	recordTicker := time.NewTicker(crosshairViewRecordInterval)
+	defer recordTicker.Stop()
+	flushTicker := time.NewTicker(crosshairViewFlushInterval)
+	defer flushTicker.Stop()
+
+	batch := make([]crosshairViewEvent, 0, crosshairViewBatchSize)
+	for {
+		select {
+		case <-ctx.Done():
+		drain:
+			for {
+				select {
+				case event := <-v.events:
+					batch = append(batch, event)
+				default:
+					break drain
+				}
+			}
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			v.record(shutdownCtx, batch)
+			v.flush(shutdownCtx)
+			cancel()
+			return
+		case event := <-v.events:
+			batch = append(batch, event)
+			if len(batch) >= crosshairViewBatchSize {
+				v.record(ctx, batch)
+				batch = batch[:0]
+			}
+		case <-recordTicker.C:
+			if len(batch) > 0 {
+				v.record(ctx, batch)
+				batch = batch[:0]
+			}
+		case <-flushTicker.C:
+			v.flush(ctx)
+		}
+	}
+}
+
+// record deduplicates the batch and adds the new views to the pending hash,
+// keyed by crosshair and UTC day.
+func (v *CrosshairViews) record(ctx context.Context, batch []crosshairViewEvent) {
+	if len(batch) == 0 {
+		return
+	}
+
+	window := strconv.Itoa(int(crosshairViewDedupWindow.Seconds()))
+	pipe := v.redisClient.Pipeline()
+	for _, event := range batch {
+		seenKey := crosshairViewSeenPrefix + event.crosshairID.String() + ":" + event.viewer
+		field := event.crosshairID.String() + ":" + analyticsDay(event.at)
+		countCrosshairView.Eval(ctx, pipe, []string{seenKey, crosshairViewsPendingKey}, window, field)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		v.logger.Warn("Failed to record crosshair views", zap.Int("views", len(batch)), zap.Error(err))
+	}
+}
+
+// flush moves the pending views to Postgres. The hash is renamed first so
+// that views recorded meanwhile go to a fresh one; if the write fails the
+// renamed hash is retried on the next flush.
+func (v *CrosshairViews) flush(ctx context.Context) {
+	exists, err := v.redisClient.Exists(ctx, crosshairViewsFlushingKey).Result()
+	if err != nil {
+		v.logger.Warn("Failed to flush crosshair views", zap.Error(err))
+		return
+	}
+	if exists == 0 {
+		err := v.redisClient.Rename(ctx, crosshairViewsPendingKey, crosshairViewsFlushingKey).Err()
+		if err != nil {
+			// Nothing is pending
+			if !strings.Contains(err.Error(), "no such key") {
+				v.logger.Warn("Failed to flush crosshair views", zap.Error(err))
+			}
+			return
+		}
+	}
+
+	pending, err := v.redisClient.HGetAll(ctx, crosshairViewsFlushingKey).Result()
+	if err != nil {
+		v.logger.Warn("Failed to read pending crosshair views", zap.Error(err))
+		return
+	}
+
+	views := make([]domain.CrosshairDailyViews, 0, len(pending))
+	for field, value := range pending {
+		view, err := parsePendingCrosshairViews(field, value)
+		if err != nil {
+			v.logger.Warn("Skipping malformed pending crosshair views", zap.String("field", field), zap.Error(err))
+			continue
+		}
+		views = append(views, view)
+	}
+
+	if err := v.repo.AddViews(views); err != nil {
+		v.logger.Error("Failed to store crosshair views", zap.Int("rows", len(views)), zap.Error(err))
+		return
+	}
+	if err := v.redisClient.Del(ctx, crosshairViewsFlushingKey).Err(); err != nil {
+		v.logger.Warn("Failed to clear flushed crosshair views", zap.Error(err))
+	}
+}
+
+func parsePendingCrosshairViews(field, value string) (domain.CrosshairDailyViews, error) {
+	id, day, ok := strings.Cut(field, ":")
+	if !ok {
+		return domain.CrosshairDailyViews{}, errors.New("missing day")
+	}
+	crosshairID, err := uuid.Parse(id)
+	if err != nil {
+		return domain.CrosshairDailyViews{}, err
+	}
+	at, err := time.Parse("20060102", day)
+	if err != nil {
+		return domain.CrosshairDailyViews{}, err
+	}
+	count, err := strconv.Atoi(value)
+	if err != nil {
+		return domain.CrosshairDailyViews{}, err
+	}
+	return domain.CrosshairDailyViews{CrosshairID: crosshairID, Day: at, Views: count}, nil
+}
@@ -0,0 +1,203 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/quenyu/deadlock-stats/internal/clients/deadlockapi"
+	"github.com/quenyu/deadlock-stats/internal/dto"
+	"go.uber.org/zap"
+)
+
+const (
+	deadlockActivityKeyPrefix = "deadlock-activity:"
+	deadlockActivityTTL       = 6 * time.Hour
+	deadlockInactivityTTL     = time.Hour
+
+	// Players that are neither cached nor stored locally are probed through
+	// the Deadlock API. Probes that outlive the wait still fill the cache.
+	maxDeadlockActivityProbes     = 20
+	deadlockActivityProbeParallel = 5
+	deadlockActivityProbeWait     = 3 * time.Second
+)
+
+// markDeadlockActivity sets IsDeadlockPlayer on every result whose status is
+// not known yet. Results that stay unknown keep DeadlockStatusKnown false.
+func (s *PlayerSearchService) markDeadlockActivity(ctx context.Context, results []dto.UserSearchResult) {
+	var steamIDs []string
+	for _, result := range results {
+		if !result.DeadlockStatusKnown && result.SteamID != "" {
+			steamIDs = append(steamIDs, result.SteamID)
+		}
+	}
+	if len(steamIDs) == 0 {
+		return
+	}
+
+	activity := s.deadlockActivity(ctx, steamIDs)
+	for i := range results {
+		if results[i].DeadlockStatusKnown {
+			continue
+		}
+		if active, ok := activity[results[i].SteamID]; ok {
+			results[i].IsDeadlockPlayer = active
+			results[i].DeadlockStatusKnown = true
+		}
+	}
+}
+
+// deadlockActivity looks the players up in Redis, then in one database query
+// and finally through a bounded number of parallel Deadlock API probes. IDs
+// missing from the result could not be determined.
+func (s *PlayerSearchService) deadlockActivity(ctx context.Context, steamIDs []string) map[string]bool {
+	steamIDs = uniqueStrings(steamIDs)
+	activity := make(map[string]bool, len(steamIDs))
+
+	keys := make([]string, len(steamIDs))
+	for i, steamID := range steamIDs {
+		keys[i] = deadlockActivityKeyPrefix + steamID
+	}
+
+	cached, err := s.redisClient.MGet(ctx, keys...).Result()
+	if err != nil {
+		s.logger.Debug("Failed to read cached Deadlock activity", zap.Error(err))
+		cached = make([]interface{}, len(steamIDs))
+	}
+
+	var missing []string
+	for i, steamID := range steamIDs {
+		if value, ok := cached[i].(string); ok {
+			activity[steamID] = value == "true"
+			continue
+		}
+		missing = append(missing, steamID)
+	}
+	if len(missing) == 0 {
+		return activity
+	}
+
+	local, err := s.playerProfileRepository.DeadlockActivity(ctx, missing)
+	if err != nil {
+		s.logger.Warn("Error checking local Deadlock activity", zap.Int("players", len(missing)), zap.Error(err))
+		local = map[string]bool{}
+	}
+	s.cacheDeadlockActivity(ctx, local)
+
+	var unknown []string
+	for _, steamID := range missing {
+		if active, ok := local[steamID]; ok {
+			activity[steamID] = active
+			continue
+		}
+		unknown = append(unknown, steamID)
+	}
+
+	for steamID, active := range s.probeDeadlockActivity(unknown) {
+		activity[steamID] = active
+	}
+
+	return activity
+}
+
+// probeDeadlockActivity asks the Deadlock API about at most
+// maxDeadlockActivityProbes players and returns what is answered within
+// deadlockActivityProbeWait.
+func (s *PlayerSearchService) probeDeadlockActivity(steamIDs []string) map[string]bool {
+	activity := make(map[string]bool)
+	if len(steamIDs) > maxDeadlockActivityProbes {
+		steamIDs = steamIDs[:maxDeadlockActivityProbes]
+	}
+	if len(steamIDs) == 0 {
+		return activity
+	}
+
+	type probeResult struct {
+		steamID string
+		active  bool
+		ok      bool
+	}
+
+	// Buffered so that probes finishing after the wait do not block
+	answers := make(chan probeResult, len(steamIDs))
+	slots := make(chan struct{}, deadlockActivityProbeParallel)
+
+	for _, steamID := range steamIDs {
+		go func(steamID string) {
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			active, ok := s.probeDeadlockPlayer(steamID)
+			if ok {
+				s.cacheDeadlockActivity(context.Background(), map[string]bool{steamID: active})
+			}
+			answers <- probeResult{steamID: steamID, active: active, ok: ok}
+		}(steamID)
+	}
+
+	timeout := time.NewTimer(deadlockActivityProbeWait)
+	defer timeout.Stop()
+
+	for range steamIDs {
+		select {
+		case answer := <-answers:
+			if answer.ok {
+				activity[answer.steamID] = answer.active
+			}
+		case <-timeout.C:
+			return activity
+		}
+	}
+
+	return activity
+}
+
+// probeDeadlockPlayer reports whether the player has hero stats. The second
+// value is false when the API could not answer.
+func (s *PlayerSearchService) probeDeadlockPlayer(steamID string) (bool, bool) {
+	heroStats, err := s.deadlockAPIClient.FetchHeroStats(steamID)
+	if err != nil {
+		if errors.Is(err, deadlockapi.ErrNotFound) {
+			return false, true
+		}
+		s.logger.Debug("Deadlock activity probe failed", zap.String("steamID", steamID), zap.Error(err))
+		return false, false
+	}
+
+	for _, heroStat := range heroStats {
+		if heroStat.Matches > 0 {
+			return true, true
+		}
+	}
+	return false, true
+}
+
+func (s *PlayerSearchService) cacheDeadlockActivity(ctx context.Context, activity map[string]bool) {
+	if len(activity) == 0 {
+		return
+	}
+
+	pipe := s.redisClient.Pipeline()
+	for steamID, active := range activity {
+		if active {
+			pipe.Set(ctx, deadlockActivityKeyPrefix+steamID, "true", deadlockActivityTTL)
+		} else {
+			pipe.Set(ctx, deadlockActivityKeyPrefix+steamID, "false", deadlockInactivityTTL)
+		}
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		s.logger.Debug("Failed to cache Deadlock activity", zap.Error(err))
+	}
+}
+
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	unique := make([]string, 0, len(values))
+	for _, value := range values {
+		if !seen[value] {
+			seen[value] = true
+			unique = append(unique, value)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,176 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const maxKeyValuesDepth = 32
+
+type kvToken struct {
+	text   string
+	quoted bool
+}
+
+// parseKeyValues parses Valve KeyValues text into nested maps of strings.
+// KeyValues has no arrays: objects keyed 0, 1, 2... become slices, and
+// empty objects become nil so that they decode as either.
+func parseKeyValues(input string) (map[string]interface{}, error) {
+	tokens, err := tokenizeKeyValues(input)
+	if err != nil {
+		return nil, err
+	}
+	p := &kvParser{tokens: tokens}
+	values, err := p.object(0)
+	if err != nil {
+		return nil, err
+	}
+	normalized, _ := normalizeKeyValues(values).(map[string]interface{})
+	if normalized == nil {
+		return nil, errors.New("no values")
+	}
+	return normalized, nil
+}
+
+type kvParser struct {
+	tokens []kvToken
+	pos    int
+}
+
+// object reads key-value pairs up to the closing brace, or to the end of
+// the input at the top level.
+func (p *kvParser) object(depth int) (map[string]interface{}, error) {
+	if depth > maxKeyValuesDepth {
+		return nil, errors.New("nested too deeply")
+	}
+	values := make(map[string]interface{})
+	for {
+		if p.pos == len(p.tokens) {
+			if depth > 0 {
+				return nil, errors.New("missing closing brace")
+			}
+			return values, nil
+		}
+		key := p.tokens[p.pos]
+		p.pos++
+		if !key.quoted && key.text == "}" {
+			if depth == 0 {
+				return nil, errors.New("unexpected closing brace")
+			}
+			return values, nil
+		}
+		if !key.quoted && key.text == "{" {
+			return nil, errors.New("unexpected opening brace")
+		}
+
+		if p.pos == len(p.tokens) {
+			return nil, fmt.Errorf("missing value for %q", key.text)
+		}
+		value := p.tokens[p.pos]
+		p.pos++
+		switch {
+		case !value.quoted && value.text == "{":
+			child, err := p.object(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			values[key.text] = child
+		case !value.quoted && value.text == "}":
+			return nil, fmt.Errorf("missing value for %q", key.text)
+		default:
+			values[key.text] = value.text
+		}
+	}
+}
+
+func tokenizeKeyValues(input string) ([]kvToken, error) {
+	var tokens []kvToken
+	for i := 0; i < len(input); {
+		switch c := input[i]; {
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+			i++
+		case strings.HasPrefix(input[i:], "//"):
+			end := strings.IndexByte(input[i:], '\n')
+			if end < 0 {
+				return tokens, nil
+			}
+			i += end + 1
+		case c == '{' || c == '}':
+			tokens = append(tokens, kvToken{text: string(c)})
+			i++
+		case c == '"':
+			var text strings.Builder
+			i++
+			for {
+				if i == len(input) {
+					return nil, errors.New("unterminated string")
+				}
+				if input[i] == '"' {
+					i++
+					break
+				}
+				if input[i] == '\\' && i+1 < len(input) {
+					i++
+					switch input[i] {
+					case 'n':
+						text.WriteByte('\n')
+					case 't':
+						text.WriteByte('\t')
+					default:
+						text.WriteByte(input[i])
+					}
+					i++
+					continue
+				}
+				text.WriteByte(input[i])
+				i++
+			}
+			tokens = append(tokens, kvToken{text: text.String(), quoted: true})
+		default:
+			start := i
+			for i < len(input) && !strings.ContainsRune(" \t\r\n{}\"", rune(input[i])) {
+				i++
+			}
+			tokens = append(tokens, kvToken{text: input[start:i]})
+		}
+	}
+	return tokens, nil
+}
+
+func normalizeKeyValues(value interface{}) interface{} {
+	object, ok := value.(map[string]interface{})
+	if !ok {
+		return value
+	}
+	if len(object) == 0 {
+		return nil
+	}
+
+	indexes := make([]int, 0, len(object))
+	for key := range object {
+		index, err := strconv.Atoi(key)
+		if err != nil || index < 0 {
+			indexes = nil
+			break
+		}
+		indexes = append(indexes, index)
+	}
+	if indexes != nil {
+		sort.Ints(indexes)
+		if indexes[len(indexes)-1] == len(indexes)-1 {
+			list := make([]interface{}, len(indexes))
+			for _, index := range indexes {
+				list[index] = normalizeKeyValues(object[strconv.Itoa(index)])
+			}
+			return list
+		}
+	}
+
+	for key, child := range object {
+		object[key] = normalizeKeyValues(child)
+	}
+	return object
+}
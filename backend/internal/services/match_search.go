@@ -0,0 +1,244 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/quenyu/deadlock-stats/internal/clients/deadlockapi"
+	"github.com/quenyu/deadlock-stats/internal/domain"
+	"github.com/quenyu/deadlock-stats/internal/dto"
+	cErrors "github.com/quenyu/deadlock-stats/internal/errors"
+	"go.uber.org/zap"
+)
+
+// matchIDPattern matches Deadlock match IDs. They are much shorter than a
+// SteamID64 (17 digits), but the same length as 32-bit account IDs: a bare
+// number is first looked up as the account ID of a tracked player, then as
+// a match, before the query falls back to the regular player search.
+var matchIDPattern = regexp.MustCompile(`^[1-9][0-9]{5,9}$`)
+
+func parseMatchID(value string) (int64, bool) {
+	if !matchIDPattern.MatchString(value) {
+		return 0, false
+	}
+	matchID, err := strconv.ParseInt(value, 10, 64)
+	return matchID, err == nil
+}
+
+// matchSearchResult is the cached outcome of a match lookup. Misses are
+// cached too so numeric nicknames do not hit the Deadlock API every time.
+type matchSearchResult struct {
+	Found   bool                   `json:"found"`
+	Results []dto.UserSearchResult `json:"results"`
+}
+
+// matchSearch resolves queries that refer to a match, or to a tracked player
+// by account ID. It reports false when the query should go through the
+// regular player search instead; the match: operator and searchType "match"
+// are always looked up as a match and never fall back.
+func (s *PlayerSearchService) matchSearch(ctx context.Context, parsed *ParsedSearchQuery, searchType string) ([]dto.UserSearchResult, bool, error) {
+	explicit := parsed.Filters.SearchType == "match" || searchType == "match"
+	if !explicit && (parsed.IsStructured() || searchType == "steamid") {
+		return nil, false, nil
+	}
+
+	matchID, ok := parseMatchID(parsed.Text)
+	if !ok {
+		if explicit {
+			return nil, false, fmt.Errorf("%w: %q is not a match ID", cErrors.ErrInvalidQuery, parsed.Text)
+		}
+		return nil, false, nil
+	}
+
+	if !explicit {
+		if user := s.trackedAccount(parsed.Text); user != nil {
+			return []dto.UserSearchResult{userSearchResult(*user)}, true, nil
+		}
+	}
+
+	results, found, err := s.searchByMatchID(ctx, matchID)
+	if err != nil {
+		return nil, false, err
+	}
+	if !found {
+		if explicit {
+			return nil, false, cErrors.ErrMatchNotFound
+		}
+		return nil, false, nil
+	}
+
+	return results, true, nil
+}
+
+// matchPagePath is the frontend route of a match, relative to the client
+// URL like the other routes in frontend/src/shared/constants/routes.ts.
+func matchPagePath(matchID int64) string {
+	return fmt.Sprintf("/matches/%d", matchID)
+}
+
+// trackedAccount returns the stored user whose 32-bit account ID is value.
+func (s *PlayerSearchService) trackedAccount(value string) *domain.User {
+	steamID, ok := ParseSteamID(value)
+	if !ok {
+		return nil
+	}
+	user, err := s.userRepository.FindBySteamID(steamID)
+	if err != nil {
+		s.logger.Warn("Failed to look up account ID", zap.String("steamID", steamID), zap.Error(err))
+		return nil
+	}
+	return user
+}
+
+// searchByMatchID lists the participants of a match. The Deadlock API knows
+// every player; the local tables only hold tracked users and are used when
+// the API does not have the match or cannot be reached.
+func (s *PlayerSearchService) searchByMatchID(ctx context.Context, matchID int64) ([]dto.UserSearchResult, bool, error) {
+	cacheKey := searchCacheKey("match", strconv.FormatInt(matchID, 10))
+	var cached matchSearchResult
+	if s.searchCache.Get(ctx, cacheKey, &cached) {
+		return cached.Results, cached.Found, nil
+	}
+
+	results, apiErr := s.apiMatchParticipants(ctx, matchID)
+	if apiErr != nil || len(results) == 0 {
+		if apiErr != nil && !errors.Is(apiErr, deadlockapi.ErrNotFound) {
+			s.logger.Warn("Failed to fetch match from Deadlock API", zap.Int64("matchID", matchID), zap.Error(apiErr))
+		}
+
+		var err error
+		results, err = s.localMatchParticipants(ctx, matchID)
+		if err != nil {
+			return nil, false, err
+		}
+	}
+
+	found := len(results) > 0
+	if found || apiErr == nil || errors.Is(apiErr, deadlockapi.ErrNotFound) {
+		s.searchCache.Set(ctx, cacheKey, matchSearchResult{Found: found, Results: results}, resultSteamIDs(results))
+	}
+
+	return results, found, nil
+}
+
+func (s *PlayerSearchService) apiMatchParticipants(ctx context.Context, matchID int64) ([]dto.UserSearchResult, error) {
+	match, err := s.deadlockAPIClient.FetchMatchMetadata(matchID)
+	if err != nil {
+		return nil, err
+	}
+
+	players := make([]deadlockapi.MatchPlayer, 0, len(match.Players))
+	steamIDs := make([]string, 0, len(match.Players))
+	for _, player := range match.Players {
+		if player.AccountID <= 0 {
+			continue
+		}
+		players = append(players, player)
+		steamIDs = append(steamIDs, s.convertAccountIDToSteamID64(player.AccountID))
+	}
+	sort.SliceStable(players, func(i, j int) bool {
+		if players[i].Team != players[j].Team {
+			return players[i].Team < players[j].Team
+		}
+		return players[i].PlayerSlot < players[j].PlayerSlot
+	})
+
+	users := make(map[string]domain.User, len(steamIDs))
+	knownUsers, err := s.userRepository.FindBySteamIDs(ctx, steamIDs)
+	if err != nil {
+		s.logger.Warn("Failed to load match participants", zap.Int64("matchID", matchID), zap.Error(err))
+	}
+	for _, user := range knownUsers {
+		users[user.SteamID] = user
+	}
+
+	var missing []string
+	for _, steamID := range steamIDs {
+		if _, ok := users[steamID]; !ok {
+			missing = append(missing, steamID)
+		}
+	}
+	if len(missing) > 0 {
+		summaries, err := s.steamClient.GetPlayerSummaries(ctx, missing)
+		if err != nil {
+			s.logger.Warn("Failed to fetch Steam profiles of match participants", zap.Int64("matchID", matchID), zap.Error(err))
+		}
+		for _, summary := range summaries {
+			users[summary.SteamID] = domain.User{
+				SteamID:     summary.SteamID,
+				Nickname:    summary.PersonaName,
+				AvatarURL:   summary.AvatarFull,
+				ProfileURL:  summary.ProfileURL,
+				CountryCode: summary.LocCountryCode,
+			}
+		}
+	}
+
+	matchURL := matchPagePath(matchID)
+	results := make([]dto.UserSearchResult, 0, len(players))
+	for _, player := range players {
+		steamID := s.convertAccountIDToSteamID64(player.AccountID)
+		team := player.Team
+
+		result := matchParticipantResult(users[steamID])
+		result.SteamID = steamID
+		result.AccountID = player.AccountID
+		result.MatchID = strconv.FormatInt(matchID, 10)
+		result.MatchURL = matchURL
+		result.MatchHeroID = player.HeroID
+		result.MatchTeam = &team
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+func (s *PlayerSearchService) localMatchParticipants(ctx context.Context, matchID int64) ([]dto.UserSearchResult, error) {
+	participants, err := s.playerProfileRepository.FindMatchParticipants(ctx, strconv.FormatInt(matchID, 10))
+	if err != nil {
+		return nil, err
+	}
+
+	matchURL := matchPagePath(matchID)
+	results := make([]dto.UserSearchResult, 0, len(participants))
+	for _, participant := range participants {
+		result := matchParticipantResult(participant.User)
+		result.MatchID = strconv.FormatInt(matchID, 10)
+		result.MatchURL = matchURL
+		result.MatchHeroName = participant.HeroName
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// matchParticipantResult fills the profile part of a participant.
+func matchParticipantResult(user domain.User) dto.UserSearchResult {
+	result := userSearchResult(user)
+	result.IsDeadlockPlayer = true
+	result.DeadlockStatusKnown = true
+	return result
+}
+
+// userSearchResult converts a user that may not be stored locally; those
+// have no ID or timestamps.
+func userSearchResult(user domain.User) dto.UserSearchResult {
+	result := dto.UserSearchResult{
+		SteamID:     user.SteamID,
+		Nickname:    user.Nickname,
+		AvatarURL:   user.AvatarURL,
+		ProfileURL:  user.ProfileURL,
+		CountryCode: user.CountryCode,
+	}
+	if user.ID != uuid.Nil {
+		result.ID = user.ID.String()
+		result.CreatedAt = &user.CreatedAt
+		result.UpdatedAt = &user.UpdatedAt
+	}
+	return result
+}
@@ -0,0 +1,144 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/quenyu/deadlock-stats/internal/clients/steam"
+	"github.com/quenyu/deadlock-stats/internal/domain"
+	"github.com/quenyu/deadlock-stats/internal/dto"
+	cErrors "github.com/quenyu/deadlock-stats/internal/errors"
+	"go.uber.org/zap"
+)
+
+// GetPlayerFriends returns the friends of a player that play Deadlock, best
+// ranked first. Rank and win rate are only known for friends tracked
+// locally; friends found through the Deadlock API are listed without them.
+// Only a bounded number of unknown friends is probed per call, the rest is
+// reported through UncheckedCount and Partial.
+func (s *PlayerSearchService) GetPlayerFriends(ctx context.Context, steamID string) (*dto.PlayerFriends, error) {
+	friends, err := s.steamClient.GetFriendList(ctx, steamID)
+	if err != nil {
+		if errors.Is(err, steam.ErrPrivateProfile) {
+			return nil, cErrors.ErrPrivateProfile
+		}
+		s.logger.Warn("Failed to fetch friend list", zap.String("steamID", steamID), zap.Error(err))
+		return nil, fmt.Errorf("%w: %v", cErrors.ErrAPIUnavailable, err)
+	}
+	if len(friends) == 0 {
+		return &dto.PlayerFriends{SteamID: steamID, Results: []dto.PlayerFriend{}}, nil
+	}
+
+	steamIDs := make([]string, len(friends))
+	for i, friend := range friends {
+		steamIDs[i] = friend.SteamID
+	}
+
+	stats, err := s.playerProfileRepository.FindStatsBySteamIDs(ctx, steamIDs)
+	if err != nil {
+		s.logger.Error("Failed to load friend stats", zap.String("steamID", steamID), zap.Error(err))
+		return nil, err
+	}
+
+	known := make(map[string]domain.UserWithStats, len(stats))
+	for _, user := range stats {
+		if user.TotalMatches > 0 {
+			known[user.SteamID] = user
+		}
+	}
+
+	var unknown []string
+	for _, friendID := range steamIDs {
+		if _, ok := known[friendID]; !ok {
+			unknown = append(unknown, friendID)
+		}
+	}
+
+	activity := s.deadlockActivity(ctx, unknown)
+	unchecked := len(unknown) - len(activity)
+
+	var active []string
+	for friendID, isActive := range activity {
+		if isActive {
+			active = append(active, friendID)
+		}
+	}
+
+	profiles := make(map[string]domain.User, len(active))
+	if len(active) > 0 {
+		users, err := s.userRepository.FindBySteamIDs(ctx, active)
+		if err != nil {
+			s.logger.Warn("Failed to load friend profiles", zap.Error(err))
+		}
+		for _, user := range users {
+			profiles[user.SteamID] = user
+		}
+
+		var missing []string
+		for _, friendID := range active {
+			if _, ok := profiles[friendID]; !ok {
+				missing = append(missing, friendID)
+			}
+		}
+		if len(missing) > 0 {
+			summaries, err := s.steamClient.GetPlayerSummaries(ctx, missing)
+			if err != nil {
+				s.logger.Warn("Failed to fetch friend summaries", zap.Error(err))
+			}
+			for _, summary := range summaries {
+				profiles[summary.SteamID] = domain.User{
+					SteamID:     summary.SteamID,
+					Nickname:    summary.PersonaName,
+					AvatarURL:   summary.AvatarFull,
+					ProfileURL:  summary.ProfileURL,
+					CountryCode: summary.LocCountryCode,
+				}
+			}
+		}
+	}
+
+	results := make([]dto.PlayerFriend, 0, len(known)+len(active))
+	for _, friend := range friends {
+		var result dto.UserSearchResult
+		if user, ok := known[friend.SteamID]; ok {
+			result = userSearchResult(user.User)
+			result.PlayerRank = user.PlayerRank
+			result.TotalMatches = user.TotalMatches
+			result.WinRate = user.WinRate
+			result.KDRatio = user.KDRatio
+		} else if user, ok := profiles[friend.SteamID]; ok {
+			result = userSearchResult(user)
+		} else {
+			continue
+		}
+		result.IsDeadlockPlayer = true
+		result.DeadlockStatusKnown = true
+
+		playerFriend := dto.PlayerFriend{UserSearchResult: result}
+		if friend.FriendSince > 0 {
+			friendSince := time.Unix(friend.FriendSince, 0).UTC()
+			playerFriend.FriendSince = &friendSince
+		}
+		results = append(results, playerFriend)
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].PlayerRank != results[j].PlayerRank {
+			return results[i].PlayerRank > results[j].PlayerRank
+		}
+		return strings.ToLower(results[i].Nickname) < strings.ToLower(results[j].Nickname)
+	})
+
+	return &dto.PlayerFriends{
+		SteamID:        steamID,
+		Results:        results,
+		TotalCount:     len(results),
+		FriendCount:    len(friends),
+		UncheckedCount: unchecked,
+		Partial:        unchecked > 0,
+	}, nil
+}
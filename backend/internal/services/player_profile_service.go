@@ -471,6 +471,8 @@ func (s *PlayerProfileService) GetExtendedPlayerProfile(ctx context.Context, ste
 		return nil, err
 	}
 
+	profile.PreviousNicknames = s.fetchPreviousNicknames(ctx, steamID, profile.Nickname)
+
 	s.logger.Info("Profile loaded successfully",
 		zap.String("steamID", steamID),
 		zap.Duration("totalTime", time.Since(start)),
@@ -480,6 +482,32 @@ func (s *PlayerProfileService) GetExtendedPlayerProfile(ctx context.Context, ste
 	return profile, nil
 }
 
+// fetchPreviousNicknames returns the names a player was known by before their
+// current one. It is read on every request so renames show up without
+// waiting for the profile cache to expire.
+func (s *PlayerProfileService) fetchPreviousNicknames(ctx context.Context, steamID, currentNickname string) []domain.NicknameHistory {
+	previous := []domain.NicknameHistory{}
+
+	user, err := s.userRepository.FindBySteamID(steamID)
+	if err != nil || user == nil {
+		return previous
+	}
+
+	history, err := s.userRepository.GetNicknameHistory(ctx, user.ID)
+	if err != nil {
+		s.logger.Warn("Failed to load nickname history", zap.String("steamID", steamID), zap.Error(err))
+		return previous
+	}
+
+	for _, entry := range history {
+		if entry.Nickname != currentNickname && entry.Nickname != user.Nickname {
+			previous = append(previous, entry)
+		}
+	}
+
+	return previous
+}
+
 func (s *PlayerProfileService) buildDomainMatches(matches []deadlockapi.DeadlockMatch, mmrHistory []domain.DeadlockMMR) []domain.Match {
 	s.logger.Info("Building domain matches", zap.Int("apiMatchesCount", len(matches)), zap.Int("mmrHistoryCount", len(mmrHistory)))
 
@@ -535,7 +563,7 @@ func (s *PlayerProfileService) convertToDomainMatches(matches []deadlockapi.Dead
 
 func (s *PlayerProfileService) enrichMatchesWithHeroData(domainMatches []domain.Match) {
 	for i := range domainMatches {
-		if hero, ok := s.staticDataService.HeroesByHeroID[domainMatches[i].HeroID]; ok {
+		if hero, ok := s.staticDataService.GetHero(domainMatches[i].HeroID); ok {
 			domainMatches[i].HeroName = hero.Name
 			if hero.Images.IconHeroCard != nil {
 				domainMatches[i].HeroAvatar = *hero.Images.IconHeroCard
@@ -582,7 +610,7 @@ func (s *PlayerProfileService) findPreviousRank(domainMatches []domain.Match, cu
 
 func (s *PlayerProfileService) enrichHeroStats(heroStats []domain.HeroStat) {
 	for i := range heroStats {
-		if hero, ok := s.staticDataService.HeroesByHeroID[heroStats[i].HeroID]; ok {
+		if hero, ok := s.staticDataService.GetHero(heroStats[i].HeroID); ok {
 			if hero.Name != "" && !strings.HasPrefix(hero.Name, "HeroID_") {
 				heroStats[i].HeroName = hero.Name
 			} else {
@@ -637,7 +665,7 @@ func (s *PlayerProfileService) SearchPlayersWithAutocomplete(ctx context.Context
 	}
 
 	if _, err := strconv.ParseInt(query, 10, 64); err == nil {
-		user, err := s.authService.SearchPlayersBySteamID(query)
+		user, err := s.authService.SearchPlayersBySteamID(ctx, query)
 		if err != nil {
 			s.logger.Error("Error finding user by SteamID64", zap.Error(err), zap.String("steamID", query))
 			return []domain.User{}, nil
@@ -647,9 +675,9 @@ func (s *PlayerProfileService) SearchPlayersWithAutocomplete(ctx context.Context
 		}
 	}
 
-	resolvedSteamID, err := s.authService.ResolveVanityURL(query)
+	resolvedSteamID, err := s.authService.ResolveVanityURL(ctx, query)
 	if err == nil && resolvedSteamID != "" {
-		user, err := s.authService.SearchPlayersBySteamID(resolvedSteamID)
+		user, err := s.authService.SearchPlayersBySteamID(ctx, resolvedSteamID)
 		if err != nil {
 			s.logger.Error("Error finding user by resolved vanity URL", zap.Error(err), zap.String("vanityURL", query), zap.String("resolvedID", resolvedSteamID))
 			return []domain.User{}, nil
@@ -721,7 +749,7 @@ func (s *PlayerProfileService) SearchPlayersWithAutocomplete(ctx context.Context
 
 func (s *PlayerProfileService) searchBySteamID(ctx context.Context, query string) ([]domain.User, error) {
 	if _, err := strconv.ParseInt(query, 10, 64); err == nil {
-		users, err := s.findAndCreateUser(query)
+		users, err := s.findAndCreateUser(ctx, query)
 		if err != nil {
 			s.logger.Error("Error finding user by SteamID64", zap.Error(err), zap.String("steamID", query))
 			return []domain.User{}, nil
@@ -731,9 +759,9 @@ func (s *PlayerProfileService) searchBySteamID(ctx context.Context, query string
 		}
 	}
 
-	resolvedSteamID, err := s.authService.ResolveVanityURL(query)
+	resolvedSteamID, err := s.authService.ResolveVanityURL(ctx, query)
 	if err == nil && resolvedSteamID != "" {
-		users, err := s.findAndCreateUser(resolvedSteamID)
+		users, err := s.findAndCreateUser(ctx, resolvedSteamID)
 		if err != nil {
 			s.logger.Error("Error finding user by resolved vanity URL", zap.Error(err), zap.String("vanityURL", query), zap.String("resolvedID", resolvedSteamID))
 			return []domain.User{}, nil
@@ -744,13 +772,13 @@ func (s *PlayerProfileService) searchBySteamID(ctx context.Context, query string
 	return []domain.User{}, nil
 }
 
-func (s *PlayerProfileService) findAndCreateUser(steamID string) ([]domain.User, error) {
+func (s *PlayerProfileService) findAndCreateUser(ctx context.Context, steamID string) ([]domain.User, error) {
 	localUser, _ := s.userRepository.FindBySteamID(steamID)
 	if localUser != nil {
 		return []domain.User{*localUser}, nil
 	}
 
-	steamUser, err := s.authService.GetPlayerSummaries(steamID)
+	steamUser, err := s.authService.GetPlayerSummaries(ctx, steamID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get player summaries for steamID %s: %w", steamID, err)
 	}
@@ -1073,7 +1101,7 @@ func (s *PlayerProfileService) fillPerformanceData(profile *domain.PlayerProfile
 }
 
 func (s *PlayerProfileService) getRankNameAndSubRank(tier int) (string, int, string) {
-	if r, ok := s.staticDataService.Ranks[tier]; ok {
+	if r, ok := s.staticDataService.GetRank(tier); ok {
 		var img string
 		if r.Images.Large != nil {
 			img = *r.Images.Large
@@ -1084,7 +1112,7 @@ func (s *PlayerProfileService) getRankNameAndSubRank(tier int) (string, int, str
 }
 
 func (s *PlayerProfileService) getRankImageURL(tier, subTier int) string {
-	rank, found := s.staticDataService.Ranks[tier]
+	rank, found := s.staticDataService.GetRank(tier)
 	if !found {
 		return ""
 	}
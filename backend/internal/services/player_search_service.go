@@ -2,45 +2,34 @@ package services
 
 import (
 	"context"
-	"encoding/json"
-	"errors"
 	"fmt"
-	"net/http"
 	"sort"
 	"strconv"
 	"strings"
 	"time"
 
-	"sync"
-
 	"github.com/google/uuid"
 	"github.com/quenyu/deadlock-stats/internal/clients/deadlockapi"
+	"github.com/quenyu/deadlock-stats/internal/clients/steam"
 	"github.com/quenyu/deadlock-stats/internal/domain"
 	"github.com/quenyu/deadlock-stats/internal/dto"
+	cErrors "github.com/quenyu/deadlock-stats/internal/errors"
 	"github.com/quenyu/deadlock-stats/internal/repositories"
 	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
 )
 
-type steamSearchCacheEntry struct {
-	results   []domain.SteamProfileSearch
-	timestamp time.Time
-}
-
-const steamSearchCacheTTL = 5 * time.Minute
-
 type PlayerSearchService struct {
 	playerProfileRepository *repositories.PlayerProfilePostgresRepository
 	userRepository          *repositories.UserRepository
 	authService             *AuthService
 	deadlockAPIClient       *deadlockapi.Client
 	logger                  *zap.Logger
+	searchCache             *SearchCache
 
-	steamSearchCache      map[string]steamSearchCacheEntry
-	steamSearchCacheMutex sync.Mutex
-
-	redisClient *redis.Client
-	steamAPIKey string
+	redisClient     *redis.Client
+	steamClient     *steam.Client
+	steamIDResolver *SteamIDResolver
 }
 
 func NewPlayerSearchService(
@@ -49,7 +38,9 @@ func NewPlayerSearchService(
 	authService *AuthService,
 	deadlockAPIClient *deadlockapi.Client,
 	redisClient *redis.Client,
-	steamAPIKey string,
+	steamClient *steam.Client,
+	steamIDResolver *SteamIDResolver,
+	searchCache *SearchCache,
 	logger *zap.Logger,
 ) *PlayerSearchService {
 	return &PlayerSearchService{
@@ -58,13 +49,15 @@ func NewPlayerSearchService(
 		authService:             authService,
 		deadlockAPIClient:       deadlockAPIClient,
 		redisClient:             redisClient,
-		steamAPIKey:             steamAPIKey,
+		steamClient:             steamClient,
+		steamIDResolver:         steamIDResolver,
 		logger:                  logger,
-		steamSearchCache:        make(map[string]steamSearchCacheEntry),
+		searchCache:             searchCache,
 	}
 }
 
-func (s *PlayerSearchService) SearchPlayers(ctx context.Context, query string, searchType string, page, pageSize int) (*dto.SearchResult, error) {
+func (s *PlayerSearchService) SearchPlayers(ctx context.Context, query string, searchType string, cursor string, page, pageSize int) (*dto.SearchResult, error) {
+	query = normalizeSearchQuery(query)
 	if len(query) < 2 {
 		return &dto.SearchResult{
 			Results:    []dto.UserSearchResult{},
@@ -75,39 +68,38 @@ func (s *PlayerSearchService) SearchPlayers(ctx context.Context, query string, s
 		}, nil
 	}
 
-	if strings.HasPrefix(query, "https://steamcommunity.com/id/") {
-		vanity := strings.TrimPrefix(query, "https://steamcommunity.com/id/")
-		vanity = strings.TrimSuffix(vanity, "/")
-		steamID, err := resolveVanityURL(vanity, s.steamAPIKey)
-		if err == nil && steamID != "" {
-			query = steamID
-		}
+	parsed, err := ParseSearchQuery(query)
+	if err != nil {
+		return nil, err
 	}
-
-	var results []dto.UserSearchResult
-	var err error
-
-	switch searchType {
-	case "steamid":
-		results, err = s.searchBySteamID(ctx, query)
-	case "nickname":
-		results, err = s.searchByNickname(ctx, query)
-	default:
-		localResults, apiResults, err := s.fetchSearchResults(ctx, query)
+	if results, ok, err := s.matchSearch(ctx, parsed, searchType); err != nil || ok {
 		if err != nil {
 			return nil, err
 		}
-		results = s.combineSearchResults(localResults, apiResults)
+		return s.applyPagination(results, page, pageSize), nil
 	}
+	if parsed.IsStructured() {
+		return s.searchWithFilters(ctx, parsed.Text, structuredSearchFilters(parsed, searchType), cursor, page, pageSize)
+	}
+	query = s.resolveSteamQuery(ctx, parsed.Text, false)
 
-	if err != nil {
-		return nil, err
+	if searchType == "steamid" {
+		results, err := s.searchBySteamID(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+		s.markDeadlockActivity(ctx, results)
+		return s.applyPagination(results, page, pageSize), nil
 	}
 
-	return s.applyPagination(results, page, pageSize), nil
+	cacheKey := s.searchCache.nicknameSearchKey(ctx, "players", query, cursor, page, pageSize)
+	return s.cachedSearch(ctx, cacheKey, func() (*dto.SearchResult, error) {
+		return s.pagedNicknameSearch(ctx, query, "relevance", true, cursor, page, pageSize)
+	})
 }
 
 func (s *PlayerSearchService) SearchPlayersWithAutocomplete(ctx context.Context, query string, limit int) ([]dto.UserSearchResult, error) {
+	query = normalizeSearchQuery(query)
 	if len(query) < 2 {
 		return []dto.UserSearchResult{}, nil
 	}
@@ -119,33 +111,39 @@ func (s *PlayerSearchService) SearchPlayersWithAutocomplete(ctx context.Context,
 		limit = 50
 	}
 
-	if strings.HasPrefix(query, "https://steamcommunity.com/id/") {
-		vanity := strings.TrimPrefix(query, "https://steamcommunity.com/id/")
-		vanity = strings.TrimSuffix(vanity, "/")
-		s.logger.Info("Attempting to resolve vanity URL", zap.String("vanity", vanity))
-		steamID, err := resolveVanityURL(vanity, s.steamAPIKey)
-		if err == nil && steamID != "" {
-			s.logger.Info("Successfully resolved vanity URL", zap.String("vanity", vanity), zap.String("steamID", steamID))
-			query = steamID
-		} else {
-			s.logger.Warn("Failed to resolve vanity URL", zap.String("vanity", vanity), zap.Error(err))
+	parsed, err := ParseSearchQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	if results, ok, err := s.matchSearch(ctx, parsed, ""); err != nil || ok {
+		if len(results) > limit {
+			results = results[:limit]
 		}
+		return results, err
 	}
-
-	if !s.isValidSteamID(query) && !strings.Contains(query, " ") {
-		s.logger.Info("Attempting to resolve potential vanity URL", zap.String("query", query))
-		steamID, err := resolveVanityURL(query, s.steamAPIKey)
-		if err == nil && steamID != "" {
-			s.logger.Info("Successfully resolved potential vanity URL", zap.String("query", query), zap.String("steamID", steamID))
-			query = steamID
-		} else {
-			s.logger.Debug("Failed to resolve potential vanity URL", zap.String("query", query), zap.Error(err))
+	if parsed.IsStructured() {
+		result, err := s.searchWithFilters(ctx, parsed.Text, structuredSearchFilters(parsed, ""), "", 1, limit)
+		if err != nil {
+			return nil, err
 		}
+		return result.Results, nil
+	}
+	query = parsed.Text
+	if len(query) < 2 {
+		return []dto.UserSearchResult{}, nil
+	}
+
+	query = s.resolveSteamQuery(ctx, query, true)
+
+	cacheKey := s.searchCache.nicknameSearchKey(ctx, "autocomplete", query, limit)
+	var cached []dto.UserSearchResult
+	if s.searchCache.Get(ctx, cacheKey, &cached) {
+		return cached, nil
 	}
 
 	s.logger.Info("Searching players with autocomplete", zap.String("query", query), zap.Int("limit", limit))
 
-	nicknameResults, err := s.playerProfileRepository.SearchByNicknamePartial(ctx, query, limit)
+	nicknameResults, err := s.playerProfileRepository.SearchByNicknameRanked(ctx, query, limit)
 	if err != nil {
 		s.logger.Error("Error searching by partial nickname", zap.Error(err))
 		return []dto.UserSearchResult{}, err
@@ -157,24 +155,49 @@ func (s *PlayerSearchService) SearchPlayersWithAutocomplete(ctx context.Context,
 		return []dto.UserSearchResult{}, err
 	}
 
-	localResults := append(nicknameResults, steamIDResults...)
+	localResults := append(rankedUsers(nicknameResults), steamIDResults...)
 
-	apiResults, err := s.deadlockAPIClient.FetchSteamProfileSearch(query)
-	if err != nil {
-		s.logger.Warn("Failed to fetch from Deadlock API search", zap.Error(err))
-		apiResults = []domain.SteamProfileSearch{}
-	}
+	apiResults := s.fetchAPISearchResults(ctx, query)
 
 	combined := s.combineSearchResults(localResults, apiResults)
+	applyRelevance(combined, query, rankedScores(nicknameResults))
+	combined = s.appendAliasMatches(ctx, query, combined, limit)
+	s.rankByRelevance(combined)
 
 	if len(combined) > limit {
 		combined = combined[:limit]
 	}
+	s.markDeadlockActivity(ctx, combined)
+
+	s.searchCache.Set(ctx, cacheKey, combined, resultSteamIDs(combined))
 	return combined, nil
 }
 
-func (s *PlayerSearchService) SearchPlayersWithFilters(ctx context.Context, query string, filters dto.SearchFilters, page, pageSize int) (*dto.SearchResult, error) {
-	if len(query) < 2 {
+// SearchPlayersWithFilters combines explicit filters with any operators in the
+// query itself; operators in the query take precedence.
+func (s *PlayerSearchService) SearchPlayersWithFilters(ctx context.Context, query string, filters dto.SearchFilters, cursor string, page, pageSize int) (*dto.SearchResult, error) {
+	parsed, err := ParseSearchQuery(normalizeSearchQuery(query))
+	if err != nil {
+		return nil, err
+	}
+
+	filters.Override(parsed.Filters)
+	if err := filters.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", cErrors.ErrInvalidQuery, err)
+	}
+
+	if results, ok, err := s.matchSearch(ctx, parsed, filters.SearchType); err != nil || ok {
+		if err != nil {
+			return nil, err
+		}
+		return s.applyPagination(results, page, pageSize), nil
+	}
+
+	return s.searchWithFilters(ctx, parsed.Text, filters, cursor, page, pageSize)
+}
+
+func (s *PlayerSearchService) searchWithFilters(ctx context.Context, query string, filters dto.SearchFilters, cursor string, page, pageSize int) (*dto.SearchResult, error) {
+	if len(query) < 2 && !filters.HasStatFilters() {
 		return &dto.SearchResult{
 			Results:    []dto.UserSearchResult{},
 			TotalCount: 0,
@@ -184,37 +207,332 @@ func (s *PlayerSearchService) SearchPlayersWithFilters(ctx context.Context, quer
 		}, nil
 	}
 
-	if strings.HasPrefix(query, "https://steamcommunity.com/id/") {
-		vanity := strings.TrimPrefix(query, "https://steamcommunity.com/id/")
-		vanity = strings.TrimSuffix(vanity, "/")
-		steamID, err := resolveVanityURL(vanity, s.steamAPIKey)
-		if err == nil && steamID != "" {
-			query = steamID
+	query = s.resolveSteamQuery(ctx, query, false)
+
+	cacheKey := s.searchCache.nicknameSearchKey(ctx, "players-filtered", query, filters, cursor, page, pageSize)
+
+	if filters.HasStatFilters() || filters.IsStatSort() {
+		return s.cachedSearch(ctx, cacheKey, func() (*dto.SearchResult, error) {
+			return s.searchWithStats(ctx, query, filters, cursor, page, pageSize)
+		})
+	}
+
+	if filters.GetSearchType() == "steamid" {
+		results, err := s.searchBySteamID(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+		s.markDeadlockActivity(ctx, results)
+		s.sortUsersByFilters(results, filters)
+		return s.applyPagination(results, page, pageSize), nil
+	}
+
+	return s.cachedSearch(ctx, cacheKey, func() (*dto.SearchResult, error) {
+		return s.pagedNicknameSearch(ctx, query, filters.GetDefaultSortBy(), filters.GetDefaultSortOrder() == "desc", cursor, page, pageSize)
+	})
+}
+
+// structuredSearchFilters returns the filters of a structured query, ordered
+// by relevance when there is free text and by rank otherwise.
+func structuredSearchFilters(parsed *ParsedSearchQuery, searchType string) dto.SearchFilters {
+	filters := parsed.Filters
+	if filters.SearchType == "" {
+		filters.SearchType = searchType
+	}
+	if filters.SortBy == "" {
+		filters.SortBy, filters.SortOrder = "rank", "desc"
+		if parsed.Text != "" {
+			filters.SortBy = "relevance"
 		}
 	}
+	return filters
+}
+
+// cachedSearch serves a result page from the search cache and otherwise runs
+// search, tagging the stored page with the players it contains.
+func (s *PlayerSearchService) cachedSearch(ctx context.Context, cacheKey string, search func() (*dto.SearchResult, error)) (*dto.SearchResult, error) {
+	var cached dto.SearchResult
+	if s.searchCache.Get(ctx, cacheKey, &cached) {
+		return &cached, nil
+	}
 
-	var results []dto.UserSearchResult
-	var err error
+	result, err := search()
+	if err != nil {
+		return nil, err
+	}
 
-	switch filters.GetSearchType() {
-	case "steamid":
-		results, err = s.searchBySteamID(ctx, query)
-	case "nickname":
-		results, err = s.searchByNickname(ctx, query)
-	default:
-		localResults, apiResults, err := s.fetchSearchResults(ctx, query)
+	s.searchCache.Set(ctx, cacheKey, result, resultSteamIDs(result.Results))
+	return result, nil
+}
+
+func resultSteamIDs(results []dto.UserSearchResult) []string {
+	steamIDs := make([]string, 0, len(results))
+	for _, result := range results {
+		steamIDs = append(steamIDs, result.SteamID)
+	}
+	return steamIDs
+}
+
+// searchWithStats runs the whole search in the database so that stat filters,
+// ordering and the total count all apply to the same set of players. Players
+// without a player_stats row cannot match and are left out.
+func (s *PlayerSearchService) searchWithStats(ctx context.Context, query string, filters dto.SearchFilters, cursorValue string, page, pageSize int) (*dto.SearchResult, error) {
+	page, pageSize = normalizePage(page, pageSize)
+
+	cursor, err := decodeSearchCursor(cursorValue)
+	if err != nil {
+		return nil, err
+	}
+	if cursor != nil && cursor.LocalDone {
+		return nil, fmt.Errorf("%w: cursor does not belong to this search", cErrors.ErrInvalidQuery)
+	}
+	sortBy := filters.GetDefaultSortBy()
+	matchSteamID := filters.GetSearchType() == "steamid"
+	fingerprint := searchFingerprint(query, matchSteamID, filters.StatsFilter())
+	if err := cursor.checkSearch(sortBy, fingerprint, s.playerProfileRepository.ValidKeysetValue); err != nil {
+		return nil, err
+	}
+
+	offset := 0
+	if cursor == nil {
+		offset = (page - 1) * pageSize
+	}
+
+	users, totalCount, err := s.playerProfileRepository.SearchWithStats(ctx, query,
+		matchSteamID, filters.StatsFilter(), cursor.keyset(), offset, pageSize+1)
+	if err != nil {
+		s.logger.Error("Error searching players with stat filters", zap.String("query", query), zap.Error(err))
+		return nil, err
+	}
+	if cursor != nil {
+		totalCount = cursor.Total
+	}
+
+	var next *searchCursor
+	if len(users) > pageSize {
+		users = users[:pageSize]
+		next = keysetCursor(users[len(users)-1].Keyset(sortBy)).forSearch(sortBy, fingerprint, totalCount)
+	}
+
+	results := make([]dto.UserSearchResult, len(users))
+	for i, user := range users {
+		results[i] = dto.UserSearchResult{
+			ID:           user.ID.String(),
+			SteamID:      user.SteamID,
+			Nickname:     user.Nickname,
+			AvatarURL:    user.AvatarURL,
+			ProfileURL:   user.ProfileURL,
+			CountryCode:  user.CountryCode,
+			CreatedAt:    &user.CreatedAt,
+			UpdatedAt:    &user.UpdatedAt,
+			PlayerRank:   user.PlayerRank,
+			TotalMatches: user.TotalMatches,
+			WinRate:      user.WinRate,
+			KDRatio:      user.KDRatio,
+			Relevance:    user.Relevance,
+
+			IsDeadlockPlayer:    user.TotalMatches > 0,
+			DeadlockStatusKnown: true,
+		}
+	}
+
+	return &dto.SearchResult{
+		Results:    results,
+		TotalCount: totalCount,
+		Page:       page,
+		PageSize:   pageSize,
+		TotalPages: (totalCount + pageSize - 1) / pageSize,
+		NextCursor: next.encode(),
+	}, nil
+}
+
+// pagedNicknameSearch pages through local nickname matches in the database
+// and then through the extra results that are not local matches. TotalCount
+// covers both sources. Without a cursor, page is turned into an offset.
+func (s *PlayerSearchService) pagedNicknameSearch(ctx context.Context, query, sortBy string, desc bool, cursorValue string, page, pageSize int) (*dto.SearchResult, error) {
+	page, pageSize = normalizePage(page, pageSize)
+
+	cursor, err := decodeSearchCursor(cursorValue)
+	if err != nil {
+		return nil, err
+	}
+	fingerprint := searchFingerprint(query, sortBy, desc)
+	if err := cursor.checkSearch(sortBy, fingerprint, s.playerProfileRepository.ValidKeysetValue); err != nil {
+		return nil, err
+	}
+
+	extras := s.extraNicknameResults(ctx, query)
+	if sortBy == "relevance" {
+		s.rankByRelevance(extras)
+	} else {
+		s.sortUsersByFilters(extras, dto.SearchFilters{SortBy: sortBy, SortOrder: sortOrder(desc)})
+	}
+
+	results := make([]dto.UserSearchResult, 0, pageSize)
+	var next *searchCursor
+	var localTotal, extraOffset int
+
+	if cursor != nil && cursor.LocalDone {
+		localTotal = cursor.Total
+		extraOffset = cursor.Extra
+	} else {
+		offset := 0
+		if cursor == nil {
+			offset = (page - 1) * pageSize
+		}
+
+		users, total, err := s.playerProfileRepository.SearchByNicknamePage(ctx, query, sortBy, desc, cursor.keyset(), offset, pageSize+1)
 		if err != nil {
+			s.logger.Error("Error searching local database", zap.Error(err))
 			return nil, err
 		}
-		results = s.combineSearchResults(localResults, apiResults)
+		localTotal = total
+		if cursor != nil {
+			localTotal = cursor.Total
+		}
+
+		if len(users) > pageSize {
+			users = users[:pageSize]
+			next = keysetCursor(users[len(users)-1].Keyset(sortBy))
+		}
+		for _, user := range users {
+			results = append(results, rankedUserResult(user))
+		}
+
+		if cursor == nil && offset >= total {
+			extraOffset = offset - total
+		}
 	}
 
+	if next == nil {
+		end := extraOffset + pageSize - len(results)
+		if end > len(extras) {
+			end = len(extras)
+		}
+		for i := extraOffset; i < end; i++ {
+			results = append(results, extras[i])
+		}
+		if end >= extraOffset && end < len(extras) {
+			next = &searchCursor{LocalDone: true, Extra: end}
+		}
+	}
+	next.forSearch(sortBy, fingerprint, localTotal)
+	s.markDeadlockActivity(ctx, results)
+
+	totalCount := localTotal + len(extras)
+	return &dto.SearchResult{
+		Results:    results,
+		TotalCount: totalCount,
+		Page:       page,
+		PageSize:   pageSize,
+		TotalPages: (totalCount + pageSize - 1) / pageSize,
+		NextCursor: next.encode(),
+	}, nil
+}
+
+// extraNicknameResults collects nickname matches that the local search does
+// not return: Deadlock API hits whose stored nickname does not match, then
+// users only found through a former nickname.
+func (s *PlayerSearchService) extraNicknameResults(ctx context.Context, query string) []dto.UserSearchResult {
+	apiResults := s.fetchAPISearchResults(ctx, query)
+
+	aliases, err := s.playerProfileRepository.SearchByNicknameHistory(ctx, query, maxAliasMatches)
 	if err != nil {
-		return nil, err
+		s.logger.Warn("Error searching nickname history", zap.Error(err))
+	}
+
+	apiSteamIDs := make([]string, len(apiResults))
+	candidates := make([]string, 0, len(apiResults)+len(aliases))
+	for i, apiPlayer := range apiResults {
+		apiSteamIDs[i] = s.convertAccountIDToSteamID64(apiPlayer.AccountID)
+		candidates = append(candidates, apiSteamIDs[i])
+	}
+	for _, alias := range aliases {
+		candidates = append(candidates, alias.SteamID)
+	}
+
+	localMatches, err := s.playerProfileRepository.FilterNicknameMatches(ctx, query, candidates)
+	if err != nil {
+		s.logger.Warn("Error matching extra results against local users", zap.Error(err))
+		localMatches = map[string]bool{}
+	}
+
+	extras := make([]dto.UserSearchResult, 0, len(candidates))
+	seen := make(map[string]bool, len(candidates))
+
+	for i, apiPlayer := range apiResults {
+		steamID64 := apiSteamIDs[i]
+		if steamID64 == "" || !s.isValidAPIPlayer(apiPlayer) || localMatches[steamID64] || seen[steamID64] {
+			continue
+		}
+		seen[steamID64] = true
+
+		result := apiSearchResult(apiPlayer, steamID64)
+		result.Relevance = estimateRelevance(query, result.Nickname)
+		extras = append(extras, result)
+	}
+
+	for _, alias := range aliases {
+		if localMatches[alias.SteamID] || seen[alias.SteamID] {
+			continue
+		}
+		seen[alias.SteamID] = true
+		extras = append(extras, aliasSearchResult(alias))
+	}
+
+	return extras
+}
+
+func rankedUserResult(user domain.RankedUser) dto.UserSearchResult {
+	return dto.UserSearchResult{
+		ID:          user.ID.String(),
+		SteamID:     user.SteamID,
+		Nickname:    user.Nickname,
+		AvatarURL:   user.AvatarURL,
+		ProfileURL:  user.ProfileURL,
+		CountryCode: user.CountryCode,
+		CreatedAt:   &user.CreatedAt,
+		UpdatedAt:   &user.UpdatedAt,
+		Relevance:   user.Relevance,
+	}
+}
+
+func apiSearchResult(apiPlayer domain.SteamProfileSearch, steamID64 string) dto.UserSearchResult {
+	return dto.UserSearchResult{
+		SteamID:             steamID64,
+		Nickname:            apiPlayer.Personaname,
+		AvatarURL:           apiPlayer.Avatar,
+		ProfileURL:          apiPlayer.Profileurl,
+		AccountID:           apiPlayer.AccountID,
+		CountryCode:         apiPlayer.CountryCode,
+		LastUpdated:         apiPlayer.LastUpdated,
+		Realname:            apiPlayer.Realname,
+		IsDeadlockPlayer:    true,
+		DeadlockStatusKnown: true,
 	}
+}
 
-	s.sortUsersByFilters(results, filters)
-	return s.applyPagination(results, page, pageSize), nil
+func aliasSearchResult(alias domain.NicknameAliasMatch) dto.UserSearchResult {
+	aliasLastSeenAt := alias.AliasLastSeenAt
+	return dto.UserSearchResult{
+		ID:              alias.ID.String(),
+		SteamID:         alias.SteamID,
+		Nickname:        alias.Nickname,
+		AvatarURL:       alias.AvatarURL,
+		ProfileURL:      alias.ProfileURL,
+		CreatedAt:       &alias.CreatedAt,
+		UpdatedAt:       &alias.UpdatedAt,
+		MatchedAlias:    alias.MatchedAlias,
+		AliasLastSeenAt: &aliasLastSeenAt,
+		Relevance:       alias.Relevance * aliasRelevanceFactor,
+	}
+}
+
+func sortOrder(desc bool) string {
+	if desc {
+		return "desc"
+	}
+	return "asc"
 }
 
 func (s *PlayerSearchService) GetPopularPlayers(ctx context.Context, page, pageSize int) (*dto.SearchResult, error) {
@@ -277,7 +595,7 @@ func (s *PlayerSearchService) IsValidUser(user dto.UserSearchResult) bool {
 	return user.SteamID != "" && user.Nickname != "" && user.AvatarURL != ""
 }
 
-func (s *PlayerSearchService) searchBySteamID(_ context.Context, query string) ([]dto.UserSearchResult, error) {
+func (s *PlayerSearchService) searchBySteamID(ctx context.Context, query string) ([]dto.UserSearchResult, error) {
 	if !s.isValidSteamID(query) {
 		return []dto.UserSearchResult{}, nil
 	}
@@ -299,7 +617,7 @@ func (s *PlayerSearchService) searchBySteamID(_ context.Context, query string) (
 			zap.String("steamID", query))
 	}
 
-	steamUser, err := s.authService.GetPlayerSummaries(query)
+	steamUser, err := s.authService.GetPlayerSummaries(ctx, query)
 	if err != nil {
 		s.logger.Debug("Failed to get player from Steam API", zap.String("steamID", query), zap.Error(err))
 		return []dto.UserSearchResult{}, nil
@@ -324,47 +642,72 @@ func (s *PlayerSearchService) searchBySteamID(_ context.Context, query string) (
 	return []dto.UserSearchResult{}, nil
 }
 
-func (s *PlayerSearchService) searchByNickname(ctx context.Context, query string) ([]dto.UserSearchResult, error) {
-	localResults, apiResults, err := s.fetchSearchResults(ctx, query)
+const maxAliasMatches = 10
+
+// appendAliasMatches adds users that are only found through a former
+// nickname. Users already matched by their current name are left untouched.
+func (s *PlayerSearchService) appendAliasMatches(ctx context.Context, query string, results []dto.UserSearchResult, limit int) []dto.UserSearchResult {
+	aliases, err := s.playerProfileRepository.SearchByNicknameHistory(ctx, query, limit)
 	if err != nil {
-		s.logger.Error("Error searching by nickname", zap.Error(err))
-		return []dto.UserSearchResult{}, err
+		s.logger.Warn("Error searching nickname history", zap.Error(err))
+		return results
 	}
 
-	combined := s.combineSearchResults(localResults, apiResults)
+	seen := make(map[string]bool, len(results))
+	for _, result := range results {
+		seen[result.SteamID] = true
+	}
 
-	return combined, nil
-}
+	for _, alias := range aliases {
+		if seen[alias.SteamID] {
+			continue
+		}
+		seen[alias.SteamID] = true
 
-func (s *PlayerSearchService) fetchSearchResults(ctx context.Context, query string) ([]domain.User, []domain.SteamProfileSearch, error) {
-	localResults, err := s.playerProfileRepository.SearchByNickname(ctx, query)
-	if err != nil {
-		s.logger.Error("Error searching local database", zap.Error(err))
-		return []domain.User{}, []domain.SteamProfileSearch{}, err
+		results = append(results, aliasSearchResult(alias))
 	}
 
-	s.steamSearchCacheMutex.Lock()
-	cacheEntry, found := s.steamSearchCache[query]
-	if found && time.Since(cacheEntry.timestamp) < steamSearchCacheTTL {
-		s.steamSearchCacheMutex.Unlock()
-		return localResults, cacheEntry.results, nil
+	return results
+}
+
+// rankByRelevance orders results so current-name matches come before matches
+// on former nicknames, then by relevance score, then by nickname.
+func (s *PlayerSearchService) rankByRelevance(results []dto.UserSearchResult) {
+	sort.SliceStable(results, func(i, j int) bool {
+		aliasI, aliasJ := results[i].MatchedAlias != "", results[j].MatchedAlias != ""
+		if aliasI != aliasJ {
+			return !aliasI
+		}
+
+		if results[i].Relevance != results[j].Relevance {
+			return results[i].Relevance > results[j].Relevance
+		}
+
+		if aliasI {
+			return results[i].AliasLastSeenAt.After(*results[j].AliasLastSeenAt)
+		}
+		return strings.ToLower(results[i].Nickname) < strings.ToLower(results[j].Nickname)
+	})
+}
+
+// fetchAPISearchResults returns the Deadlock API profile search for query,
+// served from the search cache so that consecutive pages agree.
+func (s *PlayerSearchService) fetchAPISearchResults(ctx context.Context, query string) []domain.SteamProfileSearch {
+	cacheKey := searchCacheKey("deadlock-api", query)
+
+	var apiResults []domain.SteamProfileSearch
+	if s.searchCache.Get(ctx, cacheKey, &apiResults) {
+		return apiResults
 	}
-	s.steamSearchCacheMutex.Unlock()
 
 	apiResults, err := s.deadlockAPIClient.FetchSteamProfileSearch(query)
 	if err != nil {
 		s.logger.Warn("Failed to fetch from Deadlock API search", zap.Error(err))
-		apiResults = []domain.SteamProfileSearch{}
-	}
-
-	s.steamSearchCacheMutex.Lock()
-	s.steamSearchCache[query] = steamSearchCacheEntry{
-		results:   apiResults,
-		timestamp: time.Now(),
+		return []domain.SteamProfileSearch{}
 	}
-	s.steamSearchCacheMutex.Unlock()
 
-	return localResults, apiResults, nil
+	s.searchCache.Set(ctx, cacheKey, apiResults, nil)
+	return apiResults
 }
 
 func (s *PlayerSearchService) combineSearchResults(localResults []domain.User, apiResults []domain.SteamProfileSearch) []dto.UserSearchResult {
@@ -399,78 +742,18 @@ func (s *PlayerSearchService) combineSearchResults(localResults []domain.User, a
 		}
 
 		if _, exists := combinedUsers[steamID64]; !exists {
-			combinedUsers[steamID64] = dto.UserSearchResult{
-				ID:                  "",
-				SteamID:             steamID64,
-				Nickname:            apiPlayer.Personaname,
-				AvatarURL:           apiPlayer.Avatar,
-				ProfileURL:          apiPlayer.Profileurl,
-				AccountID:           apiPlayer.AccountID,
-				CountryCode:         apiPlayer.CountryCode,
-				LastUpdated:         apiPlayer.LastUpdated,
-				Realname:            apiPlayer.Realname,
-				IsDeadlockPlayer:    true,
-				DeadlockStatusKnown: true,
-			}
+			combinedUsers[steamID64] = apiSearchResult(apiPlayer, steamID64)
 		}
 	}
 
 	result := make([]dto.UserSearchResult, 0, len(combinedUsers))
 	for _, user := range combinedUsers {
-		if !user.IsDeadlockPlayer {
-			isActive, known := s.hasDeadlockActivity(user.SteamID, false)
-			user.IsDeadlockPlayer = isActive
-			user.DeadlockStatusKnown = known
-		}
 		result = append(result, user)
 	}
 
 	return result
 }
 
-func (s *PlayerSearchService) hasDeadlockActivity(steamID string, isFromDeadlockAPI bool) (bool, bool) {
-	if isFromDeadlockAPI {
-		return true, true
-	}
-
-	ctx := context.Background()
-	cacheKey := fmt.Sprintf("deadlock-activity:%s", steamID)
-	cached, err := s.redisClient.Get(ctx, cacheKey).Result()
-	if err == nil {
-		return cached == "true", true
-	}
-
-	var count int64
-	err = s.userRepository.GetDB().Table("users").
-		Joins("JOIN player_stats ps ON users.id = ps.user_id").
-		Where("users.steam_id = ? AND ps.total_matches > 0", steamID).
-		Count(&count).Error
-
-	if err != nil {
-		s.logger.Warn("Error checking local Deadlock activity",
-			zap.String("steamID", steamID),
-			zap.Error(err))
-		return false, false
-	}
-
-	if count > 0 {
-		s.redisClient.Set(ctx, cacheKey, "true", 6*time.Hour)
-		return true, true
-	}
-
-	var userExists int64
-	err = s.userRepository.GetDB().Table("users").
-		Where("steam_id = ?", steamID).
-		Count(&userExists).Error
-
-	if err == nil && userExists > 0 {
-		s.redisClient.Set(ctx, cacheKey, "false", 6*time.Hour)
-		return false, true
-	}
-
-	return false, true
-}
-
 func (s *PlayerSearchService) isValidAPIPlayer(apiPlayer domain.SteamProfileSearch) bool {
 	if apiPlayer.AccountID <= 0 {
 		return false
@@ -500,18 +783,19 @@ func (s *PlayerSearchService) createUserFromAPISearch(apiPlayer domain.SteamProf
 	}
 
 	return domain.User{
-		ID:         uuid.New(),
-		SteamID:    steamID64,
-		Nickname:   apiPlayer.Personaname,
-		AvatarURL:  avatarURL,
-		ProfileURL: profileURL,
-		CreatedAt:  time.Now(),
-		UpdatedAt:  time.Now(),
+		ID:          uuid.New(),
+		SteamID:     steamID64,
+		Nickname:    apiPlayer.Personaname,
+		AvatarURL:   avatarURL,
+		ProfileURL:  profileURL,
+		CountryCode: apiPlayer.CountryCode,
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
 	}
 }
 
-func (s *PlayerSearchService) findAndCreateUser(steamID string) ([]domain.User, error) {
-	steamUser, err := s.authService.GetPlayerSummaries(steamID)
+func (s *PlayerSearchService) findAndCreateUser(ctx context.Context, steamID string) ([]domain.User, error) {
+	steamUser, err := s.authService.GetPlayerSummaries(ctx, steamID)
 	if err != nil {
 		s.logger.Error("Error getting player summaries", zap.Error(err), zap.String("steamID", steamID))
 		return []domain.User{}, err
@@ -540,6 +824,32 @@ func (s *PlayerSearchService) createUserFromSteamData(steamID string, steamUser
 	}
 }
 
+// resolveSteamQuery replaces a steamcommunity.com link, and a bare vanity
+// name when bareVanity is set, with the SteamID64 it refers to. Queries that
+// cannot be resolved are returned unchanged and searched as text.
+func (s *PlayerSearchService) resolveSteamQuery(ctx context.Context, query string, bareVanity bool) string {
+	isLink := strings.Contains(strings.ToLower(query), "steamcommunity.com/")
+	if !isLink && !(bareVanity && isVanityCandidate(query)) {
+		return query
+	}
+
+	steamID, err := s.steamIDResolver.Resolve(ctx, query)
+	if err != nil {
+		s.logger.Debug("Failed to resolve Steam query", zap.String("query", query), zap.Error(err))
+		return query
+	}
+	return steamID
+}
+
+// isVanityCandidate reports whether a query could be a vanity name. Numbers
+// are left to the match and account ID lookups.
+func isVanityCandidate(query string) bool {
+	if _, err := strconv.ParseInt(query, 10, 64); err == nil {
+		return false
+	}
+	return vanityNameRegex.MatchString(query)
+}
+
 func (s *PlayerSearchService) isValidSteamID(steamID string) bool {
 	if len(steamID) != 17 {
 		return false
@@ -553,7 +863,7 @@ func (s *PlayerSearchService) isValidSteamID(steamID string) bool {
 }
 
 func (s *PlayerSearchService) sortUsersByFilters(users []dto.UserSearchResult, filters dto.SearchFilters) {
-	sort.Slice(users, func(i, j int) bool {
+	sort.SliceStable(users, func(i, j int) bool {
 		switch filters.GetDefaultSortBy() {
 		case "created_at":
 			if users[i].CreatedAt == nil && users[j].CreatedAt == nil {
@@ -583,6 +893,12 @@ func (s *PlayerSearchService) sortUsersByFilters(users []dto.UserSearchResult, f
 				return users[i].UpdatedAt.After(*users[j].UpdatedAt)
 			}
 			return users[i].UpdatedAt.Before(*users[j].UpdatedAt)
+		case "rank", "matches", "win_rate", "kd_ratio", "relevance":
+			a, b := statSortValue(users[i], filters.SortBy), statSortValue(users[j], filters.SortBy)
+			if filters.GetDefaultSortOrder() == "desc" {
+				return a > b
+			}
+			return a < b
 		default: // "nickname"
 			if filters.GetDefaultSortOrder() == "desc" {
 				return users[i].Nickname > users[j].Nickname
@@ -592,7 +908,22 @@ func (s *PlayerSearchService) sortUsersByFilters(users []dto.UserSearchResult, f
 	})
 }
 
-func (s *PlayerSearchService) applyPagination(results []dto.UserSearchResult, page, pageSize int) *dto.SearchResult {
+func statSortValue(user dto.UserSearchResult, sortBy string) float64 {
+	switch sortBy {
+	case "rank":
+		return float64(user.PlayerRank)
+	case "matches":
+		return float64(user.TotalMatches)
+	case "win_rate":
+		return user.WinRate
+	case "relevance":
+		return user.Relevance
+	default:
+		return user.KDRatio
+	}
+}
+
+func normalizePage(page, pageSize int) (int, int) {
 	if page <= 0 {
 		page = 1
 	}
@@ -602,6 +933,11 @@ func (s *PlayerSearchService) applyPagination(results []dto.UserSearchResult, pa
 	if pageSize > 100 {
 		pageSize = 100
 	}
+	return page, pageSize
+}
+
+func (s *PlayerSearchService) applyPagination(results []dto.UserSearchResult, page, pageSize int) *dto.SearchResult {
+	page, pageSize = normalizePage(page, pageSize)
 
 	totalCount := len(results)
 	totalPages := (totalCount + pageSize - 1) / pageSize
@@ -631,30 +967,3 @@ func (s *PlayerSearchService) applyPagination(results []dto.UserSearchResult, pa
 		TotalPages: totalPages,
 	}
 }
-
-func resolveVanityURL(vanity string, apiKey string) (string, error) {
-	if apiKey == "" {
-		return "", errors.New("steam API key is empty")
-	}
-
-	url := "https://api.steampowered.com/ISteamUser/ResolveVanityURL/v1/?key=" + apiKey + "&vanityurl=" + vanity
-	resp, err := http.Get(url)
-	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
-
-	var result struct {
-		Response struct {
-			SteamID string `json:"steamid"`
-			Success int    `json:"success"`
-		} `json:"response"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return "", err
-	}
-	if result.Response.Success != 1 {
-		return "", errors.New("not found")
-	}
-	return result.Response.SteamID, nil
-}
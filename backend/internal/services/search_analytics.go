@@ -0,0 +1,366 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/quenyu/deadlock-stats/internal/dto"
+	"github.com/quenyu/deadlock-stats/internal/repositories"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+const (
+	searchAnalyticsQueueSize     = 1024
+	searchAnalyticsBatchSize     = 200
+	searchAnalyticsFlushInterval = time.Second
+	searchAnalyticsRetention     = 8 * 24 * time.Hour
+	searchAnalyticsMergeTTL      = time.Minute
+
+	// MaxSearchStatsDays is bounded by searchAnalyticsRetention.
+	MaxSearchStatsDays = 7
+
+	maxLatencySamplesPerDay = 5000
+	maxTrackedQueryLength   = 100
+
+	searchQueriesKeyPrefix     = "search-analytics:queries:"
+	searchZeroKeyPrefix        = "search-analytics:zero:"
+	searchCountKeyPrefix       = "search-analytics:count:"
+	searchZeroCountKeyPrefix   = "search-analytics:zero-count:"
+	searchLatencyKeyPrefix     = "search-analytics:latency:"
+	searchAnalyticsMergePrefix = "search-analytics:merged:"
+	playerViewsKeyPrefix       = "player-views:"
+)
+
+type searchAnalyticsEvent struct {
+	at time.Time
+
+	// Set for searches
+	query   string
+	results int
+	latency time.Duration
+
+	// Set for profile views
+	steamID string
+}
+
+// SearchAnalytics records searches and profile views in Redis. Recording
+// never blocks the request: events are queued and written in batches by
+// Start, and dropped when the queue is full. Searches are kept per day,
+// profile views per hour so that trending players can use a sliding window.
+type SearchAnalytics struct {
+	redisClient    *redis.Client
+	userRepository *repositories.UserRepository
+	logger         *zap.Logger
+
+	events chan searchAnalyticsEvent
+}
+
+func NewSearchAnalytics(redisClient *redis.Client, userRepository *repositories.UserRepository, logger *zap.Logger) *SearchAnalytics {
+	return &SearchAnalytics{
+		redisClient:    redisClient,
+		userRepository: userRepository,
+		logger:         logger.Named("SearchAnalytics"),
+		events:         make(chan searchAnalyticsEvent, searchAnalyticsQueueSize),
+	}
+}
+
+// RecordSearch queues a completed search.
+func (a *SearchAnalytics) RecordSearch(query string, results int, latency time.Duration) {
+	query = strings.ToLower(normalizeSearchQuery(query))
+	if query == "" {
+		return
+	}
+	if len(query) > maxTrackedQueryLength {
+		query = strings.ToValidUTF8(query[:maxTrackedQueryLength], "")
+	}
+	a.enqueue(searchAnalyticsEvent{at: time.Now(), query: query, results: results, latency: latency})
+}
+
+// RecordProfileView queues a view of a player profile.
+func (a *SearchAnalytics) RecordProfileView(steamID string) {
+	if steamID == "" {
+		return
+	}
+	a.enqueue(searchAnalyticsEvent{at: time.Now(), steamID: steamID})
+}
+
+func (a *SearchAnalytics) enqueue(event searchAnalyticsEvent) {
+	select {
+	case a.events <- event:
+	default:
+		a.logger.Debug("Search analytics queue is full, dropping event")
+	}
+}
+
+// Start writes queued events until ctx is cancelled, then flushes what is
+// left.
+func (a *SearchAnalytics) Start(ctx context.Context) {
+	ticker := time.NewTicker(searchAnalyticsFlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]searchAnalyticsEvent, 0, searchAnalyticsBatchSize)
+	for {
+		select {
+		case <-ctx.Done():
+		drain:
+			for {
+				select {
+				case event := <-a.events:
+					batch = append(batch, event)
+				default:
+					break drain
+				}
+			}
+			flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			a.flush(flushCtx, batch)
+			cancel()
+			return
+		case event := <-a.events:
+			batch = append(batch, event)
+			if len(batch) >= searchAnalyticsBatchSize {
+				a.flush(ctx, batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			if len(batch) > 0 {
+				a.flush(ctx, batch)
+				batch = batch[:0]
+			}
+		}
+	}
+}
+
+// flush aggregates the batch in memory so that each key is written once.
+func (a *SearchAnalytics) flush(ctx context.Context, batch []searchAnalyticsEvent) {
+	if len(batch) == 0 {
+		return
+	}
+
+	increments := make(map[string]map[string]float64)
+	counters := make(map[string]int64)
+	latencies := make(map[string][]interface{})
+
+	increment := func(key, member string) {
+		if increments[key] == nil {
+			increments[key] = make(map[string]float64)
+		}
+		increments[key][member]++
+	}
+
+	for _, event := range batch {
+		if event.steamID != "" {
+			increment(playerViewsKeyPrefix+analyticsHour(event.at), event.steamID)
+			continue
+		}
+
+		day := analyticsDay(event.at)
+		increment(searchQueriesKeyPrefix+day, event.query)
+		counters[searchCountKeyPrefix+day]++
+		if event.results == 0 {
+			increment(searchZeroKeyPrefix+day, event.query)
+			counters[searchZeroCountKeyPrefix+day]++
+		}
+		latencies[searchLatencyKeyPrefix+day] = append(latencies[searchLatencyKeyPrefix+day], event.latency.Milliseconds())
+	}
+
+	pipe := a.redisClient.Pipeline()
+	for key, members := range increments {
+		for member, count := range members {
+			pipe.ZIncrBy(ctx, key, count, member)
+		}
+		pipe.Expire(ctx, key, searchAnalyticsRetention)
+	}
+	for key, count := range counters {
+		pipe.IncrBy(ctx, key, count)
+		pipe.Expire(ctx, key, searchAnalyticsRetention)
+	}
+	for key, samples := range latencies {
+		pipe.LPush(ctx, key, samples...)
+		pipe.LTrim(ctx, key, 0, maxLatencySamplesPerDay-1)
+		pipe.Expire(ctx, key, searchAnalyticsRetention)
+	}
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		a.logger.Warn("Failed to write search analytics", zap.Int("events", len(batch)), zap.Error(err))
+	}
+}
+
+// TrendingPlayers returns the most viewed known players over the last
+// period, rounded up to whole hours.
+func (a *SearchAnalytics) TrendingPlayers(ctx context.Context, period time.Duration, limit int) ([]dto.TrendingPlayer, error) {
+	hours := int(math.Ceil(period.Hours()))
+	now := time.Now()
+
+	keys := make([]string, hours)
+	for i := range keys {
+		keys[i] = playerViewsKeyPrefix + analyticsHour(now.Add(-time.Duration(i)*time.Hour))
+	}
+
+	top, err := a.topMembers(ctx, fmt.Sprintf("%sviews:%dh", searchAnalyticsMergePrefix, hours), keys, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	steamIDs := make([]string, len(top))
+	for i, entry := range top {
+		steamIDs[i] = entry.Member.(string)
+	}
+
+	users, err := a.userRepository.FindBySteamIDs(ctx, steamIDs)
+	if err != nil {
+		return nil, err
+	}
+	results := make(map[string]dto.UserSearchResult, len(users))
+	for _, user := range users {
+		results[user.SteamID] = userSearchResult(user)
+	}
+
+	trending := make([]dto.TrendingPlayer, 0, len(top))
+	for i, entry := range top {
+		result, ok := results[steamIDs[i]]
+		if !ok {
+			continue
+		}
+		trending = append(trending, dto.TrendingPlayer{UserSearchResult: result, Views: int64(entry.Score)})
+	}
+
+	return trending, nil
+}
+
+// SearchStats summarizes the searches of the last days, today included.
+func (a *SearchAnalytics) SearchStats(ctx context.Context, days, limit int) (*dto.SearchStats, error) {
+	now := time.Now()
+	dayKeys := make([]string, days)
+	for i := range dayKeys {
+		dayKeys[i] = analyticsDay(now.AddDate(0, 0, -i))
+	}
+
+	withPrefix := func(prefix string) []string {
+		keys := make([]string, len(dayKeys))
+		for i, day := range dayKeys {
+			keys[i] = prefix + day
+		}
+		return keys
+	}
+
+	stats := &dto.SearchStats{Days: days}
+
+	var err error
+	if stats.TotalSearches, err = a.sumCounters(ctx, withPrefix(searchCountKeyPrefix)); err != nil {
+		return nil, err
+	}
+	if stats.ZeroResultSearches, err = a.sumCounters(ctx, withPrefix(searchZeroCountKeyPrefix)); err != nil {
+		return nil, err
+	}
+
+	topQueries, err := a.topMembers(ctx, fmt.Sprintf("%squeries:%dd", searchAnalyticsMergePrefix, days), withPrefix(searchQueriesKeyPrefix), limit)
+	if err != nil {
+		return nil, err
+	}
+	stats.TopQueries = queryCounts(topQueries)
+
+	zeroQueries, err := a.topMembers(ctx, fmt.Sprintf("%szero:%dd", searchAnalyticsMergePrefix, days), withPrefix(searchZeroKeyPrefix), limit)
+	if err != nil {
+		return nil, err
+	}
+	stats.ZeroResultQueries = queryCounts(zeroQueries)
+
+	if stats.Latency, err = a.latencyStats(ctx, withPrefix(searchLatencyKeyPrefix)); err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// topMembers merges the sorted sets in keys and returns the highest scored
+// members. The merged set is kept briefly so repeated calls are cheap.
+func (a *SearchAnalytics) topMembers(ctx context.Context, mergedKey string, keys []string, limit int) ([]redis.Z, error) {
+	exists, err := a.redisClient.Exists(ctx, mergedKey).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	if exists == 0 {
+		pipe := a.redisClient.TxPipeline()
+		pipe.ZUnionStore(ctx, mergedKey, &redis.ZStore{Keys: keys})
+		pipe.Expire(ctx, mergedKey, searchAnalyticsMergeTTL)
+		if _, err := pipe.Exec(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	return a.redisClient.ZRevRangeWithScores(ctx, mergedKey, 0, int64(limit-1)).Result()
+}
+
+func (a *SearchAnalytics) sumCounters(ctx context.Context, keys []string) (int64, error) {
+	values, err := a.redisClient.MGet(ctx, keys...).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	var total int64
+	for _, value := range values {
+		if s, ok := value.(string); ok {
+			n, _ := strconv.ParseInt(s, 10, 64)
+			total += n
+		}
+	}
+	return total, nil
+}
+
+func (a *SearchAnalytics) latencyStats(ctx context.Context, keys []string) (dto.SearchLatencyStats, error) {
+	var samples []float64
+	for _, key := range keys {
+		values, err := a.redisClient.LRange(ctx, key, 0, -1).Result()
+		if err != nil {
+			return dto.SearchLatencyStats{}, err
+		}
+		for _, value := range values {
+			if ms, err := strconv.ParseFloat(value, 64); err == nil {
+				samples = append(samples, ms)
+			}
+		}
+	}
+
+	stats := dto.SearchLatencyStats{Samples: len(samples)}
+	if len(samples) == 0 {
+		return stats, nil
+	}
+
+	sort.Float64s(samples)
+	stats.P50Ms = percentile(samples, 50)
+	stats.P90Ms = percentile(samples, 90)
+	stats.P99Ms = percentile(samples, 99)
+	return stats, nil
+}
+
+// percentile uses the nearest-rank method on sorted samples.
+func percentile(sorted []float64, p float64) float64 {
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+func queryCounts(entries []redis.Z) []dto.SearchQueryCount {
+	counts := make([]dto.SearchQueryCount, len(entries))
+	for i, entry := range entries {
+		counts[i] = dto.SearchQueryCount{Query: entry.Member.(string), Count: int64(entry.Score)}
+	}
+	return counts
+}
+
+func analyticsDay(t time.Time) string {
+	return t.UTC().Format("20060102")
+}
+
+func analyticsHour(t time.Time) string {
+	return t.UTC().Format("2006010215")
+}
@@ -0,0 +1,361 @@
+package services
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/quenyu/deadlock-stats/internal/middleware/metrics"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultSearchCacheTTL        = 2 * time.Minute
+	defaultSearchCacheLocalTTL   = 30 * time.Second
+	defaultSearchCacheMaxEntries = 1000
+
+	searchCacheKeyPrefix     = "search-cache:"
+	searchCacheTagPrefix     = "search-cache-user:"
+	searchCacheGenerationKey = "search-cache-generation"
+
+	searchCacheMetric = "search"
+
+	// User changes are queued and applied in batches, so a burst of writes
+	// costs one generation bump instead of one per user.
+	searchInvalidationQueueSize = 1024
+	searchInvalidationInterval  = 250 * time.Millisecond
+	searchInvalidationTimeout   = 5 * time.Second
+)
+
+type searchCacheChange struct {
+	steamID         string
+	nicknameChanged bool
+}
+
+type searchCacheEntry struct {
+	key       string
+	payload   searchCachePayload
+	expiresAt time.Time
+}
+
+// searchCachePayload is what both tiers store: the serialized result and the
+// SteamIDs it mentions, which are used for invalidation.
+type searchCachePayload struct {
+	SteamIDs []string        `json:"ids,omitempty"`
+	Value    json.RawMessage `json:"v"`
+}
+
+// SearchCache stores search results in a size-bounded in-process LRU with a
+// short TTL in front of a shared Redis tier. Entries are tagged with the
+// SteamIDs they contain so that a changed user evicts them; other replicas
+// drop their in-process copy once the local TTL runs out. Nickname searches
+// are also keyed by a generation that is bumped whenever a nickname changes,
+// since a new or renamed user can match pages that do not contain them yet.
+type SearchCache struct {
+	redisClient *redis.Client
+	logger      *zap.Logger
+
+	ttl        time.Duration
+	localTTL   time.Duration
+	maxEntries int
+
+	mu           sync.Mutex
+	order        *list.List
+	entries      map[string]*list.Element
+	generation   string
+	generationAt time.Time
+
+	changes chan searchCacheChange
+	// overflowed is set when a change could not be queued; the next batch
+	// then bumps the generation even without a nickname change.
+	overflowed atomic.Bool
+}
+
+func NewSearchCache(redisClient *redis.Client, ttl, localTTL time.Duration, maxEntries int, logger *zap.Logger) *SearchCache {
+	if ttl <= 0 {
+		ttl = defaultSearchCacheTTL
+	}
+	if localTTL <= 0 || localTTL > ttl {
+		localTTL = min(defaultSearchCacheLocalTTL, ttl)
+	}
+	if maxEntries <= 0 {
+		maxEntries = defaultSearchCacheMaxEntries
+	}
+
+	return &SearchCache{
+		redisClient: redisClient,
+		logger:      logger.Named("SearchCache"),
+		ttl:         ttl,
+		localTTL:    localTTL,
+		maxEntries:  maxEntries,
+		order:       list.New(),
+		entries:     make(map[string]*list.Element),
+		changes:     make(chan searchCacheChange, searchInvalidationQueueSize),
+	}
+}
+
+// normalizeSearchQuery trims and collapses whitespace. Searches are run with
+// the normalized query so equivalent inputs share a cache entry.
+func normalizeSearchQuery(query string) string {
+	return strings.Join(strings.Fields(query), " ")
+}
+
+// searchCacheKey builds a key from a namespace, the case- and accent-folded
+// query and every other parameter that changes the result.
+func searchCacheKey(namespace, query string, params ...interface{}) string {
+	return searchCacheKeyPrefix + namespace + ":" + searchFingerprint(query, params...)
+}
+
+// nicknameSearchKey is searchCacheKey for searches that match nicknames. Such
+// keys include the current generation, so a nickname change makes every
+// cached page unreachable.
+func (c *SearchCache) nicknameSearchKey(ctx context.Context, namespace, query string, params ...interface{}) string {
+	return searchCacheKey(namespace+":"+c.currentGeneration(ctx), query, params...)
+}
+
+// currentGeneration reads the generation from Redis at most once per local
+// TTL.
+func (c *SearchCache) currentGeneration(ctx context.Context) string {
+	c.mu.Lock()
+	generation, fresh := c.generation, time.Since(c.generationAt) < c.localTTL
+	c.mu.Unlock()
+	if generation != "" && fresh {
+		return generation
+	}
+
+	value, err := c.redisClient.Get(ctx, searchCacheGenerationKey).Result()
+	switch {
+	case err == redis.Nil:
+		value = "0"
+	case err != nil:
+		c.logger.Debug("Failed to read search cache generation", zap.Error(err))
+		if generation != "" {
+			return generation
+		}
+		return "0"
+	}
+
+	c.mu.Lock()
+	c.generation, c.generationAt = value, time.Now()
+	c.mu.Unlock()
+	return value
+}
+
+// Get decodes a cached value into dest and reports whether one was found.
+func (c *SearchCache) Get(ctx context.Context, key string, dest interface{}) bool {
+	if payload, ok := c.getLocal(key); ok {
+		if err := json.Unmarshal(payload.Value, dest); err == nil {
+			metrics.RecordCacheHit(searchCacheMetric, "memory")
+			return true
+		}
+	}
+
+	data, err := c.redisClient.Get(ctx, key).Bytes()
+	if err != nil {
+		if err != redis.Nil {
+			c.logger.Debug("Failed to read search cache", zap.Error(err))
+		}
+		metrics.RecordCacheMiss(searchCacheMetric)
+		return false
+	}
+
+	var payload searchCachePayload
+	if err := json.Unmarshal(data, &payload); err != nil || json.Unmarshal(payload.Value, dest) != nil {
+		metrics.RecordCacheMiss(searchCacheMetric)
+		return false
+	}
+
+	c.setLocal(key, payload)
+	metrics.RecordCacheHit(searchCacheMetric, "redis")
+	return true
+}
+
+// Set stores value in both tiers and tags it with steamIDs.
+func (c *SearchCache) Set(ctx context.Context, key string, value interface{}, steamIDs []string) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		c.logger.Warn("Failed to encode search cache entry", zap.Error(err))
+		return
+	}
+
+	payload := searchCachePayload{SteamIDs: steamIDs, Value: data}
+	c.setLocal(key, payload)
+
+	encoded, err := json.Marshal(payload)
+	if err != nil {
+		return
+	}
+
+	pipe := c.redisClient.Pipeline()
+	pipe.Set(ctx, key, encoded, c.ttl)
+	for _, steamID := range steamIDs {
+		tagKey := searchCacheTagPrefix + steamID
+		pipe.SAdd(ctx, tagKey, key)
+		pipe.Expire(ctx, tagKey, c.ttl)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		c.logger.Debug("Failed to write search cache", zap.Error(err))
+	}
+}
+
+// OnUserChange queues the invalidation of the results a changed user
+// affects, so that writing a user never waits on Redis. It is meant to be
+// registered with UserRepository.OnChange; Start applies the queue.
+func (c *SearchCache) OnUserChange(steamID string, nicknameChanged bool) {
+	select {
+	case c.changes <- searchCacheChange{steamID: steamID, nicknameChanged: nicknameChanged}:
+	default:
+		c.overflowed.Store(true)
+	}
+}
+
+// Start applies queued user changes in batches until ctx is cancelled, then
+// applies what is left.
+func (c *SearchCache) Start(ctx context.Context) {
+	ticker := time.NewTicker(searchInvalidationInterval)
+	defer ticker.Stop()
+
+	steamIDs := make(map[string]struct{})
+	nicknameChanged := false
+	for {
+		select {
+		case <-ctx.Done():
+		drain:
+			for {
+				select {
+				case change := <-c.changes:
+					steamIDs[change.steamID] = struct{}{}
+					nicknameChanged = nicknameChanged || change.nicknameChanged
+				default:
+					break drain
+				}
+			}
+			c.applyChanges(steamIDs, nicknameChanged)
+			return
+		case change := <-c.changes:
+			steamIDs[change.steamID] = struct{}{}
+			nicknameChanged = nicknameChanged || change.nicknameChanged
+		case <-ticker.C:
+			c.applyChanges(steamIDs, nicknameChanged)
+			clear(steamIDs)
+			nicknameChanged = false
+		}
+	}
+}
+
+func (c *SearchCache) applyChanges(steamIDs map[string]struct{}, nicknameChanged bool) {
+	if c.overflowed.Swap(false) {
+		// Dropped changes may have touched nicknames; tagged results of
+		// those users expire with the TTL.
+		c.logger.Warn("Search cache invalidation queue overflowed")
+		nicknameChanged = true
+	}
+	if len(steamIDs) == 0 && !nicknameChanged {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), searchInvalidationTimeout)
+	defer cancel()
+
+	for steamID := range steamIDs {
+		c.InvalidateUser(ctx, steamID)
+	}
+	if nicknameChanged {
+		c.InvalidateNicknameSearches(ctx)
+	}
+}
+
+// InvalidateNicknameSearches bumps the generation so that no cached
+// nickname search is served again.
+func (c *SearchCache) InvalidateNicknameSearches(ctx context.Context) {
+	generation, err := c.redisClient.Incr(ctx, searchCacheGenerationKey).Result()
+	if err != nil {
+		c.logger.Warn("Failed to invalidate nickname searches", zap.Error(err))
+		return
+	}
+
+	c.mu.Lock()
+	c.generation, c.generationAt = strconv.FormatInt(generation, 10), time.Now()
+	c.mu.Unlock()
+}
+
+// InvalidateUser drops every cached result that contains the user.
+func (c *SearchCache) InvalidateUser(ctx context.Context, steamID string) {
+	if steamID == "" {
+		return
+	}
+
+	c.mu.Lock()
+	for element := c.order.Front(); element != nil; {
+		next := element.Next()
+		entry := element.Value.(*searchCacheEntry)
+		for _, id := range entry.payload.SteamIDs {
+			if id == steamID {
+				c.removeLocked(element)
+				break
+			}
+		}
+		element = next
+	}
+	c.mu.Unlock()
+
+	tagKey := searchCacheTagPrefix + steamID
+	keys, err := c.redisClient.SMembers(ctx, tagKey).Result()
+	if err != nil {
+		c.logger.Debug("Failed to read search cache tags", zap.String("steamID", steamID), zap.Error(err))
+		return
+	}
+
+	if err := c.redisClient.Del(ctx, append(keys, tagKey)...).Err(); err != nil {
+		c.logger.Warn("Failed to invalidate search cache", zap.String("steamID", steamID), zap.Error(err))
+	}
+}
+
+func (c *SearchCache) getLocal(key string) (searchCachePayload, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return searchCachePayload{}, false
+	}
+
+	entry := element.Value.(*searchCacheEntry)
+	if time.Now().After(entry.expiresAt) {
+		c.removeLocked(element)
+		return searchCachePayload{}, false
+	}
+
+	c.order.MoveToFront(element)
+	return entry.payload, true
+}
+
+func (c *SearchCache) setLocal(key string, payload searchCachePayload) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expiresAt := time.Now().Add(c.localTTL)
+	if element, ok := c.entries[key]; ok {
+		entry := element.Value.(*searchCacheEntry)
+		entry.payload = payload
+		entry.expiresAt = expiresAt
+		c.order.MoveToFront(element)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&searchCacheEntry{key: key, payload: payload, expiresAt: expiresAt})
+	for c.order.Len() > c.maxEntries {
+		c.removeLocked(c.order.Back())
+	}
+}
+
+func (c *SearchCache) removeLocked(element *list.Element) {
+	c.order.Remove(element)
+	delete(c.entries, element.Value.(*searchCacheEntry).key)
+}
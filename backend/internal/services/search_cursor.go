@@ -0,0 +1,110 @@
+package services
+
+import (
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/quenyu/deadlock-stats/internal/domain"
+	cErrors "github.com/quenyu/deadlock-stats/internal/errors"
+)
+
+// searchCursor is the opaque position handed out as next_cursor. Local
+// database results are paged by keyset (Value/ID); once they are exhausted
+// the results that only exist outside the users table (Deadlock API hits and
+// former-nickname matches) are paged by offset. A cursor records the search
+// it belongs to, and the total counted on the first page so that later pages
+// do not count again.
+type searchCursor struct {
+	Sort      string `json:"s"`
+	Search    string `json:"q"`
+	Total     int    `json:"t"`
+	Value     string `json:"v,omitempty"`
+	ID        string `json:"id,omitempty"`
+	LocalDone bool   `json:"d,omitempty"`
+	Extra     int    `json:"x,omitempty"`
+}
+
+// searchFingerprint identifies a search by the normalized query and every
+// other parameter that changes its results.
+func searchFingerprint(query string, params ...interface{}) string {
+	hash := sha1.New()
+	fmt.Fprintf(hash, "%q", foldNickname(normalizeSearchQuery(query)))
+	for _, param := range params {
+		fmt.Fprintf(hash, "|%+v", param)
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func keysetCursor(keyset domain.SearchKeyset) *searchCursor {
+	return &searchCursor{Value: keyset.Value, ID: keyset.ID.String()}
+}
+
+// forSearch stamps the cursor with the search it continues.
+func (c *searchCursor) forSearch(sortBy, fingerprint string, total int) *searchCursor {
+	if c != nil {
+		c.Sort, c.Search, c.Total = sortBy, fingerprint, total
+	}
+	return c
+}
+
+// checkSearch rejects cursors handed out by another search, and keyset
+// values that the database could not cast to the sort column.
+func (c *searchCursor) checkSearch(sortBy, fingerprint string, validValue func(sortBy, value string) bool) error {
+	if c == nil {
+		return nil
+	}
+	if c.Sort != sortBy || c.Search != fingerprint {
+		return fmt.Errorf("%w: cursor does not belong to this search", cErrors.ErrInvalidQuery)
+	}
+	if !c.LocalDone && !validValue(sortBy, c.Value) {
+		return fmt.Errorf("%w: malformed cursor", cErrors.ErrInvalidQuery)
+	}
+	return nil
+}
+
+func (c *searchCursor) keyset() *domain.SearchKeyset {
+	if c == nil || c.LocalDone || c.ID == "" {
+		return nil
+	}
+	id, err := uuid.Parse(c.ID)
+	if err != nil {
+		return nil
+	}
+	return &domain.SearchKeyset{Value: c.Value, ID: id}
+}
+
+func (c *searchCursor) encode() string {
+	if c == nil {
+		return ""
+	}
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeSearchCursor returns nil for an empty cursor.
+func decodeSearchCursor(value string) (*searchCursor, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor", cErrors.ErrInvalidQuery)
+	}
+
+	var cursor searchCursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.Extra < 0 || cursor.Total < 0 {
+		return nil, fmt.Errorf("%w: malformed cursor", cErrors.ErrInvalidQuery)
+	}
+	if !cursor.LocalDone {
+		if _, err := uuid.Parse(cursor.ID); err != nil {
+			return nil, fmt.Errorf("%w: malformed cursor", cErrors.ErrInvalidQuery)
+		}
+	}
+
+	return &cursor, nil
+}
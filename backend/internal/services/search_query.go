@@ -0,0 +1,435 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/quenyu/deadlock-stats/internal/domain"
+	"github.com/quenyu/deadlock-stats/internal/dto"
+	cErrors "github.com/quenyu/deadlock-stats/internal/errors"
+)
+
+// rankTiers are the Deadlock rank names by tier. A rank value is
+// tier*10 + sub-tier (1..6), the same encoding domain.GetRankFromScore uses.
+var rankTiers = []string{
+	"obscurus", "initiate", "seeker", "alchemist", "arcanist", "ritualist",
+	"emissary", "archon", "oracle", "phantom", "ascendant", "eternus",
+}
+
+const maxRankSubTier = 6
+
+var searchSortAliases = map[string]string{
+	"relevance": "relevance",
+	"name":      "nickname",
+	"nickname":  "nickname",
+	"rank":      "rank",
+	"matches":   "matches",
+	"wr":        "win_rate",
+	"winrate":   "win_rate",
+	"win_rate":  "win_rate",
+	"kd":        "kd_ratio",
+	"kda":       "kd_ratio",
+	"kd_ratio":  "kd_ratio",
+	"created":   "created_at",
+	"updated":   "updated_at",
+}
+
+// ParsedSearchQuery is a search box input split into structured filters and
+// the remaining free text.
+type ParsedSearchQuery struct {
+	Text    string
+	Filters dto.SearchFilters
+}
+
+// IsStructured reports whether the query used any operator besides name:.
+func (p *ParsedSearchQuery) IsStructured() bool {
+	return p.Filters.HasStatFilters() || p.Filters.SortBy != "" || p.Filters.SearchType != ""
+}
+
+type searchToken struct {
+	raw    string
+	key    string
+	value  string
+	quoted bool
+}
+
+// ParseSearchQuery understands inputs such as
+//
+//	hero:haze rank:>=Archon country:DE wr:>55 name:"foo bar"
+//
+// Supported keys are name, steamid, match, hero, rank, country, wr, kd,
+// matches and sort. Numeric keys accept =, >, >=, <, <= and inclusive a..b ranges. Tokens
+// with an unknown key are kept as free text so nicknames and links that
+// contain a colon still work.
+func ParseSearchQuery(input string) (*ParsedSearchQuery, error) {
+	tokens := tokenizeSearchQuery(input)
+	parsed := &ParsedSearchQuery{}
+	var text []string
+
+	for _, token := range tokens {
+		if token.key == "" {
+			if token.value != "" {
+				text = append(text, token.value)
+			}
+			continue
+		}
+
+		if token.value == "" {
+			return nil, searchQueryError(token, "missing value")
+		}
+
+		if err := parsed.apply(token, &text); err != nil {
+			return nil, err
+		}
+	}
+
+	parsed.Text = strings.Join(text, " ")
+	if err := parsed.Filters.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", cErrors.ErrInvalidQuery, err)
+	}
+
+	return parsed, nil
+}
+
+func (p *ParsedSearchQuery) apply(token searchToken, text *[]string) error {
+	filters := &p.Filters
+
+	switch token.key {
+	case "name":
+		*text = append(*text, token.value)
+	case "steamid", "id":
+		filters.SearchType = "steamid"
+		*text = append(*text, token.value)
+	case "match":
+		if _, ok := parseMatchID(token.value); !ok {
+			return searchQueryError(token, "expected a numeric match ID")
+		}
+		filters.SearchType = "match"
+		*text = append(*text, token.value)
+	case "hero":
+		filters.Hero = strings.Join(strings.Fields(token.value), " ")
+	case "country":
+		if len(token.value) != 2 || !isASCIILetters(token.value) {
+			return searchQueryError(token, "expected a two-letter country code such as DE")
+		}
+		filters.Country = strings.ToUpper(token.value)
+	case "rank":
+		return parseRankFilter(token, &filters.MinRank, &filters.MaxRank)
+	case "matches":
+		return parseIntFilter(token, &filters.MinMatches, &filters.MaxMatches)
+	case "wr", "winrate":
+		return parseFloatFilter(token, &filters.MinWinRate, &filters.MaxWinRate)
+	case "kd", "kda":
+		return parseFloatFilter(token, &filters.MinKDRatio, &filters.MaxKDRatio)
+	case "sort":
+		return parseSortFilter(token, filters)
+	default:
+		*text = append(*text, token.raw)
+	}
+
+	return nil
+}
+
+// tokenizeSearchQuery splits on whitespace outside double quotes. The key of
+// a key:value token is lower-cased; quotes are removed from values. An
+// unbalanced quote is most likely part of a nickname, so the whole input is
+// then returned as a single free-text token.
+func tokenizeSearchQuery(input string) []searchToken {
+	var tokens []searchToken
+	var current, raw strings.Builder
+	var token searchToken
+	inQuote, started := false, false
+
+	flush := func() {
+		if started {
+			token.value = current.String()
+			token.raw = raw.String()
+			if token.key != "" && !isSearchQueryKey(token.key) {
+				token.key, token.value = "", token.raw
+			}
+			tokens = append(tokens, token)
+		}
+		current.Reset()
+		raw.Reset()
+		token = searchToken{}
+		started = false
+	}
+
+	for _, r := range input {
+		switch {
+		case r == '"':
+			inQuote = !inQuote
+			token.quoted = true
+			started = true
+			raw.WriteRune(r)
+		case unicode.IsSpace(r) && !inQuote:
+			flush()
+		case r == ':' && !inQuote && token.key == "" && !token.quoted && current.Len() > 0:
+			token.key = strings.ToLower(current.String())
+			current.Reset()
+			raw.WriteRune(r)
+		default:
+			current.WriteRune(r)
+			raw.WriteRune(r)
+			started = true
+		}
+	}
+
+	if inQuote {
+		text := strings.Join(strings.Fields(input), " ")
+		return []searchToken{{raw: text, value: text}}
+	}
+	flush()
+
+	return tokens
+}
+
+func isSearchQueryKey(key string) bool {
+	switch key {
+	case "name", "steamid", "id", "match", "hero", "country", "rank", "matches", "wr", "winrate", "kd", "kda", "sort":
+		return true
+	default:
+		return false
+	}
+}
+
+func searchQueryError(token searchToken, format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s: %s", cErrors.ErrInvalidQuery, token.raw, fmt.Sprintf(format, args...))
+}
+
+// splitComparison separates a leading operator from its operand. Ranges are
+// reported with the ".." operator and both operands.
+func splitComparison(value string) (op, left, right string) {
+	if lo, hi, ok := strings.Cut(value, ".."); ok {
+		return "..", lo, hi
+	}
+	for _, candidate := range []string{">=", "<=", ">", "<", "="} {
+		if strings.HasPrefix(value, candidate) {
+			return candidate, strings.TrimSpace(value[len(candidate):]), ""
+		}
+	}
+	return "=", value, ""
+}
+
+func parseIntFilter(token searchToken, lower, upper *int) error {
+	op, left, right := splitComparison(token.value)
+
+	parse := func(value string) (int, error) {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return 0, searchQueryError(token, "%q is not a non-negative whole number", value)
+		}
+		return n, nil
+	}
+
+	lo, err := parse(left)
+	if err != nil {
+		return err
+	}
+
+	switch op {
+	case "..":
+		hi, err := parse(right)
+		if err != nil {
+			return err
+		}
+		*lower, *upper = lo, hi
+	case ">=":
+		*lower = lo
+	case ">":
+		*lower = lo + 1
+	case "<=":
+		*upper = lo
+	case "<":
+		*upper = lo - 1
+	default:
+		*lower, *upper = lo, lo
+	}
+
+	if (op == "<" || op == "<=") && *upper <= 0 {
+		return searchQueryError(token, "upper bound must be greater than zero")
+	}
+	return nil
+}
+
+func parseFloatFilter(token searchToken, lower, upper *float64) error {
+	op, left, right := splitComparison(token.value)
+
+	parse := func(value string) (float64, error) {
+		f, err := strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+		if err != nil || f < 0 || math.IsInf(f, 0) || math.IsNaN(f) {
+			return 0, searchQueryError(token, "%q is not a non-negative number", value)
+		}
+		return f, nil
+	}
+
+	lo, err := parse(left)
+	if err != nil {
+		return err
+	}
+
+	// Filters are inclusive, so strict comparisons move to the next
+	// representable value.
+	switch op {
+	case "..":
+		hi, err := parse(right)
+		if err != nil {
+			return err
+		}
+		*lower, *upper = lo, hi
+	case ">=":
+		*lower = lo
+	case ">":
+		*lower = math.Nextafter(lo, math.Inf(1))
+	case "<=":
+		*upper = lo
+	case "<":
+		*upper = math.Nextafter(lo, math.Inf(-1))
+	default:
+		*lower, *upper = lo, lo
+	}
+
+	if (op == "<" || op == "<=") && *upper <= 0 {
+		return searchQueryError(token, "upper bound must be greater than zero")
+	}
+	return nil
+}
+
+// parseRankFilter sets rank bounds within the ranked range; the search then
+// leaves out unranked players.
+func parseRankFilter(token searchToken, lower, upper *int) error {
+	op, left, right := splitComparison(token.value)
+
+	lo, hi, err := parseRankOperand(token, left)
+	if err != nil {
+		return err
+	}
+
+	switch op {
+	case "..":
+		_, rangeHi, err := parseRankOperand(token, right)
+		if err != nil {
+			return err
+		}
+		*lower, *upper = lo, rangeHi
+	case ">=":
+		*lower = lo
+	case ">":
+		*lower = nextRank(hi)
+		if *lower > domain.MaxPlayerRank {
+			return searchQueryError(token, "there is no rank above %s", strings.TrimSpace(left))
+		}
+	case "<=":
+		*upper = hi
+	case "<":
+		*upper = previousRank(lo)
+		if *upper < domain.MinPlayerRank {
+			return searchQueryError(token, "there is no rank below %s", strings.TrimSpace(left))
+		}
+	default:
+		*lower, *upper = lo, hi
+	}
+
+	return nil
+}
+
+// parseRankOperand accepts a rank value such as 73 or a tier name with an
+// optional sub-tier ("Archon", "archon3", "Archon 3"). A bare tier name
+// covers all of its sub-tiers.
+func parseRankOperand(token searchToken, value string) (int, int, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+
+	if n, err := strconv.Atoi(value); err == nil {
+		if !isValidRank(n) {
+			return 0, 0, searchQueryError(token, "%d is not a valid rank", n)
+		}
+		return n, n, nil
+	}
+
+	name := strings.TrimRightFunc(value, unicode.IsDigit)
+	subTierValue := value[len(name):]
+	name = strings.TrimSpace(name)
+
+	tier := -1
+	for i, tierName := range rankTiers {
+		if tierName == name {
+			tier = i
+			break
+		}
+	}
+	if tier <= 0 {
+		return 0, 0, searchQueryError(token, "unknown rank %q, expected one of %s", name, strings.Join(rankTiers[1:], ", "))
+	}
+
+	if subTierValue == "" {
+		return tier*10 + 1, tier*10 + maxRankSubTier, nil
+	}
+
+	subTier, _ := strconv.Atoi(subTierValue)
+	if subTier < 1 || subTier > maxRankSubTier {
+		return 0, 0, searchQueryError(token, "sub-tier must be between 1 and %d", maxRankSubTier)
+	}
+	return tier*10 + subTier, tier*10 + subTier, nil
+}
+
+func isValidRank(rank int) bool {
+	if rank < domain.MinPlayerRank || rank > domain.MaxPlayerRank {
+		return false
+	}
+	tier, subTier := rank/10, rank%10
+	return tier >= 1 && tier < len(rankTiers) && subTier >= 1 && subTier <= maxRankSubTier
+}
+
+func nextRank(rank int) int {
+	if rank%10 >= maxRankSubTier {
+		return (rank/10+1)*10 + 1
+	}
+	return rank + 1
+}
+
+func previousRank(rank int) int {
+	if rank%10 <= 1 {
+		if rank/10 <= 1 {
+			return 0
+		}
+		return (rank/10-1)*10 + maxRankSubTier
+	}
+	return rank - 1
+}
+
+// parseSortFilter handles sort:<key> and sort:<key>:<asc|desc>. Stat keys
+// default to descending, names and dates to ascending.
+func parseSortFilter(token searchToken, filters *dto.SearchFilters) error {
+	key, order, _ := strings.Cut(strings.ToLower(token.value), ":")
+
+	sortBy, ok := searchSortAliases[key]
+	if !ok {
+		return searchQueryError(token, "cannot sort by %q", key)
+	}
+
+	switch order {
+	case "":
+		order = "desc"
+		if sortBy == "nickname" || sortBy == "created_at" || sortBy == "updated_at" {
+			order = "asc"
+		}
+	case "asc", "desc":
+	default:
+		return searchQueryError(token, "sort order must be asc or desc")
+	}
+
+	filters.SortBy, filters.SortOrder = sortBy, order
+	return nil
+}
+
+func isASCIILetters(value string) bool {
+	for _, r := range value {
+		if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') {
+			return false
+		}
+	}
+	return true
+}
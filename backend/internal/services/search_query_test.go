@@ -0,0 +1,33 @@
+package services
+
+import "testing"
+
+func TestParseSearchQueryText(t *testing.T) {
+	tests := []struct {
+		name       string
+		input      string
+		wantText   string
+		structured bool
+	}{
+		{"plain nickname", "haze main", "haze main", false},
+		{"quoted name", `name:"foo bar"`, "foo bar", false},
+		{"unknown key kept", "https://steamcommunity.com/id/x", "https://steamcommunity.com/id/x", false},
+		{"filters and text", `hero:haze rank:>=Archon "foo bar"`, "foo bar", true},
+		{"stray quote", `foo"bar`, `foo"bar`, false},
+		{"stray quote with operators", `hero:haze  "foo`, `hero:haze "foo`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed, err := ParseSearchQuery(tt.input)
+			if err != nil {
+				t.Fatalf("ParseSearchQuery(%q) error = %v", tt.input, err)
+			}
+			if parsed.Text != tt.wantText {
+				t.Errorf("Text = %q, want %q", parsed.Text, tt.wantText)
+			}
+			if parsed.IsStructured() != tt.structured {
+				t.Errorf("IsStructured() = %v, want %v", parsed.IsStructured(), tt.structured)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package services
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/quenyu/deadlock-stats/internal/domain"
+	"github.com/quenyu/deadlock-stats/internal/dto"
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// aliasRelevanceFactor keeps matches on former nicknames below matches on
+// current ones with a comparable score.
+const aliasRelevanceFactor = 0.5
+
+// foldNickname mirrors the search_fold() SQL function: accents are stripped
+// and the result is lower-cased, so "Ñíko" and "niko" compare equal.
+func foldNickname(value string) string {
+	t := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	folded, _, err := transform.String(t, value)
+	if err != nil {
+		folded = value
+	}
+	return strings.ToLower(folded)
+}
+
+// estimateRelevance scores results that did not come from the ranked
+// database search, such as Deadlock API hits.
+func estimateRelevance(query, nickname string) float64 {
+	q, n := foldNickname(query), foldNickname(nickname)
+
+	switch {
+	case q == "" || n == "":
+		return 0
+	case n == q:
+		return 1.0
+	case strings.HasPrefix(n, q):
+		return 0.9
+	case strings.Contains(n, q):
+		return 0.7
+	default:
+		return 0.4
+	}
+}
+
+func rankedScores(ranked []domain.RankedUser) map[string]float64 {
+	scores := make(map[string]float64, len(ranked))
+	for _, user := range ranked {
+		scores[user.SteamID] = user.Relevance
+	}
+	return scores
+}
+
+func rankedUsers(ranked []domain.RankedUser) []domain.User {
+	users := make([]domain.User, len(ranked))
+	for i, user := range ranked {
+		users[i] = user.User
+	}
+	return users
+}
+
+// applyRelevance fills UserSearchResult.Relevance, preferring scores computed
+// by Postgres and falling back to estimateRelevance.
+func applyRelevance(results []dto.UserSearchResult, query string, scores map[string]float64) {
+	for i := range results {
+		if results[i].MatchedAlias != "" {
+			continue
+		}
+		if score, ok := scores[results[i].SteamID]; ok {
+			results[i].Relevance = score
+			continue
+		}
+		results[i].Relevance = estimateRelevance(query, results[i].Nickname)
+	}
+}
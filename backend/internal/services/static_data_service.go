@@ -1,11 +1,13 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"sync"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/quenyu/deadlock-stats/internal/clients/deadlockapi"
@@ -16,13 +18,25 @@ import (
 const (
 	ranksURL  = "https://assets.deadlock-api.com/v2/ranks"
 	heroesURL = "https://assets.deadlock-api.com/v2/heroes"
+	itemsURL  = "https://assets.deadlock-api.com/v2/items"
 )
 
+const (
+	staticDataRefreshInterval = 6 * time.Hour
+	staticDataRetryMin        = 5 * time.Second
+	staticDataRetryMax        = 5 * time.Minute
+)
+
+// HeroAbilitySlots is how many ability slots every hero has.
+const HeroAbilitySlots = 4
+
 type StaticDataService struct {
 	logger         *zap.Logger
 	Ranks          map[int]deadlockapi.RankV2
 	Heroes         map[string]deadlockapi.HeroV2
 	HeroesByHeroID map[int]deadlockapi.HeroV2
+	items          map[int]deadlockapi.ItemV2
+	itemsByClass   map[string]deadlockapi.ItemV2
 	mx             sync.RWMutex
 }
 
@@ -32,6 +46,33 @@ func NewStaticDataService(logger *zap.Logger) *StaticDataService {
 		Ranks:          make(map[int]deadlockapi.RankV2),
 		Heroes:         make(map[string]deadlockapi.HeroV2),
 		HeroesByHeroID: make(map[int]deadlockapi.HeroV2),
+		items:          make(map[int]deadlockapi.ItemV2),
+		itemsByClass:   make(map[string]deadlockapi.ItemV2),
+	}
+}
+
+// Start loads the static data and reloads it every
+// staticDataRefreshInterval until ctx is cancelled. Failed loads are retried
+// with exponential backoff.
+func (s *StaticDataService) Start(ctx context.Context) {
+	retry := staticDataRetryMin
+	for {
+		delay := staticDataRefreshInterval
+		if err := s.LoadStaticData(); err != nil {
+			s.logger.Error("Failed to load static data", zap.Duration("retryIn", retry), zap.Error(err))
+			delay = retry
+			retry = min(retry*2, staticDataRetryMax)
+		} else {
+			retry = staticDataRetryMin
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
 	}
 }
 
@@ -46,6 +87,10 @@ func (s *StaticDataService) LoadStaticData() error {
 		return s.loadHeroes()
 	})
 
+	g.Go(func() error {
+		return s.loadItems()
+	})
+
 	if err := g.Wait(); err != nil {
 		return err
 	}
@@ -71,6 +116,15 @@ func (s *StaticDataService) loadHeroes() error {
 	return s.parseAndStoreHeroes(data)
 }
 
+func (s *StaticDataService) loadItems() error {
+	data, err := s.fetchDataFromURL(itemsURL)
+	if err != nil {
+		return fmt.Errorf("failed to fetch items: %w", err)
+	}
+
+	return s.parseAndStoreItems(data)
+}
+
 func (s *StaticDataService) fetchDataFromURL(url string) ([]byte, error) {
 	resp, err := http.Get(url)
 	if err != nil {
@@ -96,13 +150,15 @@ func (s *StaticDataService) parseAndStoreRanks(data []byte) error {
 		return fmt.Errorf("failed to decode ranks JSON: %w", err)
 	}
 
-	s.mx.Lock()
-	defer s.mx.Unlock()
-
+	byTier := make(map[int]deadlockapi.RankV2, len(ranks))
 	for _, rank := range ranks {
-		s.Ranks[rank.Tier] = rank
+		byTier[rank.Tier] = rank
 	}
 
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	s.Ranks = byTier
+
 	s.logger.Info("Successfully loaded ranks from API", zap.Int("count", len(s.Ranks)))
 	return nil
 }
@@ -113,18 +169,97 @@ func (s *StaticDataService) parseAndStoreHeroes(data []byte) error {
 		return fmt.Errorf("failed to decode heroes JSON: %w", err)
 	}
 
+	byClass := make(map[string]deadlockapi.HeroV2, len(heroes))
+	byID := make(map[int]deadlockapi.HeroV2, len(heroes))
+	for _, hero := range heroes {
+		byClass[hero.ClassName] = hero
+		byID[hero.ID] = hero
+	}
+
 	s.mx.Lock()
 	defer s.mx.Unlock()
+	s.Heroes, s.HeroesByHeroID = byClass, byID
 
-	for _, hero := range heroes {
-		s.Heroes[hero.ClassName] = hero
-		s.HeroesByHeroID[hero.ID] = hero
+	s.logger.Info("Successfully loaded heroes", zap.Int("count", len(s.Heroes)))
+	return nil
+}
+
+func (s *StaticDataService) parseAndStoreItems(data []byte) error {
+	var items []deadlockapi.ItemV2
+	if err := json.Unmarshal(data, &items); err != nil {
+		return fmt.Errorf("failed to decode items JSON: %w", err)
 	}
 
-	s.logger.Info("Successfully loaded heroes", zap.Int("count", len(s.Heroes)))
+	byID := make(map[int]deadlockapi.ItemV2, len(items))
+	byClass := make(map[string]deadlockapi.ItemV2, len(items))
+	for _, item := range items {
+		byID[item.ID] = item
+		byClass[item.ClassName] = item
+	}
+
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	s.items, s.itemsByClass = byID, byClass
+
+	s.logger.Info("Successfully loaded items", zap.Int("count", len(s.items)))
 	return nil
 }
 
+// GameDataLoaded reports whether heroes and items have been loaded.
+func (s *StaticDataService) GameDataLoaded() bool {
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+	return len(s.HeroesByHeroID) > 0 && len(s.items) > 0
+}
+
+func (s *StaticDataService) GetHero(heroID int) (deadlockapi.HeroV2, bool) {
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+	hero, ok := s.HeroesByHeroID[heroID]
+	return hero, ok
+}
+
+func (s *StaticDataService) GetRank(tier int) (deadlockapi.RankV2, bool) {
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+	rank, ok := s.Ranks[tier]
+	return rank, ok
+}
+
+func (s *StaticDataService) GetItem(itemID int) (deadlockapi.ItemV2, bool) {
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+	item, ok := s.items[itemID]
+	return item, ok
+}
+
+// HeroAbilities returns a hero's abilities by slot: index 0 holds slot 1.
+// Slots the assets API does not list are left zero.
+func (s *StaticDataService) HeroAbilities(heroID int) ([HeroAbilitySlots]deadlockapi.ItemV2, bool) {
+	var abilities [HeroAbilitySlots]deadlockapi.ItemV2
+
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+
+	hero, ok := s.HeroesByHeroID[heroID]
+	if !ok {
+		return abilities, false
+	}
+	for slot := 1; slot <= HeroAbilitySlots; slot++ {
+		className, ok := hero.Items[fmt.Sprintf("signature%d", slot)]
+		if !ok {
+			continue
+		}
+		if ability, ok := s.itemsByClass[className]; ok {
+			abilities[slot-1] = ability
+		}
+	}
+	return abilities, true
+}
+
 func (s *StaticDataService) GetRanksHandler(c echo.Context) error {
-	return c.JSON(http.StatusOK, s.Ranks)
+	s.mx.RLock()
+	ranks := s.Ranks
+	s.mx.RUnlock()
+	return c.JSON(http.StatusOK, ranks)
 }
@@ -0,0 +1,187 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/quenyu/deadlock-stats/internal/clients/steam"
+	cErrors "github.com/quenyu/deadlock-stats/internal/errors"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+const (
+	steamID64Base      int64 = 76561197960265728
+	maxSteamAccountID  int64 = 4294967295
+	vanityCacheTTL           = 24 * time.Hour
+	vanityNotFoundTTL        = 10 * time.Minute
+	vanityNotFoundMark       = "-"
+)
+
+var (
+	steamID3Regex      = regexp.MustCompile(`^\[?U:1:(\d+)\]?$`)
+	steamLegacyIDRegex = regexp.MustCompile(`^STEAM_[0-5]:([01]):(\d+)$`)
+	vanityNameRegex    = regexp.MustCompile(`^[A-Za-z0-9_-]{2,32}$`)
+)
+
+// SteamIDResolver normalises every supported way of referring to a Steam
+// account (SteamID64, SteamID3, legacy STEAM_X:Y:Z, 32-bit account ID,
+// vanity name or steamcommunity.com link) into a canonical SteamID64.
+type SteamIDResolver struct {
+	steamClient *steam.Client
+	redisClient *redis.Client
+	logger      *zap.Logger
+}
+
+func NewSteamIDResolver(steamClient *steam.Client, redisClient *redis.Client, logger *zap.Logger) *SteamIDResolver {
+	return &SteamIDResolver{
+		steamClient: steamClient,
+		redisClient: redisClient,
+		logger:      logger.Named("SteamIDResolver"),
+	}
+}
+
+// Resolve returns the SteamID64 for the given identifier. Vanity names are
+// looked up through the Steam Web API and cached in Redis.
+func (r *SteamIDResolver) Resolve(ctx context.Context, input string) (string, error) {
+	input = strings.TrimSpace(input)
+	if input == "" {
+		return "", cErrors.ErrInvalidSteamID
+	}
+
+	if steamID, ok, err := r.resolveProfileURL(ctx, input); ok {
+		return steamID, err
+	}
+
+	if steamID, ok := ParseSteamID(input); ok {
+		return steamID, nil
+	}
+
+	if !vanityNameRegex.MatchString(input) {
+		return "", cErrors.ErrInvalidSteamID
+	}
+
+	return r.resolveVanity(ctx, input)
+}
+
+// ParseSteamID converts identifiers that can be resolved without a network
+// call into a SteamID64.
+func ParseSteamID(input string) (string, bool) {
+	input = strings.TrimSpace(input)
+
+	if matches := steamID3Regex.FindStringSubmatch(input); matches != nil {
+		return accountIDStringToSteamID64(matches[1])
+	}
+
+	if matches := steamLegacyIDRegex.FindStringSubmatch(strings.ToUpper(input)); matches != nil {
+		y, _ := strconv.ParseInt(matches[1], 10, 64)
+		z, err := strconv.ParseInt(matches[2], 10, 64)
+		if err != nil {
+			return "", false
+		}
+		return accountIDToSteamID64(z*2 + y)
+	}
+
+	id, err := strconv.ParseInt(input, 10, 64)
+	if err != nil || id <= 0 {
+		return "", false
+	}
+
+	if id <= maxSteamAccountID {
+		return accountIDToSteamID64(id)
+	}
+
+	if id > steamID64Base && id-steamID64Base <= maxSteamAccountID {
+		return strconv.FormatInt(id, 10), true
+	}
+
+	return "", false
+}
+
+func accountIDStringToSteamID64(value string) (string, bool) {
+	accountID, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return "", false
+	}
+	return accountIDToSteamID64(accountID)
+}
+
+func accountIDToSteamID64(accountID int64) (string, bool) {
+	if accountID <= 0 || accountID > maxSteamAccountID {
+		return "", false
+	}
+	return strconv.FormatInt(accountID+steamID64Base, 10), true
+}
+
+// resolveProfileURL handles steamcommunity.com/profiles/<id> and
+// steamcommunity.com/id/<vanity> links, with or without a scheme.
+func (r *SteamIDResolver) resolveProfileURL(ctx context.Context, input string) (string, bool, error) {
+	lower := strings.ToLower(input)
+	if !strings.Contains(lower, "steamcommunity.com/") {
+		return "", false, nil
+	}
+
+	if !strings.Contains(lower, "://") {
+		input = "https://" + input
+	}
+
+	parsedURL, err := url.Parse(input)
+	if err != nil || !isSteamCommunityHost(parsedURL.Hostname()) {
+		return "", true, cErrors.ErrInvalidSteamID
+	}
+
+	segments := strings.Split(strings.Trim(parsedURL.Path, "/"), "/")
+	if len(segments) < 2 || segments[1] == "" {
+		return "", true, cErrors.ErrInvalidSteamID
+	}
+
+	switch strings.ToLower(segments[0]) {
+	case "profiles":
+		steamID, ok := ParseSteamID(segments[1])
+		if !ok {
+			return "", true, cErrors.ErrInvalidSteamID
+		}
+		return steamID, true, nil
+	case "id":
+		steamID, err := r.resolveVanity(ctx, segments[1])
+		return steamID, true, err
+	default:
+		return "", true, cErrors.ErrInvalidSteamID
+	}
+}
+
+func isSteamCommunityHost(host string) bool {
+	host = strings.ToLower(host)
+	return host == "steamcommunity.com" || strings.HasSuffix(host, ".steamcommunity.com")
+}
+
+func (r *SteamIDResolver) resolveVanity(ctx context.Context, vanity string) (string, error) {
+	cacheKey := fmt.Sprintf("steam-vanity:%s", strings.ToLower(vanity))
+
+	cached, err := r.redisClient.Get(ctx, cacheKey).Result()
+	if err == nil {
+		if cached == vanityNotFoundMark {
+			return "", cErrors.ErrPlayerNotFound
+		}
+		return cached, nil
+	}
+
+	steamID, err := r.steamClient.ResolveVanityURL(ctx, vanity)
+	if errors.Is(err, steam.ErrVanityNotFound) || (err == nil && steamID == "") {
+		r.redisClient.Set(ctx, cacheKey, vanityNotFoundMark, vanityNotFoundTTL)
+		return "", cErrors.ErrPlayerNotFound
+	}
+	if err != nil {
+		r.logger.Warn("Failed to resolve vanity name", zap.String("vanity", vanity), zap.Error(err))
+		return "", cErrors.ErrAPIUnavailable
+	}
+
+	r.redisClient.Set(ctx, cacheKey, steamID, vanityCacheTTL)
+	return steamID, nil
+}
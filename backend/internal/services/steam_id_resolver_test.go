@@ -0,0 +1,45 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	cErrors "github.com/quenyu/deadlock-stats/internal/errors"
+	"go.uber.org/zap"
+)
+
+func TestSteamIDResolverOffline(t *testing.T) {
+	const steamID = "76561197960287930"
+	resolver := NewSteamIDResolver(nil, nil, zap.NewNop())
+
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr error
+	}{
+		{"steamid64", steamID, steamID, nil},
+		{"account id", "22202", steamID, nil},
+		{"steamid3", "[U:1:22202]", steamID, nil},
+		{"legacy", "STEAM_0:0:11101", steamID, nil},
+		{"profile link", "https://steamcommunity.com/profiles/" + steamID, steamID, nil},
+		{"profile link without scheme", "steamcommunity.com/profiles/" + steamID + "/", steamID, nil},
+		{"subdomain", "https://www.steamcommunity.com/profiles/" + steamID, steamID, nil},
+		{"look-alike host", "https://evilsteamcommunity.com/profiles/" + steamID, "", cErrors.ErrInvalidSteamID},
+		{"look-alike suffix", "https://steamcommunity.com.evil.io/profiles/" + steamID, "", cErrors.ErrInvalidSteamID},
+		{"unknown path", "https://steamcommunity.com/groups/x", "", cErrors.ErrInvalidSteamID},
+		{"empty", "  ", "", cErrors.ErrInvalidSteamID},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolver.Resolve(context.Background(), tt.input)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Resolve(%q) error = %v, want %v", tt.input, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Resolve(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
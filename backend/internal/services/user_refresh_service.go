@@ -0,0 +1,162 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/quenyu/deadlock-stats/internal/clients/steam"
+	"github.com/quenyu/deadlock-stats/internal/domain"
+	"github.com/quenyu/deadlock-stats/internal/repositories"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultUserRefreshInterval   = time.Hour
+	defaultUserRefreshStaleAfter = 7 * 24 * time.Hour
+	defaultUserRefreshMaxUsers   = 1000
+)
+
+// UserRefreshService periodically re-fetches Steam player summaries for users
+// whose nickname/avatar have not been refreshed for a while.
+type UserRefreshService struct {
+	userRepository *repositories.UserRepository
+	steamClient    *steam.Client
+	logger         *zap.Logger
+
+	interval   time.Duration
+	staleAfter time.Duration
+	maxUsers   int
+}
+
+func NewUserRefreshService(
+	userRepository *repositories.UserRepository,
+	steamClient *steam.Client,
+	interval time.Duration,
+	staleAfter time.Duration,
+	maxUsers int,
+	logger *zap.Logger,
+) *UserRefreshService {
+	if interval <= 0 {
+		interval = defaultUserRefreshInterval
+	}
+	if staleAfter <= 0 {
+		staleAfter = defaultUserRefreshStaleAfter
+	}
+	if maxUsers <= 0 {
+		maxUsers = defaultUserRefreshMaxUsers
+	}
+
+	return &UserRefreshService{
+		userRepository: userRepository,
+		steamClient:    steamClient,
+		logger:         logger.Named("UserRefreshService"),
+		interval:       interval,
+		staleAfter:     staleAfter,
+		maxUsers:       maxUsers,
+	}
+}
+
+// Start runs the refresh loop until ctx is cancelled.
+func (s *UserRefreshService) Start(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.RefreshStaleUsers(ctx); err != nil {
+			s.logger.Error("Failed to refresh stale users", zap.Error(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RefreshStaleUsers refreshes up to maxUsers stale users in batches of
+// steam.MaxSteamIDsPerRequest and returns how many were updated.
+func (s *UserRefreshService) RefreshStaleUsers(ctx context.Context) (int, error) {
+	refreshed := 0
+	before := time.Now().Add(-s.staleAfter)
+
+	for processed := 0; processed < s.maxUsers; {
+		users, err := s.userRepository.FindStaleUsers(ctx, before, steam.MaxSteamIDsPerRequest)
+		if err != nil {
+			return refreshed, err
+		}
+		if len(users) == 0 {
+			break
+		}
+
+		count, err := s.refreshBatch(ctx, users)
+		refreshed += count
+		if err != nil {
+			return refreshed, err
+		}
+
+		processed += len(users)
+		if len(users) < steam.MaxSteamIDsPerRequest {
+			break
+		}
+	}
+
+	if refreshed > 0 {
+		s.logger.Info("Refreshed stale users", zap.Int("count", refreshed))
+	}
+
+	return refreshed, nil
+}
+
+func (s *UserRefreshService) refreshBatch(ctx context.Context, users []domain.User) (int, error) {
+	steamIDs := make([]string, len(users))
+	for i, user := range users {
+		steamIDs[i] = user.SteamID
+	}
+
+	summaries, err := s.steamClient.GetPlayerSummaries(ctx, steamIDs)
+	if err != nil {
+		return 0, err
+	}
+
+	summariesByID := make(map[string]steam.PlayerSummary, len(summaries))
+	for _, summary := range summaries {
+		summariesByID[summary.SteamID] = summary
+	}
+
+	refreshed := 0
+	var unknown []uuid.UUID
+
+	for _, user := range users {
+		summary, ok := summariesByID[user.SteamID]
+		if !ok || summary.PersonaName == "" {
+			unknown = append(unknown, user.ID)
+			continue
+		}
+
+		if summary.PersonaName != user.Nickname {
+			s.logger.Debug("Player renamed",
+				zap.String("steamID", user.SteamID),
+				zap.String("from", user.Nickname),
+				zap.String("to", summary.PersonaName))
+		}
+
+		user.Nickname = summary.PersonaName
+		user.AvatarURL = summary.AvatarFull
+		user.ProfileURL = summary.ProfileURL
+		user.CountryCode = summary.LocCountryCode
+
+		if err := s.userRepository.RefreshSteamProfile(ctx, &user); err != nil {
+			s.logger.Warn("Failed to store refreshed user", zap.String("steamID", user.SteamID), zap.Error(err))
+			continue
+		}
+		refreshed++
+	}
+
+	if err := s.userRepository.TouchUsers(ctx, unknown); err != nil {
+		s.logger.Warn("Failed to mark unknown users as refreshed", zap.Error(err))
+	}
+
+	return refreshed, nil
+}
@@ -96,3 +96,24 @@ func ValidateAbilitySlot(slot int) error {
 	}
 	return nil
 }
+
+func ValidateHeroID(heroID int) error {
+	if heroID <= 0 {
+		return cErrors.ErrInvalidHeroID
+	}
+	return nil
+}
+
+func ValidateBuildPhaseName(name string) error {
+	name = strings.TrimSpace(name)
+
+	if name == "" {
+		return cErrors.ErrInvalidPhase
+	}
+
+	if len(name) > 32 {
+		return cErrors.ErrFieldTooLong
+	}
+
+	return nil
+}
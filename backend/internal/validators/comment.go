@@ -0,0 +1,210 @@
+package validators
+
+import (
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	cErrors "github.com/quenyu/deadlock-stats/internal/errors"
+)
+
+const MaxCommentLength = 5000
+
+var (
+	// linkDestinationPattern matches the destination of inline links and of
+	// reference definitions, which may start on the next line.
+	linkDestinationPattern = regexp.MustCompile(`(?m)(\]\(|^[ ]{0,3}\[[^\]\n]+\]:)([ \t]*\n?[ \t]*)([^\s)]+)`)
+	linkSchemePattern      = regexp.MustCompile(`^([a-z][a-z0-9+.-]*):`)
+	percentEscapePattern   = regexp.MustCompile(`%[0-9a-fA-F]{2}`)
+	blankLinesPattern      = regexp.MustCompile(`\n{3,}`)
+)
+
+// allowedLinkSchemes are the schemes links may use; links without a scheme
+// are relative and allowed too.
+var allowedLinkSchemes = map[string]bool{
+	"http":   true,
+	"https":  true,
+	"mailto": true,
+}
+
+// SanitizeCommentBody makes a markdown comment safe to render. Control and
+// bidi override characters are dropped, '<' is escaped outside code so that
+// no raw HTML gets through, and links are disabled unless they are relative
+// or use an allowed scheme. Code spans and fenced code blocks are kept as
+// written; renderers escape them.
+func SanitizeCommentBody(body string) string {
+	body = strings.ToValidUTF8(body, "")
+	body = strings.ReplaceAll(body, "\r\n", "\n")
+	body = strings.ReplaceAll(body, "\r", "\n")
+	body = strings.Map(func(r rune) rune {
+		if r == '\n' || r == '\t' {
+			return r
+		}
+		if unicode.IsControl(r) || (r >= '\u202a' && r <= '\u202e') || (r >= '\u2066' && r <= '\u2069') {
+			return -1
+		}
+		return r
+	}, body)
+
+	var out strings.Builder
+	var text strings.Builder
+	fence := ""
+	for _, line := range strings.SplitAfter(body, "\n") {
+		if fence != "" {
+			out.WriteString(line)
+			if closesFence(line, fence) {
+				fence = ""
+			}
+			continue
+		}
+		if opening, ok := openingFence(line); ok {
+			out.WriteString(sanitizeMarkdownText(text.String()))
+			text.Reset()
+			out.WriteString(line)
+			fence = opening
+			continue
+		}
+		text.WriteString(line)
+	}
+	out.WriteString(sanitizeMarkdownText(text.String()))
+
+	body = blankLinesPattern.ReplaceAllString(out.String(), "\n\n")
+	return strings.TrimSpace(body)
+}
+
+// openingFence returns the run of backticks or tildes that opens a fenced
+// code block on line. As in CommonMark, the run is at least three long and
+// indented at most three spaces, and the info string of a backtick fence
+// has no backtick.
+func openingFence(line string) (string, bool) {
+	trimmed := strings.TrimLeft(line, " ")
+	if len(line)-len(trimmed) > 3 || !(strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~")) {
+		return "", false
+	}
+	run := len(trimmed) - len(strings.TrimLeft(trimmed, trimmed[:1]))
+	if trimmed[0] == '`' && strings.Contains(trimmed[run:], "`") {
+		return "", false
+	}
+	return trimmed[:run], true
+}
+
+// closesFence reports whether line closes the block opened by fence: a run
+// of the same character at least as long, indented at most three spaces and
+// followed only by whitespace.
+func closesFence(line, fence string) bool {
+	trimmed := strings.TrimLeft(line, " ")
+	if len(line)-len(trimmed) > 3 || !strings.HasPrefix(trimmed, fence) {
+		return false
+	}
+	return strings.TrimSpace(strings.TrimLeft(trimmed, fence[:1])) == ""
+}
+
+// sanitizeMarkdownText escapes '<' and disables unsafe links outside the
+// code spans of text.
+func sanitizeMarkdownText(text string) string {
+	var out, plain strings.Builder
+	flush := func() {
+		escaped := strings.ReplaceAll(plain.String(), "<", "&lt;")
+		out.WriteString(disableUnsafeLinks(escaped))
+		plain.Reset()
+	}
+
+	for i := 0; i < len(text); {
+		if text[i] != '`' {
+			plain.WriteByte(text[i])
+			i++
+			continue
+		}
+		run := backtickRun(text[i:])
+		if end := closingBacktickRun(text[i+run:], run); end >= 0 {
+			flush()
+			span := text[i : i+run+end+run]
+			out.WriteString(span)
+			i += len(span)
+			continue
+		}
+		plain.WriteString(text[i : i+run])
+		i += run
+	}
+	flush()
+	return out.String()
+}
+
+// disableUnsafeLinks points links that fail safeLinkDestination at "#".
+func disableUnsafeLinks(text string) string {
+	return linkDestinationPattern.ReplaceAllStringFunc(text, func(link string) string {
+		parts := linkDestinationPattern.FindStringSubmatch(link)
+		if safeLinkDestination(parts[3]) {
+			return link
+		}
+		return parts[1] + parts[2] + "#"
+	})
+}
+
+// safeLinkDestination reports whether a link destination is relative or uses
+// an allowed scheme once it is read the way renderers and browsers read it:
+// with entities, percent-escapes and backslash escapes decoded, and with
+// whitespace and control characters removed.
+func safeLinkDestination(destination string) bool {
+	for i := 0; i < 3; i++ {
+		decoded := html.UnescapeString(destination)
+		decoded = percentEscapePattern.ReplaceAllStringFunc(decoded, func(escape string) string {
+			b, _ := strconv.ParseUint(escape[1:], 16, 8)
+			return string(rune(b))
+		})
+		if decoded == destination {
+			break
+		}
+		destination = decoded
+	}
+
+	destination = strings.Map(func(r rune) rune {
+		if r == '\\' || r <= ' ' || unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, destination)
+	destination = strings.ToLower(strings.TrimLeft(destination, "<"))
+
+	scheme := linkSchemePattern.FindStringSubmatch(destination)
+	return scheme == nil || allowedLinkSchemes[scheme[1]]
+}
+
+func backtickRun(s string) int {
+	n := 0
+	for n < len(s) && s[n] == '`' {
+		n++
+	}
+	return n
+}
+
+// closingBacktickRun returns the offset of the first run of exactly n
+// backticks in s, or -1.
+func closingBacktickRun(s string, n int) int {
+	for i := 0; i < len(s); {
+		if s[i] != '`' {
+			i++
+			continue
+		}
+		run := backtickRun(s[i:])
+		if run == n {
+			return i
+		}
+		i += run
+	}
+	return -1
+}
+
+// ValidateCommentBody checks a sanitized comment body.
+func ValidateCommentBody(body string) error {
+	if body == "" {
+		return cErrors.ErrFieldRequired
+	}
+	if utf8.RuneCountInString(body) > MaxCommentLength {
+		return cErrors.ErrFieldTooLong
+	}
+	return nil
+}
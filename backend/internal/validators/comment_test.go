@@ -0,0 +1,67 @@
+package validators
+
+import "testing"
+
+func TestSanitizeCommentBody(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"plain text", "gg, nice build", "gg, nice build"},
+		{"raw html", "<img src=x onerror=alert(1)>", "&lt;img src=x onerror=alert(1)>"},
+		{"control and bidi characters", "a\x00b‮c", "abc"},
+		{"crlf and blank lines", "a\r\n\r\n\r\n\r\nb", "a\n\nb"},
+
+		{"http link", "[x](https://example.com/a_(b))", "[x](https://example.com/a_(b))"},
+		{"mailto link", "[m](mailto:a@b.c)", "[m](mailto:a@b.c)"},
+		{"relative link", "[b](/builds/123?x=1:2)", "[b](/builds/123?x=1:2)"},
+		{"javascript link", "[x](javascript:alert(1))", "[x](#))"},
+		{"mixed case scheme", "[x](JaVaScRiPt:alert(1))", "[x](#))"},
+		{"entity colon", "[x](javascript&#58;alert(1))", "[x](#))"},
+		{"entity letter", "[x](&#106;avascript:alert(1))", "[x](#))"},
+		{"percent colon", "[x](javascript%3Aalert(1))", "[x](#))"},
+		{"entity tab", "[x](java&#9;script:alert(1))", "[x](#))"},
+		{"backslash escape", "[x](javascript\\:alert(1))", "[x](#))"},
+		{"image", "![i](vbscript:msgbox)", "![i](#)"},
+		{"reference definition", "[x]: &#x6A;avascript:alert(1)", "[x]: #)"},
+		{"reference on next line", "[x]:\n  data:text/html,hi", "[x]:\n  #"},
+
+		{"code span", "`<b>[x](javascript:alert(1))</b>`", "`<b>[x](javascript:alert(1))</b>`"},
+		{"fenced code", "```\n<b>\n```\n<b>", "```\n<b>\n```\n&lt;b>"},
+		{"tilde fence", "~~~go\n<b>\n~~~", "~~~go\n<b>\n~~~"},
+		{
+			"backtick in info string",
+			"```x`\n[click](javascript:alert(1))\n<img src=x onerror=alert(1)>",
+			"```x`\n[click](#))\n&lt;img src=x onerror=alert(1)>",
+		},
+		{"short closing run", "````\n<b>\n```\n<b>", "````\n<b>\n```\n<b>"},
+		{"long closing run", "```\n<b>\n`````\n<b>", "```\n<b>\n`````\n&lt;b>"},
+		{"other fence character", "```\n<b>\n~~~\n<b>", "```\n<b>\n~~~\n<b>"},
+		{"indented code fence", "    ```\n<b>", "```\n&lt;b>"},
+		{"indented closing fence", "```\n<b>\n    ```\n<b>", "```\n<b>\n    ```\n<b>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SanitizeCommentBody(tt.input); got != tt.want {
+				t.Errorf("SanitizeCommentBody(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateCommentBody(t *testing.T) {
+	if err := ValidateCommentBody(""); err == nil {
+		t.Error("expected an error for an empty body")
+	}
+	long := make([]rune, MaxCommentLength+1)
+	for i := range long {
+		long[i] = 'é'
+	}
+	if err := ValidateCommentBody(string(long[:MaxCommentLength])); err != nil {
+		t.Errorf("unexpected error at the limit: %v", err)
+	}
+	if err := ValidateCommentBody(string(long)); err == nil {
+		t.Error("expected an error past the limit")
+	}
+}
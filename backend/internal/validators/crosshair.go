@@ -14,6 +14,16 @@ func ValidateCrosshairTitle(title string) error {
 	return nil
 }
 
+func ValidateCollectionName(name string) error {
+	if len(name) < 1 {
+		return cErrors.ErrFieldRequired
+	}
+	if len(name) > 100 {
+		return cErrors.ErrFieldTooLong
+	}
+	return nil
+}
+
 func ValidateCrosshairDescription(description string) error {
 	return ValidateDescription(description, 2000)
 }
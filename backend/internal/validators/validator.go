@@ -119,3 +119,53 @@ func getErrorMessage(fe validator.FieldError) string {
 		return fmt.Sprintf("%s validation failed on '%s'", field, tag)
 	}
 }
+
+// FieldErrors collects the validation failures of a request, one entry per
+// failing field. It wraps Cause, which decides the response status.
+type FieldErrors struct {
+	Cause  error
+	Fields []ValidationError
+}
+
+func NewFieldErrors(cause error) *FieldErrors {
+	return &FieldErrors{Cause: cause}
+}
+
+func (e *FieldErrors) Add(field, tag, value, message string) {
+	e.Fields = append(e.Fields, ValidationError{
+		Field:   field,
+		Message: message,
+		Tag:     tag,
+		Value:   value,
+	})
+}
+
+// Check adds err, if any, as the failure of field.
+func (e *FieldErrors) Check(field string, err error) {
+	if err != nil {
+		e.Add(field, "invalid", "", err.Error())
+	}
+}
+
+// Err returns e if any field failed, nil otherwise.
+func (e *FieldErrors) Err() error {
+	if len(e.Fields) == 0 {
+		return nil
+	}
+	return e
+}
+
+func (e *FieldErrors) Error() string {
+	if len(e.Fields) == 0 {
+		return e.Cause.Error()
+	}
+	msg := fmt.Sprintf("%s: %s: %s", e.Cause, e.Fields[0].Field, e.Fields[0].Message)
+	if len(e.Fields) > 1 {
+		msg += fmt.Sprintf(" (and %d more)", len(e.Fields)-1)
+	}
+	return msg
+}
+
+func (e *FieldErrors) Unwrap() error {
+	return e.Cause
+}
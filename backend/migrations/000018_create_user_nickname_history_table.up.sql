@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS user_nickname_history (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL,
+    nickname VARCHAR(255) NOT NULL,
+    first_seen_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_seen_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT fk_user
+        FOREIGN KEY(user_id)
+        REFERENCES users(id)
+        ON DELETE CASCADE,
+    CONSTRAINT uq_user_nickname UNIQUE (user_id, nickname)
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_nickname_history_user_id ON user_nickname_history(user_id);
+CREATE INDEX IF NOT EXISTS idx_user_nickname_history_last_seen_at ON user_nickname_history(last_seen_at DESC);
+CREATE INDEX IF NOT EXISTS idx_users_updated_at ON users(updated_at);
+
+-- Seed the history with the names we currently know about
+INSERT INTO user_nickname_history (user_id, nickname, first_seen_at, last_seen_at)
+SELECT id, nickname, created_at, updated_at FROM users
+ON CONFLICT (user_id, nickname) DO NOTHING;
@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS idx_user_nickname_history_nickname_trgm;
+DROP INDEX IF EXISTS idx_users_nickname_tsv;
+DROP INDEX IF EXISTS idx_users_nickname_search_trgm;
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS nickname_tsv,
+    DROP COLUMN IF EXISTS nickname_search;
+
+DROP FUNCTION IF EXISTS search_fold(TEXT);
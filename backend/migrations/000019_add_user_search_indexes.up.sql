@@ -0,0 +1,22 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+CREATE EXTENSION IF NOT EXISTS unaccent;
+
+-- unaccent() is only STABLE, so wrap it to be usable in generated columns and indexes.
+-- The folded form is what every nickname search compares against.
+CREATE OR REPLACE FUNCTION search_fold(value TEXT)
+RETURNS TEXT
+LANGUAGE sql
+IMMUTABLE
+PARALLEL SAFE
+STRICT
+AS $$
+    SELECT lower(public.unaccent('public.unaccent'::regdictionary, value))
+$$;
+
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS nickname_search TEXT GENERATED ALWAYS AS (search_fold(nickname)) STORED,
+    ADD COLUMN IF NOT EXISTS nickname_tsv TSVECTOR GENERATED ALWAYS AS (to_tsvector('simple', search_fold(nickname))) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_users_nickname_search_trgm ON users USING GIN (nickname_search gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_users_nickname_tsv ON users USING GIN (nickname_tsv);
+CREATE INDEX IF NOT EXISTS idx_user_nickname_history_nickname_trgm ON user_nickname_history USING GIN (search_fold(nickname) gin_trgm_ops);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_player_stats_kd_ratio;
+DROP INDEX IF EXISTS idx_player_stats_win_rate;
+DROP INDEX IF EXISTS idx_player_stats_total_matches;
+DROP INDEX IF EXISTS idx_player_stats_player_rank;
@@ -0,0 +1,4 @@
+CREATE INDEX IF NOT EXISTS idx_player_stats_player_rank ON player_stats(player_rank);
+CREATE INDEX IF NOT EXISTS idx_player_stats_total_matches ON player_stats(total_matches);
+CREATE INDEX IF NOT EXISTS idx_player_stats_win_rate ON player_stats(win_rate);
+CREATE INDEX IF NOT EXISTS idx_player_stats_kd_ratio ON player_stats(kd_ratio);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_player_match_stats_user_hero;
+DROP INDEX IF EXISTS idx_users_country_code;
+
+ALTER TABLE users DROP COLUMN IF EXISTS country_code;
@@ -0,0 +1,4 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS country_code VARCHAR(2);
+
+CREATE INDEX IF NOT EXISTS idx_users_country_code ON users(country_code);
+CREATE INDEX IF NOT EXISTS idx_player_match_stats_user_hero ON player_match_stats(user_id, lower(hero_name));
@@ -0,0 +1,11 @@
+CREATE TABLE crosshair_revisions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    crosshair_id UUID NOT NULL REFERENCES crosshairs(id) ON DELETE CASCADE,
+    title VARCHAR(255) NOT NULL,
+    description TEXT,
+    settings JSONB NOT NULL,
+    is_public BOOLEAN NOT NULL DEFAULT false,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_crosshair_revisions_crosshair_id ON crosshair_revisions(crosshair_id, created_at DESC);
@@ -0,0 +1 @@
+ALTER TABLE crosshairs ALTER COLUMN likes_count DROP NOT NULL;
@@ -0,0 +1,5 @@
+UPDATE crosshairs c
+SET likes_count = (SELECT COUNT(*) FROM crosshair_likes l WHERE l.crosshair_id = c.id);
+
+ALTER TABLE crosshairs ALTER COLUMN likes_count SET DEFAULT 0;
+ALTER TABLE crosshairs ALTER COLUMN likes_count SET NOT NULL;
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_crosshair_likes_crosshair_created;
+DROP INDEX IF EXISTS idx_crosshairs_color_family;
+DROP INDEX IF EXISTS idx_crosshairs_public_likes;
+DROP INDEX IF EXISTS idx_crosshairs_public_created;
+
+DROP FUNCTION IF EXISTS crosshair_color_family(TEXT);
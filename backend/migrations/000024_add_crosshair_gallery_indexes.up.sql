@@ -0,0 +1,40 @@
+-- Buckets a #rrggbb colour by hue; low saturation colours are white, gray
+-- or black. Returns NULL for anything that is not a hex colour.
+CREATE OR REPLACE FUNCTION crosshair_color_family(color TEXT) RETURNS TEXT AS $$
+    WITH rgb AS (
+        SELECT ('x' || substr(hex, 1, 2))::bit(8)::int / 255.0 AS r,
+               ('x' || substr(hex, 3, 2))::bit(8)::int / 255.0 AS g,
+               ('x' || substr(hex, 5, 2))::bit(8)::int / 255.0 AS b
+        FROM (SELECT lower(ltrim(color, '#')) AS hex) c
+        WHERE hex ~ '^[0-9a-f]{6}$'
+    ), bounds AS (
+        SELECT r, g, b, greatest(r, g, b) AS mx, least(r, g, b) AS mn FROM rgb
+    ), hue AS (
+        SELECT mx, mn,
+               CASE
+                   WHEN mx = mn THEN 0
+                   WHEN mx = r THEN mod((60 * (g - b) / (mx - mn) + 360)::numeric, 360)
+                   WHEN mx = g THEN 60 * ((b - r) / (mx - mn) + 2)
+                   ELSE 60 * ((r - g) / (mx - mn) + 4)
+               END AS h
+        FROM bounds
+    )
+    SELECT CASE
+        WHEN mx - mn < 0.15 THEN
+            CASE WHEN mx >= 0.8 THEN 'white' WHEN mx <= 0.2 THEN 'black' ELSE 'gray' END
+        WHEN h < 15 OR h >= 345 THEN 'red'
+        WHEN h < 45 THEN 'orange'
+        WHEN h < 70 THEN 'yellow'
+        WHEN h < 165 THEN 'green'
+        WHEN h < 195 THEN 'cyan'
+        WHEN h < 255 THEN 'blue'
+        WHEN h < 290 THEN 'purple'
+        ELSE 'pink'
+    END
+    FROM hue
+$$ LANGUAGE sql IMMUTABLE;
+
+CREATE INDEX IF NOT EXISTS idx_crosshairs_public_created ON crosshairs(created_at DESC, id DESC) WHERE is_public;
+CREATE INDEX IF NOT EXISTS idx_crosshairs_public_likes ON crosshairs(likes_count DESC, id DESC) WHERE is_public;
+CREATE INDEX IF NOT EXISTS idx_crosshairs_color_family ON crosshairs(crosshair_color_family(settings->>'color'));
+CREATE INDEX IF NOT EXISTS idx_crosshair_likes_crosshair_created ON crosshair_likes(crosshair_id, created_at);
@@ -0,0 +1,8 @@
+CREATE TABLE crosshair_daily_views (
+    crosshair_id UUID NOT NULL REFERENCES crosshairs(id) ON DELETE CASCADE,
+    day DATE NOT NULL,
+    views INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (crosshair_id, day)
+);
+
+CREATE INDEX idx_crosshair_daily_views_day ON crosshair_daily_views(day);
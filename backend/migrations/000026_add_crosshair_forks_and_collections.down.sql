@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS crosshair_collection_items;
+DROP TABLE IF EXISTS crosshair_collections;
+
+DROP INDEX IF EXISTS idx_crosshairs_forked_from;
+ALTER TABLE crosshairs
+    DROP COLUMN IF EXISTS forks_count,
+    DROP COLUMN IF EXISTS forked_from;
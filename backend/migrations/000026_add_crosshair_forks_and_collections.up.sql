@@ -0,0 +1,27 @@
+ALTER TABLE crosshairs
+    ADD COLUMN forked_from UUID REFERENCES crosshairs(id) ON DELETE SET NULL,
+    ADD COLUMN forks_count INTEGER NOT NULL DEFAULT 0;
+
+CREATE INDEX idx_crosshairs_forked_from ON crosshairs(forked_from) WHERE forked_from IS NOT NULL;
+
+CREATE TABLE crosshair_collections (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    owner_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    description TEXT,
+    is_public BOOLEAN NOT NULL DEFAULT false,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE(owner_id, name)
+);
+
+CREATE TABLE crosshair_collection_items (
+    collection_id UUID NOT NULL REFERENCES crosshair_collections(id) ON DELETE CASCADE,
+    crosshair_id UUID NOT NULL REFERENCES crosshairs(id) ON DELETE CASCADE,
+    position INTEGER NOT NULL,
+    added_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (collection_id, crosshair_id)
+);
+
+CREATE INDEX idx_crosshair_collection_items_position ON crosshair_collection_items(collection_id, position);
+CREATE INDEX idx_crosshair_collection_items_crosshair_id ON crosshair_collection_items(crosshair_id);
@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS build_ability_upgrades;
+DROP TABLE IF EXISTS build_items;
+
+DROP INDEX IF EXISTS idx_builds_public_hero;
+ALTER TABLE builds
+    DROP COLUMN IF EXISTS hero_id;
@@ -0,0 +1,27 @@
+-- Builds were never written before this migration, so the hero can be
+-- required without backfilling.
+ALTER TABLE builds
+    ADD COLUMN hero_id INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE builds
+    ALTER COLUMN hero_id DROP DEFAULT;
+
+CREATE INDEX idx_builds_public_hero ON builds(hero_id, created_at DESC) WHERE is_public = true;
+
+CREATE TABLE build_items (
+    build_id UUID NOT NULL REFERENCES builds(id) ON DELETE CASCADE,
+    phase_position SMALLINT NOT NULL,
+    phase VARCHAR(32) NOT NULL,
+    position SMALLINT NOT NULL,
+    -- Game item IDs are unsigned 32-bit hashes and do not all fit an INTEGER.
+    item_id BIGINT NOT NULL,
+    PRIMARY KEY (build_id, phase_position, position)
+);
+
+CREATE INDEX idx_build_items_item_id ON build_items(item_id);
+
+CREATE TABLE build_ability_upgrades (
+    build_id UUID NOT NULL REFERENCES builds(id) ON DELETE CASCADE,
+    position SMALLINT NOT NULL,
+    ability_slot SMALLINT NOT NULL CHECK (ability_slot BETWEEN 1 AND 4),
+    PRIMARY KEY (build_id, position)
+);
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS build_stats;
+DROP TABLE IF EXISTS match_player_items;
+DROP TABLE IF EXISTS match_players;
+DROP TABLE IF EXISTS match_details_failures;
+DROP TABLE IF EXISTS match_details;
@@ -0,0 +1,57 @@
+-- Match details fetched from the Deadlock API: who played which hero, the
+-- outcome and the items they bought. found is false for matches the API
+-- does not have, so they are not fetched again.
+CREATE TABLE match_details (
+    match_id BIGINT PRIMARY KEY,
+    found BOOLEAN NOT NULL,
+    start_time TIMESTAMP WITH TIME ZONE,
+    duration_s INTEGER NOT NULL DEFAULT 0,
+    ingested_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_match_details_start_time ON match_details(start_time) WHERE found;
+
+-- Matches whose details could not be fetched or stored. They are retried on
+-- later runs until they have failed too many times.
+CREATE TABLE match_details_failures (
+    match_id BIGINT PRIMARY KEY,
+    attempts INTEGER NOT NULL DEFAULT 1,
+    last_error TEXT NOT NULL DEFAULT '',
+    last_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE match_players (
+    match_id BIGINT NOT NULL REFERENCES match_details(match_id) ON DELETE CASCADE,
+    account_id BIGINT NOT NULL,
+    hero_id INTEGER NOT NULL,
+    team SMALLINT NOT NULL,
+    won BOOLEAN NOT NULL,
+    kills INTEGER NOT NULL DEFAULT 0,
+    deaths INTEGER NOT NULL DEFAULT 0,
+    assists INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (match_id, account_id)
+);
+
+CREATE INDEX idx_match_players_hero_id ON match_players(hero_id);
+
+CREATE TABLE match_player_items (
+    match_id BIGINT NOT NULL,
+    account_id BIGINT NOT NULL,
+    item_id BIGINT NOT NULL,
+    game_time_s INTEGER NOT NULL,
+    PRIMARY KEY (match_id, account_id, item_id, game_time_s),
+    FOREIGN KEY (match_id, account_id) REFERENCES match_players(match_id, account_id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_match_player_items_item_id ON match_player_items(item_id);
+
+-- win_rate is a percentage, like player_stats.win_rate.
+CREATE TABLE build_stats (
+    build_id UUID PRIMARY KEY REFERENCES builds(id) ON DELETE CASCADE,
+    matches INTEGER NOT NULL DEFAULT 0,
+    wins INTEGER NOT NULL DEFAULT 0,
+    win_rate DOUBLE PRECISION NOT NULL DEFAULT 0,
+    avg_kda DOUBLE PRECISION NOT NULL DEFAULT 0,
+    avg_adherence DOUBLE PRECISION NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,13 @@
+ALTER TABLE builds DROP COLUMN IF EXISTS comments_count;
+ALTER TABLE crosshairs DROP COLUMN IF EXISTS comments_count;
+
+DROP INDEX IF EXISTS idx_comments_parent_id;
+DROP INDEX IF EXISTS idx_comments_content;
+CREATE INDEX IF NOT EXISTS idx_comments_content ON comments(content_type, content_id);
+CREATE INDEX IF NOT EXISTS idx_comments_parent_id ON comments(parent_id);
+
+ALTER TABLE comments
+    DROP CONSTRAINT IF EXISTS chk_comments_content_type,
+    DROP COLUMN IF EXISTS deleted_at,
+    DROP COLUMN IF EXISTS updated_at,
+    DROP COLUMN IF EXISTS depth;
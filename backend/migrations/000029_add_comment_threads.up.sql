@@ -0,0 +1,40 @@
+-- Comments are soft deleted so that their replies keep their place in the
+-- thread; deleted comments lose their body. depth is 0 for top-level
+-- comments.
+ALTER TABLE comments
+    ADD COLUMN depth SMALLINT NOT NULL DEFAULT 0,
+    ADD COLUMN updated_at TIMESTAMPTZ,
+    ADD COLUMN deleted_at TIMESTAMPTZ,
+    ADD CONSTRAINT chk_comments_content_type CHECK (content_type IN ('crosshair', 'build'));
+
+DROP INDEX IF EXISTS idx_comments_content;
+DROP INDEX IF EXISTS idx_comments_parent_id;
+CREATE INDEX idx_comments_content ON comments(content_type, content_id, created_at DESC) WHERE parent_id IS NULL;
+CREATE INDEX idx_comments_parent_id ON comments(parent_id, created_at);
+
+-- comments_count counts the comments that are not deleted, replies
+-- included.
+ALTER TABLE crosshairs
+    ADD COLUMN comments_count INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE builds
+    ADD COLUMN comments_count INTEGER NOT NULL DEFAULT 0;
+
+UPDATE crosshairs c
+SET comments_count = counts.total
+FROM (
+    SELECT content_id, COUNT(*) AS total
+    FROM comments
+    WHERE content_type = 'crosshair'
+    GROUP BY content_id
+) counts
+WHERE counts.content_id = c.id;
+
+UPDATE builds b
+SET comments_count = counts.total
+FROM (
+    SELECT content_id, COUNT(*) AS total
+    FROM comments
+    WHERE content_type = 'build'
+    GROUP BY content_id
+) counts
+WHERE counts.content_id = b.id;
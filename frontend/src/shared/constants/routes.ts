@@ -12,6 +12,9 @@ export const routes = {
     view: (id = ':id') => `/builds/${id}`,
     edit: (id = ':id') => `/builds/${id}/edit`,
   },
+  matches: {
+    view: (id = ':id') => `/matches/${id}`,
+  },
   crosshairs: {
     list: '/crosshairs',
     create: '/crosshairs/create',